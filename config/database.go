@@ -66,19 +66,49 @@ func ConnectDB() {
 		&models.Question{},
 		&models.QuestionAnalysis{},
 		&models.History{},
+		&models.XPEvent{},
+		&models.UserDailyXP{},
+		&models.FarmingFlag{},
 		&models.Achievement{},
 		&models.Activity{},
 		&models.Challenge{},
 		&models.ChallengeParticipant{},
+		&models.LobbyMessage{},
+		&models.ChallengeTimelineEntry{},
+		&models.ChallengeMatchEvent{},
+		&models.CoinTransaction{},
+		&models.WagerEscrow{},
+		&models.WagerStake{},
+		&models.Tournament{},
+		&models.TournamentEntry{},
+		&models.TournamentMatch{},
+		&models.SurvivalRun{},
+		&models.SurvivalSeenQuestion{},
+		&models.SurvivalDailySeed{},
 		&models.UserAchievement{},
 		&models.SystemConfig{},
 		&models.Notification{},
 		&models.Item{},
 		&models.UserItem{},
+		&models.ShopOffer{},
+		&models.ShopBundle{},
+		&models.ShopBundleItem{},
+		&models.ShopRotation{},
+		&models.QuizAttempt{},
+		&models.PowerUpUse{},
+		&models.Gift{},
 		&models.DailyClaim{},
 		&models.DailyRewardConfig{},
 		&models.Mission{},
 		&models.UserMission{},
+		&models.MissionEventLog{},
+		&models.Season{},
+		&models.SeasonTier{},
+		&models.UserSeasonPass{},
+		&models.UserSeasonClaim{},
+		&models.UserTitle{},
+		&models.LeagueCohort{},
+		&models.LeagueMember{},
 		&models.StreakLog{},
 		&models.Report{},
 		&models.QuizReview{},
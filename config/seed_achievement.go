@@ -2,31 +2,74 @@ package config
 
 import (
 	"fmt"
+
 	"github.com/ROFL1ST/quizzes-backend/models"
 )
 
+func intPtr(v int) *int { return &v }
 
-func SeedAchievements() {
-	var count int64
-	DB.Model(&models.Achievement{}).Count(&count)
+// defaultAchievements adalah achievement bawaan. LegacyID menunjuk ID lama yang dulu
+// di-hardcode di kode, supaya achievement yang sudah di-unlock user tetap tersambung.
+var defaultAchievements = []struct {
+	LegacyID uint
+	models.Achievement
+}{
+	{1, models.Achievement{Key: "first_quiz", Name: "Langkah Pertama", Description: "Menyelesaikan kuis pertama kali", IconURL: "🎯", Trigger: "quiz_finished", Metric: "count", Threshold: 1}},
+	{2, models.Achievement{Key: "perfect_score", Name: "Sempurna!", Description: "Mendapatkan nilai 100 dalam kuis", IconURL: "💯", Trigger: "quiz_finished", Metric: "count", Threshold: 1, MinValue: intPtr(100)}},
+	{3, models.Achievement{Key: "quiz_10", Name: "Raja Kuis", Description: "Menyelesaikan 10 kuis", IconURL: "👑", Trigger: "quiz_finished", Metric: "count", Threshold: 10}},
+	{4, models.Achievement{Key: "first_win", Name: "Petarung", Description: "Memenangkan duel pertama", IconURL: "⚔️", Trigger: "challenge_won", Metric: "count", Threshold: 1}},
+	{5, models.Achievement{Key: "level_5", Name: "Sepuh", Description: "Mencapai Level 5", IconURL: "👴", Trigger: "level_up", Metric: "max", Threshold: 5}},
+	{6, models.Achievement{Key: "streak_3", Name: "Konsisten", Description: "Streak selama 3 hari", IconURL: "🔥", Trigger: "quiz_finished", Metric: "streak", Threshold: 3}},
+	{7, models.Achievement{Key: "friends_3", Name: "Gaul", Description: "Memiliki 3 teman", IconURL: "🤝", Trigger: "friend_added", Metric: "count", Threshold: 3}},
+	{8, models.Achievement{Key: "topics_3", Name: "Penjelajah", Description: "Mengerjakan kuis dari 3 topik berbeda", IconURL: "🧭", Trigger: "quiz_finished", Metric: "distinct", DistinctBy: "topic", Threshold: 3}},
+	{9, models.Achievement{Key: "wins_5", Name: "Jagoan Duel", Description: "Memenangkan 5 challenge", IconURL: "🏅", Trigger: "challenge_won", Metric: "count", Threshold: 5}},
+	{10, models.Achievement{Key: "xp_5000", Name: "Kolektor XP", Description: "Mengumpulkan 5000 XP", IconURL: "💎", Trigger: "xp_gained", Metric: "max", Threshold: 5000}},
+	{11, models.Achievement{Key: "never_give_up", Name: "Pantang Menyerah", Description: "Tetap menyelesaikan kuis walau nilai di bawah 50", IconURL: "💪", Trigger: "quiz_finished", Metric: "count", Threshold: 1, MaxValue: intPtr(49)}},
+	{12, models.Achievement{Key: "night_owl", Name: "Burung Hantu", Description: "Mengerjakan kuis antara jam 00.00 - 05.00", IconURL: "🦉", Trigger: "quiz_finished", Metric: "count", Threshold: 1, HourFrom: intPtr(0), HourTo: intPtr(5)}},
+	{13, models.Achievement{Key: "level_10", Name: "Veteran", Description: "Mencapai Level 10", IconURL: "🎖️", Trigger: "level_up", Metric: "max", Threshold: 10}},
+	{14, models.Achievement{Key: "member_30_days", Name: "Setia", Description: "Bergabung selama 30 hari", IconURL: "📅", Trigger: "login", Metric: "max", Threshold: 30}},
+	{15, models.Achievement{Key: "perfect_3_quizzes", Name: "Perfeksionis", Description: "Nilai 100 di 3 kuis berbeda", IconURL: "🌟", Trigger: "quiz_finished", Metric: "distinct", DistinctBy: "quiz", Threshold: 3, MinValue: intPtr(100)}},
+	{16, models.Achievement{Key: "friend_requests_5", Name: "Ramah", Description: "Mengirim 5 permintaan teman", IconURL: "👋", Trigger: "friend_request_sent", Metric: "count", Threshold: 5}},
+}
 
-	if count > 0 {
-		return
-	}
+// SeedAchievements memastikan achievement bawaan ada (berdasarkan Key).
+// Baris lama tanpa Key (ID hardcode) dilengkapi aturannya tanpa mengubah nama/ikon.
+func SeedAchievements() {
+	for _, def := range defaultAchievements {
+		var count int64
+		DB.Model(&models.Achievement{}).Where("key = ?", def.Key).Count(&count)
+		if count > 0 {
+			continue
+		}
 
-	fmt.Println("Seeding Achievements...")
+		var legacy models.Achievement
+		if err := DB.Where("id = ? AND (key IS NULL OR key = '')", def.LegacyID).First(&legacy).Error; err == nil {
+			DB.Model(&legacy).Updates(map[string]interface{}{
+				"key":         def.Key,
+				"trigger":     def.Trigger,
+				"metric":      def.Metric,
+				"distinct_by": def.DistinctBy,
+				"threshold":   def.Threshold,
+				"hour_from":   def.HourFrom,
+				"hour_to":     def.HourTo,
+				"min_value":   def.MinValue,
+				"max_value":   def.MaxValue,
+				"is_active":   true,
+			})
+			continue
+		}
 
-	achievements := []models.Achievement{
-		{ID: 1, Name: "Langkah Pertama", Description: "Menyelesaikan kuis pertama kali", IconURL: "🎯"},
-		{ID: 2, Name: "Sempurna!", Description: "Mendapatkan nilai 100 dalam kuis", IconURL: "💯"},
-		{ID: 3, Name: "Raja Kuis", Description: "Menyelesaikan 10 kuis", IconURL: "👑"},
-		{ID: 4, Name: "Petarung", Description: "Memenangkan duel pertama", IconURL: "⚔️"},
-		{ID: 5, Name: "Sepuh", Description: "Mencapai Level 5", IconURL: "👴"},
-        {ID: 6, Name: "Konsisten", Description: "Streak selama 3 hari", IconURL: "🔥"},
+		// Pakai ID lama jika masih kosong supaya data lama tetap cocok
+		ach := def.Achievement
+		DB.Model(&models.Achievement{}).Where("id = ?", def.LegacyID).Count(&count)
+		if count == 0 {
+			ach.ID = def.LegacyID
+		}
+		if err := DB.Create(&ach).Error; err != nil {
+			fmt.Println("Failed to seed achievement", def.Key, err)
+		}
 	}
 
-	for _, a := range achievements {
-		DB.Create(&a)
-	}
-	fmt.Println("Achievements Seeded!")
-}
\ No newline at end of file
+	// Seed memakai ID eksplisit, geser sequence supaya insert dari admin tidak bentrok
+	DB.Exec("SELECT setval(pg_get_serial_sequence('achievements', 'id'), (SELECT COALESCE(MAX(id), 1) FROM achievements))")
+}
@@ -40,44 +40,67 @@ func SeedDailyData() {
 	// ==========================================
 	missions := []models.Mission{
 		// --- Kategori: Main Kuis ---
-		{Key: "play_quiz_1", Title: "Pemanasan", Description: "Mainkan 1 Kuis mode apa saja", Target: 1, Reward: 20},
-		{Key: "play_quiz_3", Title: "Marathon Kuis", Description: "Mainkan 3 Kuis hari ini", Target: 3, Reward: 50},
-		{Key: "play_quiz_5", Title: "Kecanduan", Description: "Mainkan 5 Kuis hari ini", Target: 5, Reward: 100},
-		
+		{Key: "play_quiz_1", Title: "Pemanasan", Description: "Mainkan 1 Kuis mode apa saja", Target: 1, Reward: 20, EventType: "quiz_finished", Aggregation: "count", Weight: 20, Rarity: "common", PassPoints: 10},
+		{Key: "play_quiz_3", Title: "Marathon Kuis", Description: "Mainkan 3 Kuis hari ini", Target: 3, Reward: 50, EventType: "quiz_finished", Aggregation: "count", Weight: 15, Rarity: "common", PassPoints: 10},
+		{Key: "play_quiz_5", Title: "Kecanduan", Description: "Mainkan 5 Kuis hari ini", Target: 5, Reward: 100, EventType: "quiz_finished", Aggregation: "count", Weight: 8, Rarity: "rare", PassPoints: 20},
+
 		// --- Kategori: Skor ---
-		{Key: "score_100", Title: "Sempurna", Description: "Dapatkan nilai 100 dalam kuis", Target: 1, Reward: 40},
-		{Key: "score_100_3x", Title: "Jenius Sejati", Description: "Dapatkan 3x nilai 100", Target: 3, Reward: 150},
-		{Key: "total_score_500", Title: "Pengumpul Poin", Description: "Kumpulkan total 500 skor dari semua kuis", Target: 500, Reward: 60},
+		{Key: "score_100", Title: "Sempurna", Description: "Dapatkan nilai 100 dalam kuis", Target: 1, Reward: 40, EventType: "quiz_finished", Filter: "score == 100 && survival == 0", Aggregation: "count", Weight: 12, Rarity: "common", PassPoints: 10},
+		{Key: "score_100_3x", Title: "Jenius Sejati", Description: "Dapatkan 3x nilai 100", Target: 3, Reward: 150, EventType: "quiz_finished", Filter: "score == 100 && survival == 0", Aggregation: "count", Weight: 4, Rarity: "epic", PassPoints: 40},
+		{Key: "total_score_500", Title: "Pengumpul Poin", Description: "Kumpulkan total 500 skor dari semua kuis", Target: 500, Reward: 60, EventType: "quiz_finished", Aggregation: "sum", Weight: 8, Rarity: "rare", PassPoints: 20},
 
 		// --- Kategori: Challenge (Duel) ---
-		{Key: "win_challenge_1", Title: "Petarung", Description: "Menangkan 1 Challenge lawan teman", Target: 1, Reward: 50},
-		{Key: "play_challenge_2v2", Title: "Teamwork", Description: "Mainkan 1 kali mode 2v2", Target: 1, Reward: 30},
-		
+		{Key: "win_challenge_1", Title: "Petarung", Description: "Menangkan 1 Challenge lawan teman", Target: 1, Reward: 50, EventType: "challenge_won", Aggregation: "count", Weight: 8, Rarity: "rare", PassPoints: 20},
+		{Key: "play_challenge_2v2", Title: "Teamwork", Description: "Mainkan 1 kali mode 2v2", Target: 1, Reward: 30, EventType: "challenge_played", Filter: `mode == "2v2"`, Aggregation: "count", Weight: 6, Rarity: "common", PassPoints: 10},
+
 		// --- Kategori: Waktu Login ---
-		{Key: "login_morning", Title: "Semangat Pagi", Description: "Login antara jam 05:00 - 10:00", Target: 1, Reward: 15},
-		{Key: "login_night", Title: "Anak Malam", Description: "Login antara jam 20:00 - 24:00", Target: 1, Reward: 15},
+		{Key: "login_morning", Title: "Semangat Pagi", Description: "Login antara jam 05:00 - 10:00", Target: 1, Reward: 15, EventType: "login", Filter: "hour >= 5 && hour < 10", Aggregation: "count", Weight: 10, Rarity: "common", PassPoints: 10},
+		{Key: "login_night", Title: "Anak Malam", Description: "Login antara jam 20:00 - 24:00", Target: 1, Reward: 15, EventType: "login", Filter: "hour >= 20", Aggregation: "count", Weight: 10, Rarity: "common", PassPoints: 10},
 
 		// --- Kategori: Shop & Item ---
-		{Key: "buy_item", Title: "Belanja", Description: "Beli 1 item apa saja di Shop", Target: 1, Reward: 25},
-		{Key: "equip_avatar", Title: "Gaya Baru", Description: "Ganti/Pasang Avatar Frame", Target: 1, Reward: 10},
+		{Key: "buy_item", Title: "Belanja", Description: "Beli 1 item apa saja di Shop", Target: 1, Reward: 25, EventType: "shop_buy", Aggregation: "count", Weight: 8, Rarity: "common", PassPoints: 10},
+		{Key: "equip_avatar", Title: "Gaya Baru", Description: "Ganti/Pasang Avatar Frame", Target: 1, Reward: 10, EventType: "shop_equip", Aggregation: "count", Weight: 10, Rarity: "common", PassPoints: 10},
 
 		// --- Kategori: XP & Level ---
-		{Key: "earn_xp_1000", Title: "Grinding XP", Description: "Dapatkan 1000 XP hari ini", Target: 1000, Reward: 40},
-		{Key: "level_up_daily", Title: "Naik Kelas", Description: "Naik 1 Level hari ini", Target: 1, Reward: 200},
+		{Key: "earn_xp_1000", Title: "Grinding XP", Description: "Dapatkan 1000 XP hari ini", Target: 1000, Reward: 40, EventType: "xp_gained", Aggregation: "sum", Weight: 6, Rarity: "rare", PassPoints: 20},
+		{Key: "level_up_daily", Title: "Naik Kelas", Description: "Naik 1 Level hari ini", Target: 1, Reward: 200, EventType: "level_up", Aggregation: "count", Weight: 3, Rarity: "epic", PassPoints: 40},
 
 		// --- Kategori: Sosial ---
-		{Key: "add_friend", Title: "Mencari Teman", Description: "Tambah 1 teman baru", Target: 1, Reward: 20},
-		{Key: "check_leaderboard", Title: "Ambis", Description: "Cek halaman Leaderboard", Target: 1, Reward: 5},
+		{Key: "add_friend", Title: "Mencari Teman", Description: "Tambah 1 teman baru", Target: 1, Reward: 20, EventType: "friend_added", Aggregation: "count", Weight: 10, Rarity: "common", PassPoints: 10},
+		{Key: "check_leaderboard", Title: "Ambis", Description: "Cek halaman Leaderboard", Target: 1, Reward: 5, EventType: "leaderboard_view", Aggregation: "count", Weight: 12, Rarity: "common", PassPoints: 10},
 
 		// --- Kategori: Spesial ---
-		{Key: "perfect_streak", Title: "Tanpa Salah", Description: "Jawab 10 soal berturut-turut tanpa salah (Logika khusus)", Target: 10, Reward: 100},
-		{Key: "quiz_math", Title: "Ahli Hitung", Description: "Mainkan kuis topik Matematika", Target: 1, Reward: 30},
-		{Key: "quiz_history", Title: "Sejarawan", Description: "Mainkan kuis topik Sejarah", Target: 1, Reward: 30},
-		{Key: "share_app", Title: "Influencer", Description: "Bagikan profilmu (Tombol Share)", Target: 1, Reward: 10},
+		{Key: "perfect_streak", Title: "Tanpa Salah", Description: "Dapatkan nilai 100 di kuis minimal 10 soal", Target: 1, Reward: 100, EventType: "quiz_finished", Filter: "score == 100 && questions >= 10 && survival == 0", Aggregation: "count", Weight: 4, Rarity: "epic", PassPoints: 40},
+		{Key: "quiz_math", Title: "Ahli Hitung", Description: "Mainkan kuis topik Matematika", Target: 1, Reward: 30, EventType: "quiz_finished", Filter: `topic == "Matematika"`, Aggregation: "count", Weight: 8, Rarity: "common", PassPoints: 10},
+		{Key: "quiz_history", Title: "Sejarawan", Description: "Mainkan kuis topik Sejarah", Target: 1, Reward: 30, EventType: "quiz_finished", Filter: `topic == "Sejarah"`, Aggregation: "count", Weight: 8, Rarity: "common", PassPoints: 10},
+		{Key: "share_app", Title: "Influencer", Description: "Bagikan profilmu (Tombol Share)", Target: 1, Reward: 10, EventType: "profile_share", Aggregation: "count", Weight: 6, Rarity: "common", PassPoints: 10},
+
+		// --- Misi Mingguan ---
+		{Key: "weekly_quiz_15", Title: "Pejuang Mingguan", Description: "Mainkan 15 kuis minggu ini", Target: 15, Reward: 150, EventType: "quiz_finished", Aggregation: "count", Weight: 10, Rarity: "common", Period: "weekly", PassPoints: 100},
+		{Key: "weekly_perfect_5", Title: "Lima Kali Sempurna", Description: "Dapatkan nilai 100 sebanyak 5 kali minggu ini", Target: 5, Reward: 250, EventType: "quiz_finished", Filter: "score == 100 && survival == 0", Aggregation: "count", Weight: 6, Rarity: "rare", Period: "weekly", PassPoints: 150},
+		{Key: "weekly_win_3", Title: "Penguasa Arena", Description: "Menangkan 3 challenge minggu ini", Target: 3, Reward: 200, EventType: "challenge_won", Aggregation: "count", Weight: 8, Rarity: "common", Period: "weekly", PassPoints: 120},
+		{Key: "weekly_xp_5000", Title: "Grinding Mingguan", Description: "Dapatkan 5000 XP minggu ini", Target: 5000, Reward: 200, EventType: "xp_gained", Aggregation: "sum", Weight: 8, Rarity: "common", Period: "weekly", PassPoints: 120},
+		{Key: "weekly_friends_2", Title: "Lingkar Pertemanan", Description: "Tambah 2 teman baru minggu ini", Target: 2, Reward: 80, EventType: "friend_added", Aggregation: "count", Weight: 5, Rarity: "common", Period: "weekly", PassPoints: 60},
+		{Key: "weekly_royale_2", Title: "Bertahan Hidup", Description: "Mainkan 2 battle royale minggu ini", Target: 2, Reward: 150, EventType: "challenge_played", Filter: `mode == "battle_royale"`, Aggregation: "count", Weight: 4, Rarity: "epic", Period: "weekly", PassPoints: 150},
 	}
 
 	for _, m := range missions {
-		DB.FirstOrCreate(&models.Mission{Key: m.Key}, m)
+		mission := m
+		DB.Where("key = ?", m.Key).FirstOrCreate(&mission)
+
+		// Misi lama (sebelum ada aturan) dilengkapi aturannya
+		DB.Model(&models.Mission{}).
+			Where("key = ? AND (event_type IS NULL OR event_type = '')", m.Key).
+			Updates(map[string]interface{}{
+				"event_type":  m.EventType,
+				"filter":      m.Filter,
+				"aggregation": m.Aggregation,
+				"weight":      m.Weight,
+				"rarity":      m.Rarity,
+				"pass_points": m.PassPoints,
+				"target":      m.Target,
+				"description": m.Description,
+			})
 	}
 
 	fmt.Println("Seeding Daily Data Done!")
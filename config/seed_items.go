@@ -6,6 +6,9 @@ import (
 )
 
 func SeedShopItems() {
+	// Consumable ditambahkan terpisah supaya database lama juga mendapatkannya
+	defer seedConsumableItems()
+
 	var count int64
 	DB.Model(&models.Item{}).Count(&count)
 	if count > 0 {
@@ -61,3 +64,20 @@ func SeedShopItems() {
 
 	fmt.Println("Success! Shop Items seeded (Themes are hidden).")
 }
+
+func seedConsumableItems() {
+	items := []models.Item{
+		{Name: "Streak Freeze", Description: "Otomatis menjaga streak saat kamu bolos sehari.", Price: 200, Type: "streak_freeze", AssetURL: "item_streak_freeze", IsActive: true, Consumable: true, MaxStack: 2},
+		{Name: "Streak Repair", Description: "Pulihkan streak yang putus (maks 48 jam).", Price: 400, Type: "streak_repair", AssetURL: "item_streak_repair", IsActive: true, Consumable: true, MaxStack: 5},
+
+		// --- POWER-UP KUIS ---
+		{Name: "50/50", Description: "Hapus dua pilihan jawaban yang salah.", Price: 60, Type: "powerup_fifty_fifty", AssetURL: "powerup_fifty_fifty", IsActive: true, Consumable: true, MaxStack: 99},
+		{Name: "+15 Detik", Description: "Tambah waktu 15 detik.", Price: 40, Type: "powerup_time_plus", AssetURL: "powerup_time_plus", IsActive: true, Consumable: true, MaxStack: 99},
+		{Name: "Skip", Description: "Lewati satu soal tanpa mengurangi nilai.", Price: 80, Type: "powerup_skip", AssetURL: "powerup_skip", IsActive: true, Consumable: true, MaxStack: 99},
+		{Name: "Extra Life", Description: "Tambah satu nyawa di mode survival.", Price: 150, Type: "powerup_extra_life", AssetURL: "powerup_extra_life", IsActive: true, Consumable: true, MaxStack: 10},
+	}
+
+	for _, item := range items {
+		DB.Where("type = ?", item.Type).FirstOrCreate(&item)
+	}
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GET /api/admin/achievements
+func GetAllAchievementsAdmin(c *fiber.Ctx) error {
+	var achievements []models.Achievement
+	query := config.DB.Order("id asc")
+	if c.Query("active") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+	query.Find(&achievements)
+
+	type achievementStats struct {
+		AchievementID uint
+		Total         int64
+	}
+	var stats []achievementStats
+	config.DB.Model(&models.UserAchievement{}).
+		Select("achievement_id, COUNT(*) AS total").
+		Group("achievement_id").
+		Scan(&stats)
+
+	unlockCount := make(map[uint]int64)
+	for _, s := range stats {
+		unlockCount[s.AchievementID] = s.Total
+	}
+
+	response := []fiber.Map{}
+	for _, ach := range achievements {
+		response = append(response, fiber.Map{
+			"achievement":  ach,
+			"unlock_count": unlockCount[ach.ID],
+		})
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Achievements retrieved", response)
+}
+
+// POST /api/admin/achievements
+func CreateAchievement(c *fiber.Ctx) error {
+	var ach models.Achievement
+	if err := c.BodyParser(&ach); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	ach.ID = 0
+	ach.IsActive = true
+	ach.RetiredAt = nil
+
+	if err := utils.ValidateAchievementRule(ach); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	var count int64
+	config.DB.Model(&models.Achievement{}).Where("key = ?", ach.Key).Count(&count)
+	if count > 0 {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Achievement key already exists", nil)
+	}
+
+	if err := config.DB.Create(&ach).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create achievement", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Achievement created", ach)
+}
+
+// PUT /api/admin/achievements/:id
+// Aturan boleh diubah; user yang sudah membuka tetap memilikinya
+func UpdateAchievement(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var ach models.Achievement
+	if err := config.DB.First(&ach, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found", nil)
+	}
+	key, isActive, retiredAt := ach.Key, ach.IsActive, ach.RetiredAt
+
+	if err := c.BodyParser(&ach); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	// Key, ID & status pensiun tidak diubah lewat endpoint ini
+	ach.ID = uint(id)
+	ach.Key = key
+	ach.IsActive = isActive
+	ach.RetiredAt = retiredAt
+
+	if err := utils.ValidateAchievementRule(ach); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	if err := config.DB.Save(&ach).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update achievement", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Achievement updated", ach)
+}
+
+// DELETE /api/admin/achievements/:id
+// Achievement tidak dihapus (user yang sudah membuka tetap memilikinya), hanya dipensiunkan
+func RetireAchievement(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	now := time.Now()
+	res := config.DB.Model(&models.Achievement{}).
+		Where("id = ? AND is_active = ?", id, true).
+		Updates(map[string]interface{}{"is_active": false, "retired_at": now})
+	if res.Error != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retire achievement", res.Error.Error())
+	}
+	if res.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found or already retired", nil)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Achievement retired", nil)
+}
+
+// POST /api/admin/achievements/:id/restore
+func RestoreAchievement(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	res := config.DB.Model(&models.Achievement{}).
+		Where("id = ? AND is_active = ?", id, false).
+		Updates(map[string]interface{}{"is_active": true, "retired_at": nil})
+	if res.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found or already active", nil)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Achievement restored", nil)
+}
+
+// POST /api/admin/achievements/:id/backfill?notify=true
+// Mengevaluasi achievement untuk semua user lama
+func BackfillAchievement(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var ach models.Achievement
+	if err := config.DB.First(&ach, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found", nil)
+	}
+	if !ach.IsActive {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Achievement sudah pensiun", nil)
+	}
+
+	unlocked := utils.BackfillAchievement(ach, c.Query("notify") == "true")
+	return utils.SuccessResponse(c, fiber.StatusOK, "Backfill finished", fiber.Map{"unlocked": unlocked})
+}
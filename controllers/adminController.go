@@ -88,6 +88,30 @@ func UpdateLevelingConfig(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, fiber.StatusOK, "Leveling difficulty updated", conf)
 }
 
+func GetWagerFeeConfig(c *fiber.Ctx) error {
+	return utils.SuccessResponse(c, fiber.StatusOK, "Config retrieved", fiber.Map{
+		"value": strconv.Itoa(utils.GetWagerHouseFeePercent()),
+	})
+}
+
+// UpdateWagerFeeConfig mengatur potongan bandar (0-100 persen dari pot taruhan)
+func UpdateWagerFeeConfig(c *fiber.Ctx) error {
+	var input ConfigInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", nil)
+	}
+
+	percent, err := strconv.Atoi(input.Value)
+	if err != nil || percent < 0 || percent > 100 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Value must be a number between 0 and 100", nil)
+	}
+
+	var conf models.SystemConfig
+	config.DB.Where("key = ?", "wager_house_fee_percent").Assign(models.SystemConfig{Value: input.Value}).FirstOrCreate(&conf, models.SystemConfig{Key: "wager_house_fee_percent"})
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Wager house fee updated", conf)
+}
+
 func GetDashboardAnalytics(c *fiber.Ctx) error {
 	var totalUsers, totalQuizzes, totalAttempts, totalQuestions int64
 	var avgScore float64
@@ -525,6 +549,9 @@ func CreateShopItem(c *fiber.Ctx) error {
 	if err := c.BodyParser(&item); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
 	}
+	if err := utils.ValidateShopItem(item); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
 	if err := config.DB.Create(&item).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed create item", err.Error())
 	}
@@ -541,6 +568,9 @@ func UpdateShopItem(c *fiber.Ctx) error {
 	if err := c.BodyParser(&item); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
 	}
+	if err := utils.ValidateShopItem(item); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
 	if err := config.DB.Save(&item).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed update item", err.Error())
 	}
@@ -16,7 +16,7 @@ func BanUser(c *fiber.Ctx) error {
 	}
 
 	user.IsBanned = true
-	if err := config.DB.Save(&user).Error; err != nil {
+	if err := config.DB.Omit("coins").Save(&user).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to ban user", err.Error())
 	}
 
@@ -32,7 +32,7 @@ func UnbanUser(c *fiber.Ctx) error {
 	}
 
 	user.IsBanned = false
-	if err := config.DB.Save(&user).Error; err != nil {
+	if err := config.DB.Omit("coins").Save(&user).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to unban user", err.Error())
 	}
 
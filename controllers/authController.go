@@ -3,7 +3,6 @@ package controllers
 import (
 	"fmt"
 	"os"
-	"strconv"
 	"time"
 
 	"github.com/ROFL1ST/quizzes-backend/config"
@@ -71,11 +70,13 @@ func LoginUser(c *fiber.Ctx) error {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	t, _ := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 
-	config.DB.Omit("UserItems").Save(&user)
+	config.DB.Omit("UserItems", "coins").Save(&user)
 
-	config.DB.Save(&user)
-	currentHour := utils.GetJakartaTime().Hour()
-	utils.CheckDailyMissions(user.ID, "login", 0, strconv.Itoa(currentHour))
+	utils.TrackMissionEvent(user.ID, utils.MissionEvent{
+		Type:  utils.MissionLogin,
+		Attrs: map[string]interface{}{"hour": utils.GetJakartaTime().Hour()},
+	})
+	utils.FireAchievementEvent(user.ID, utils.AchievementLogin)
 	return utils.SuccessResponse(c, fiber.StatusOK, "Login success", fiber.Map{
 		"token": t,
 		"user":  user,
@@ -164,10 +165,13 @@ func AuthMe(c *fiber.Ctx) error {
 			Where("user_items.user_id = ? AND user_items.is_equipped = ?", user.ID, true).
 			Find(&equippedItems)
 
-		config.DB.Omit("UserItems").Save(&user)
+		config.DB.Omit("UserItems", "coins").Save(&user)
 
-		currentHour := utils.GetJakartaTime().Hour()
-		utils.CheckDailyMissions(user.ID, "login", 0, strconv.Itoa(currentHour))
+		utils.TrackMissionEvent(user.ID, utils.MissionEvent{
+			Type:  utils.MissionLogin,
+			Attrs: map[string]interface{}{"hour": utils.GetJakartaTime().Hour()},
+		})
+		utils.FireAchievementEvent(user.ID, utils.AchievementLogin)
 		return utils.SuccessResponse(c, fiber.StatusOK, "User session refreshed", fiber.Map{
 			"token":          t,
 			"user":           user,
@@ -2,7 +2,7 @@ package controllers
 
 import (
 	"bufio"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -11,6 +11,7 @@ import (
 	"github.com/ROFL1ST/quizzes-backend/models"
 	"github.com/ROFL1ST/quizzes-backend/utils"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type CreateChallengeInput struct {
@@ -20,6 +21,19 @@ type CreateChallengeInput struct {
 	TimeLimit         int      `json:"time_limit"`
 	IsRealtime        bool     `json:"is_realtime"`
 	WagerAmount       int      `json:"wager_amount"`
+	AcceptHours       int      `json:"accept_hours"`   // Opsional, default 24 jam
+	CompleteHours     int      `json:"complete_hours"` // Opsional, default 72 jam
+	AllowSpectators   bool     `json:"allow_spectators"`
+	Visibility        string   `json:"visibility"`  // private (default), public, friends
+	MaxPlayers        int      `json:"max_players"` // Lobby terbuka mode survival, default 8
+	Ghost             bool     `json:"ghost"`       // Async: lawan bermain melawan replay progres creator
+	DisablePowerups   bool     `json:"disable_powerups"`
+	SurvivalLives     int      `json:"survival_lives"` // Mode survival: 1-3 nyawa, default 1
+
+	// Battle royale (realtime): aturan eliminasi
+	EliminationRule   string `json:"elimination_rule"`    // slowest_wrong (default), lowest_score
+	EliminatePerRound int    `json:"eliminate_per_round"` // Default 1
+	SafeRounds        int    `json:"safe_rounds"`         // Ronde awal tanpa eliminasi
 }
 
 func CreateChallenge(c *fiber.Ctx) error {
@@ -30,37 +44,89 @@ func CreateChallenge(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
 	}
 
-	// Validasi input khusus 2v2
-	if input.Mode == "2v2" && len(input.OpponentUsernames) != 3 {
+	if input.Visibility == "" {
+		input.Visibility = "private"
+	}
+	if input.Visibility != "private" && input.Visibility != "public" && input.Visibility != "friends" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "visibility harus private, public, atau friends", nil)
+	}
+	openLobby := input.Visibility != "private"
+	if openLobby && !input.IsRealtime {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Lobby terbuka hanya untuk challenge realtime", nil)
+	}
+
+	if input.Mode == utils.BattleRoyaleMode {
+		if !input.IsRealtime {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Battle royale hanya untuk challenge realtime", nil)
+		}
+		if input.EliminationRule == "" {
+			input.EliminationRule = utils.DefaultEliminationRule
+		}
+		if !utils.EliminationRules[input.EliminationRule] {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "elimination_rule harus slowest_wrong atau lowest_score", nil)
+		}
+		if input.EliminatePerRound < 0 || input.SafeRounds < 0 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Aturan eliminasi tidak valid", nil)
+		}
+		if input.EliminatePerRound == 0 {
+			input.EliminatePerRound = 1
+		}
+	} else {
+		input.EliminationRule, input.EliminatePerRound, input.SafeRounds = "", 0, 0
+	}
+
+	if input.Ghost && input.IsRealtime {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Mode ghost hanya untuk challenge async", nil)
+	}
+
+	// Validasi input khusus 2v2 (lobby terbuka boleh diisi pemain yang join lewat kode)
+	if input.Mode == "2v2" && !openLobby && len(input.OpponentUsernames) != 3 {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Mode 2v2 butuh 3 orang (1 teman, 2 lawan)", nil)
 	}
 
+	maxPlayers := 0
+	if openLobby {
+		maxPlayers = utils.LobbyCapacity(input.Mode, input.MaxPlayers)
+		if len(input.OpponentUsernames)+1 > maxPlayers {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, fmt.Sprintf("Lobby maksimal %d pemain", maxPlayers), nil)
+		}
+	}
+
 	// Validasi Survival (Optional)
 	if input.Mode == "survival" {
 		// Survival bisa 1v1 atau banyak. QuizID diabaikan (Random Global).
 		if input.QuizID == 0 {
 			// Opsional: Set placeholder ID jika perlu
 		}
-	}
-
-	// --- [LOGIC BARU] Cek Saldo & Potong Taruhan Creator ---
-	if input.WagerAmount > 0 {
-		var creator models.User
-		if err := config.DB.First(&creator, uint(creatorID)).Error; err != nil {
-			return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", nil)
+		if input.SurvivalLives == 0 {
+			input.SurvivalLives = 1
 		}
-
-		if creator.Coins < input.WagerAmount {
-			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Koin tidak cukup untuk taruhan!", nil)
+		if input.SurvivalLives < 1 || input.SurvivalLives > utils.SurvivalMaxLives {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "survival_lives harus 1-3", nil)
 		}
+	} else {
+		input.SurvivalLives = 1
+	}
 
-		// Potong koin creator sekarang
-		creator.Coins -= input.WagerAmount
-		if err := config.DB.Save(&creator).Error; err != nil {
-			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Gagal memproses koin", err.Error())
-		}
+	// Deadline accept & selesai
+	acceptWindow := utils.DefaultAcceptWindow
+	if input.AcceptHours > 0 {
+		acceptWindow = time.Duration(input.AcceptHours) * time.Hour
+	}
+	completeWindow := utils.DefaultCompleteWindow
+	if input.CompleteHours > 0 {
+		completeWindow = time.Duration(input.CompleteHours) * time.Hour
+	}
+	if completeWindow < acceptWindow {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "complete_hours tidak boleh lebih kecil dari accept_hours", nil)
+	}
+	now := time.Now()
+	acceptDeadline := now.Add(acceptWindow)
+	completeDeadline := now.Add(completeWindow)
+
+	if input.WagerAmount < 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Taruhan tidak valid", nil)
 	}
-	// --------------------------------------------------------
 
 	// Prepare QuizID pointer (handle nullable)
 	var quizIDPtr *uint
@@ -77,25 +143,64 @@ func CreateChallenge(c *fiber.Ctx) error {
 		IsRealtime:  input.IsRealtime,
 		Status:      "pending",
 		WagerAmount: input.WagerAmount, // <-- Simpan nilai taruhan
-	}
 
-	if err := config.DB.Create(&challenge).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed create challenge", err.Error())
+		AcceptDeadline:   &acceptDeadline,
+		CompleteDeadline: &completeDeadline,
+		AllowSpectators:  input.AllowSpectators,
+		Visibility:       input.Visibility,
+		MaxPlayers:       maxPlayers,
+		Ghost:            input.Ghost,
+		DisablePowerups:  input.DisablePowerups,
+		SurvivalLives:    input.SurvivalLives,
+
+		EliminationRule:   input.EliminationRule,
+		EliminatePerRound: input.EliminatePerRound,
+		SafeRounds:        input.SafeRounds,
+	}
+	if openLobby {
+		code := utils.GenerateJoinCode()
+		challenge.JoinCode = &code
+	}
+	if input.Mode == "survival" {
+		challenge.Seed = utils.NewSurvivalSeed()
 	}
 
-	// 2. Masukkan Creator sebagai Peserta (Creator selalu Tim A)
+	// 1-2. Header, Creator sebagai Peserta (Creator selalu Tim A), dan taruhan creator
+	// masuk escrow dalam satu transaksi
 	creatorTeam := "solo"
 	if input.Mode == "2v2" {
 		creatorTeam = "A"
 	}
 
-	creatorPart := models.ChallengeParticipant{
-		ChallengeID: challenge.ID,
-		UserID:      uint(creatorID),
-		Status:      "accepted",
-		Team:        creatorTeam,
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&challenge).Error; err != nil {
+			return err
+		}
+
+		creatorPart := models.ChallengeParticipant{
+			ChallengeID: challenge.ID,
+			UserID:      uint(creatorID),
+			Status:      "accepted",
+			Team:        creatorTeam,
+		}
+		if err := tx.Create(&creatorPart).Error; err != nil {
+			return err
+		}
+
+		if challenge.WagerAmount > 0 {
+			if err := utils.OpenWagerEscrow(tx, challenge.ID); err != nil {
+				return err
+			}
+			return utils.HoldWagerStake(tx, challenge, uint(creatorID))
+		}
+		return nil
+	})
+	if errors.Is(err, utils.ErrInsufficientCoins) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Koin tidak cukup untuk taruhan!", nil)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed create challenge", err.Error())
 	}
-	config.DB.Create(&creatorPart)
 
 	// 3. Masukkan Lawan/Teman
 	if len(input.OpponentUsernames) > 0 {
@@ -142,6 +247,10 @@ func CreateChallenge(c *fiber.Ctx) error {
 		}
 	}
 
+	if challenge.JoinCode != nil {
+		challenge.InviteLink = utils.LobbyInviteLink(*challenge.JoinCode)
+	}
+
 	return utils.SuccessResponse(c, fiber.StatusCreated, "Challenge created", challenge)
 }
 
@@ -217,55 +326,71 @@ func GetMyChallenges(c *fiber.Ctx) error {
 
 func AcceptChallenge(c *fiber.Ctx) error {
 	id := c.Params("id") // Challenge ID
+	challengeIDData, _ := strconv.Atoi(id)
 	userID := c.Locals("user_id").(float64)
 
+	if err := acceptChallengeParticipant(uint(challengeIDData), uint(userID)); err != nil {
+		return utils.ErrorResponse(c, err.Code, err.Message, nil)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Challenge accepted!", nil)
+}
+
+// acceptChallengeParticipant dipakai oleh endpoint HTTP dan pesan "ready" di WebSocket lobby
+func acceptChallengeParticipant(challengeID uint, userID uint) *fiber.Error {
 	// 1. Ambil data partisipan
 	var participant models.ChallengeParticipant
-	if err := config.DB.Where("challenge_id = ? AND user_id = ?", id, userID).First(&participant).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "You are not in this challenge", nil)
+	if err := config.DB.Where("challenge_id = ? AND user_id = ?", challengeID, userID).First(&participant).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "You are not in this challenge")
 	}
 
 	if participant.Status != "pending" {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Already responded", nil)
+		return fiber.NewError(fiber.StatusBadRequest, "Already responded")
 	}
 
 	// 2. Ambil Data Challenge untuk cek WagerAmount
 	var challenge models.Challenge
 	if err := config.DB.First(&challenge, participant.ChallengeID).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Challenge data missing", nil)
+		return fiber.NewError(fiber.StatusNotFound, "Challenge data missing")
 	}
 
-	// --- [LOGIC BARU] Cek Saldo Penantang ---
-	if challenge.WagerAmount > 0 {
-		var user models.User
-		if err := config.DB.First(&user, uint(userID)).Error; err != nil {
-			return utils.ErrorResponse(c, fiber.StatusNotFound, "User data error", nil)
-		}
+	if challenge.Status != "pending" && challenge.Status != "active" {
+		return fiber.NewError(fiber.StatusBadRequest, "Challenge is no longer open")
+	}
+	if challenge.AcceptDeadline != nil && time.Now().After(*challenge.AcceptDeadline) {
+		return fiber.NewError(fiber.StatusBadRequest, "Tantangan sudah kedaluwarsa")
+	}
 
-		if user.Coins < challenge.WagerAmount {
-			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Koin kamu kurang untuk menerima taruhan ini!", nil)
+	// 3. Taruhan masuk escrow & status jadi Accepted dalam satu transaksi
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.ChallengeParticipant{}).
+			Where("id = ? AND status = ?", participant.ID, "pending").
+			Update("status", "accepted")
+		if res.Error != nil {
+			return res.Error
 		}
-
-		// Potong koin penantang
-		user.Coins -= challenge.WagerAmount
-		if err := config.DB.Save(&user).Error; err != nil {
-			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Gagal memproses pembayaran", err.Error())
+		if res.RowsAffected == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Already responded")
 		}
+		return utils.HoldWagerStake(tx, challenge, userID)
+	})
+	if errors.Is(err, utils.ErrInsufficientCoins) {
+		return fiber.NewError(fiber.StatusBadRequest, "Koin kamu kurang untuk menerima taruhan ini!")
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Gagal memproses pembayaran")
 	}
-	// ----------------------------------------
-
-	// 3. Update Status jadi Accepted
-	participant.Status = "accepted"
-	config.DB.Save(&participant)
 
 	// 4. Load ulang challenge dengan preload peserta (untuk broadcast)
 	config.DB.Preload("Participants.User").First(&challenge, participant.ChallengeID)
 
 	// A. Jika REALTIME
 	if challenge.IsRealtime {
-		utils.BroadcastLobby(challenge.ID, "player_update", fiber.Map{
-			"players": formatParticipants(challenge.Participants),
-		})
+		utils.BroadcastLobby(challenge.ID, "player_update", lobbyPlayersPayload(challenge))
 	} else {
 		// B. Jika ASYNC
 		if challenge.Status == "pending" {
@@ -274,7 +399,7 @@ func AcceptChallenge(c *fiber.Ctx) error {
 		}
 	}
 
-	return utils.SuccessResponse(c, fiber.StatusOK, "Challenge accepted!", nil)
+	return nil
 }
 
 func RejectChallenge(c *fiber.Ctx) error {
@@ -287,9 +412,35 @@ func RejectChallenge(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "You are not in this challenge", nil)
 	}
 
-	// 2. Set status partisipan jadi 'rejected'
-	participant.Status = "rejected"
-	config.DB.Save(&participant)
+	if participant.IsFinished {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Already played", nil)
+	}
+
+	// Hanya undangan yang belum dijawab & challenge yang belum dimulai yang bisa ditolak.
+	// Setelah match berjalan, keluar = kalah WO (ForfeitParticipant), taruhan tetap di escrow.
+	if participant.Status != "pending" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Challenge sudah diterima, gunakan forfeit untuk keluar", nil)
+	}
+
+	var current models.Challenge
+	if err := config.DB.Select("id", "status", "tournament_id").First(&current, participant.ChallengeID).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Challenge not found", nil)
+	}
+	// Match turnamen tidak bisa ditolak, pemain yang tidak main kalah WO saat deadline
+	if current.TournamentID != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Match turnamen tidak bisa ditolak", nil)
+	}
+	if current.Status != "pending" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Challenge sudah dimulai", nil)
+	}
+
+	// 2. Set status partisipan jadi 'rejected' (atomik: hanya dari pending)
+	res := config.DB.Model(&models.ChallengeParticipant{}).
+		Where("id = ? AND status = ?", participant.ID, "pending").
+		Update("status", "rejected")
+	if res.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Challenge sudah diterima atau ditolak", nil)
+	}
 
 	// 3. LOGIC BARU: Cek apakah Challenge harus dibatalkan sepenuhnya?
 	var challenge models.Challenge
@@ -303,27 +454,81 @@ func RejectChallenge(c *fiber.Ctx) error {
 				continue
 			}
 
-			if p.Status != "rejected" {
+			if p.Status == "pending" || p.Status == "accepted" {
 				allOpponentsRejected = false
 				break
 			}
 		}
 
 		if allOpponentsRejected {
-			challenge.Status = "rejected"
-			config.DB.Save(&challenge)
+			res := config.DB.Model(&models.Challenge{}).
+				Where("id = ? AND status = ?", challenge.ID, "pending").
+				Update("status", "rejected")
+			if res.RowsAffected > 0 {
+				// Taruhan creator dikembalikan
+				utils.RefundWagerEscrow(challenge.ID)
+			}
+		} else {
+			utils.FinishChallengeIfComplete(challenge.ID)
 		}
 
 		if challenge.IsRealtime {
-			utils.BroadcastLobby(challenge.ID, "player_update", fiber.Map{
-				"players": formatParticipants(challenge.Participants),
-			})
+			utils.BroadcastLobby(challenge.ID, "player_update", lobbyPlayersPayload(challenge))
 		}
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Challenge rejected", nil)
 }
 
+// CancelChallenge: host membatalkan challenge yang belum dimulai, semua taruhan dikembalikan
+func CancelChallenge(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userID := uint(c.Locals("user_id").(float64))
+
+	var challenge models.Challenge
+	if err := config.DB.Preload("Participants").First(&challenge, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Challenge not found", nil)
+	}
+
+	if challenge.CreatorID != userID {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Only host can cancel the challenge", nil)
+	}
+
+	if challenge.TournamentID != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Match turnamen tidak bisa dibatalkan", nil)
+	}
+
+	// Challenge async yang sudah ada pemain selesai tidak bisa dibatalkan
+	for _, p := range challenge.Participants {
+		if p.IsFinished {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Challenge already played", nil)
+		}
+	}
+
+	// Hanya lobby yang belum mulai atau challenge async yang belum dimainkan
+	if challenge.Status != "pending" && !(challenge.Status == "active" && !challenge.IsRealtime) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Challenge can no longer be cancelled", nil)
+	}
+
+	res := config.DB.Model(&models.Challenge{}).
+		Where("id = ? AND status = ?", challenge.ID, challenge.Status).
+		Update("status", "cancelled")
+	if res.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Challenge status changed, try again", nil)
+	}
+
+	utils.RefundWagerEscrow(challenge.ID)
+
+	for _, p := range challenge.Participants {
+		if p.UserID != userID && (p.Status == "pending" || p.Status == "accepted") {
+			utils.SendNotification(p.UserID, "info", "Challenge Dibatalkan", "Host membatalkan challenge.", "/challenges")
+		}
+	}
+	utils.BroadcastLobby(challenge.ID, "challenge_cancelled", fiber.Map{"challenge_id": challenge.ID})
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Challenge cancelled", nil)
+}
+
 func StreamChallengeLobby(c *fiber.Ctx) error {
 	idStr := c.Params("id")
 	challengeIDData, _ := strconv.Atoi(idStr)
@@ -332,55 +537,146 @@ func StreamChallengeLobby(c *fiber.Ctx) error {
 	userVal := c.Locals("user_id")
 	userID := uint(userVal.(float64))
 
+	var challenge models.Challenge
+	if err := config.DB.Preload("Participants.User").First(&challenge, challengeID).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Challenge not found", nil)
+	}
+
+	// Hanya peserta aktif yang masuk sebagai pemain; yang dikick / menolak hanya bisa menonton
+	isPlayer := false
+	for _, p := range challenge.Participants {
+		if p.UserID == userID && (p.Status == "pending" || p.Status == "accepted") {
+			isPlayer = true
+			break
+		}
+	}
+
+	// Bukan peserta: masuk sebagai penonton (read-only) jika host mengizinkan
+	if !isPlayer {
+		if !challenge.AllowSpectators {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Spectating is disabled for this challenge", nil)
+		}
+		return streamChallengeSpectator(c, challenge, userID)
+	}
+
 	c.Set("Content-Type", "text/event-stream")
 	c.Set("Cache-Control", "no-cache")
 	c.Set("Connection", "keep-alive")
 	c.Set("Transfer-Encoding", "chunked")
 
-	msgChan := utils.AddClientToLobby(challengeID, userID)
+	// Data awal langsung masuk buffer channel (diformat SSE oleh writer di bawah)
+	msgChan := utils.AddClientToLobby(challengeID, userID, utils.LobbyEvent{
+		Type: "player_update",
+		Data: lobbyPlayersPayload(challenge),
+	})
+	utils.CancelDisconnectForfeit(challengeID, userID)
 
-	// --- FIX: Kirim Data Awal dengan Format SSE yang Benar ---
-	var challenge models.Challenge
-	if err := config.DB.Preload("Participants.User").First(&challenge, challengeID).Error; err == nil {
-		go func() {
-			// Siapkan JSON data pemain
-			playersJSON, _ := json.Marshal(fiber.Map{
-				"players": formatParticipants(challenge.Participants),
-			})
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer func() {
+			utils.RemoveClientFromLobby(challengeID, userID, msgChan)
+			if !utils.IsInLobby(challengeID, userID) {
+				utils.ScheduleDisconnectForfeit(challengeID, userID)
+				utils.ScheduleHostMigration(challengeID, userID)
+			}
+		}()
 
-			// Format Manual: event: ... \n data: ... \n\n
-			initMsg := fmt.Sprintf("event: player_update\ndata: %s\n\n", string(playersJSON))
+		writeLobbySSE(w, msgChan)
+	})
 
-			// Kirim ke channel
-			msgChan <- initMsg
-		}()
-	}
+	return nil
+}
+
+// streamChallengeSpectator: stream SSE read-only untuk penonton. Penonton hanya menerima
+// event publik (progress, hasil ronde setelah ditutup, klasemen akhir), tidak pernah kunci jawaban.
+func streamChallengeSpectator(c *fiber.Ctx, challenge models.Challenge, userID uint) error {
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("Transfer-Encoding", "chunked")
+
+	payload := lobbyPlayersPayload(challenge)
+	payload["role"] = "spectator"
+	msgChan := utils.AddSpectatorToLobby(challenge.ID, userID, utils.LobbyEvent{
+		Type: "player_update",
+		Data: payload,
+	})
+	broadcastSpectatorCount(challenge.ID)
 
 	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
-		ticker := time.NewTicker(15 * time.Second)
-		defer ticker.Stop()
-		defer utils.RemoveClientFromLobby(challengeID, userID)
-
-		for {
-			select {
-			case msg, ok := <-msgChan:
-				if !ok {
-					return
-				}
-				// --- FIX: Tulis langsung msg (karena sudah diformat di BroadcastLobby/initMsg) ---
-				// Jangan pakai fmt.Fprintf(w, "data: %s\n\n", msg) lagi!
-				fmt.Fprint(w, msg)
-				w.Flush()
-
-			case <-ticker.C:
-				// Keepalive event
-				fmt.Fprintf(w, ":keepalive\n\n")
-				w.Flush()
+		defer func() {
+			utils.RemoveSpectatorFromLobby(challenge.ID, userID, msgChan)
+			broadcastSpectatorCount(challenge.ID)
+		}()
+
+		writeLobbySSE(w, msgChan)
+	})
+
+	return nil
+}
+
+// writeLobbySSE menulis event lobby ke stream SSE sampai channel ditutup atau client putus
+func writeLobbySSE(w *bufio.Writer, msgChan chan utils.LobbyEvent) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, ok := <-msgChan:
+			if !ok {
+				return
+			}
+			fmt.Fprint(w, msg.SSE())
+			if err := w.Flush(); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			// Keepalive event
+			fmt.Fprintf(w, ":keepalive\n\n")
+			if err := w.Flush(); err != nil {
+				return
 			}
 		}
+	}
+}
+
+func broadcastSpectatorCount(challengeID uint) {
+	utils.BroadcastLobby(challengeID, "spectator_update", fiber.Map{
+		"spectators": utils.SpectatorCount(challengeID),
 	})
+}
 
-	return nil
+type SpectatorSettingInput struct {
+	AllowSpectators bool `json:"allow_spectators"`
+}
+
+// UpdateSpectatorSetting: host mengatur apakah challenge boleh ditonton
+func UpdateSpectatorSetting(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userID := uint(c.Locals("user_id").(float64))
+
+	var input SpectatorSettingInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", nil)
+	}
+
+	var challenge models.Challenge
+	if err := config.DB.First(&challenge, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Challenge not found", nil)
+	}
+	if challenge.CreatorID != userID {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Only host can change spectator settings", nil)
+	}
+
+	challenge.AllowSpectators = input.AllowSpectators
+	config.DB.Model(&challenge).Update("allow_spectators", input.AllowSpectators)
+
+	if !input.AllowSpectators {
+		utils.KickAllSpectators(challenge.ID)
+		broadcastSpectatorCount(challenge.ID)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Spectator setting updated", challenge)
 }
 
 func StartGameRealtime(c *fiber.Ctx) error {
@@ -402,14 +698,40 @@ func StartGameRealtime(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Game already started", nil)
 	}
 
-	// 1. Ubah Status DB jadi Active
+	// Ready check: host dianggap ready saat menekan start
+	var participants []models.ChallengeParticipant
+	config.DB.Where("challenge_id = ? AND status = ?", challenge.ID, "accepted").Find(&participants)
+	readyCount := 0
+	for _, p := range participants {
+		if p.IsReady || p.UserID == challenge.CreatorID {
+			readyCount++
+		}
+	}
+	if minReady := minReadyPlayers(challenge.Mode); readyCount < minReady {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, fmt.Sprintf("Minimal %d pemain harus ready (baru %d)", minReady, readyCount), nil)
+	}
+
+	// 1. Ubah Status DB jadi Active (atomik supaya start tidak jalan dua kali)
+	res := config.DB.Model(&models.Challenge{}).
+		Where("id = ? AND status = ?", challenge.ID, "pending").
+		Updates(map[string]interface{}{"status": "active", "started_at": time.Now().Add(3 * time.Second)})
+	if res.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Game already started", nil)
+	}
 	challenge.Status = "active"
-	config.DB.Save(&challenge)
 
-	// Generate SEED jika Survival Mode
-	seed := ""
-	if challenge.Mode == "survival" {
-		seed = fmt.Sprintf("%d-%d", time.Now().UnixNano(), challenge.ID)
+	// Peserta yang tidak ada di lobby saat game dimulai tidak ikut dihitung
+	var absentees []models.ChallengeParticipant
+	config.DB.Where("challenge_id = ? AND status = ?", challenge.ID, "pending").Find(&absentees)
+	for _, p := range absentees {
+		config.DB.Model(&models.ChallengeParticipant{}).Where("id = ?", p.ID).Update("status", "expired")
+		utils.RefundWagerStake(challenge.ID, p.UserID)
+	}
+
+	// Seed survival disimpan di server; pemain mulai run lewat /survival/start dengan challenge_id
+	if challenge.Mode == "survival" && challenge.Seed == "" {
+		challenge.Seed = utils.NewSurvivalSeed()
+		config.DB.Model(&challenge).Update("seed", challenge.Seed)
 	}
 
 	// Safely dereference QuizID
@@ -422,19 +744,18 @@ func StartGameRealtime(c *fiber.Ctx) error {
 	utils.BroadcastLobby(challenge.ID, "start_countdown", fiber.Map{
 		"seconds": 3,
 		"mode":    challenge.Mode, // Biar frontend tau
-		"seed":    seed,           // Kirim seed di awal (opsional) atau pas start
 	})
 
 	// 3. Goroutine untuk kirim sinyal 'GO' setelah 3 detik
-	go func(chID uint, quizID uint, chMode string, chSeed string) {
+	go func(chID uint, quizID uint, chMode string) {
 		time.Sleep(3 * time.Second)
+		utils.LogMatchEvent(chID, 0, "game_start", models.ChallengeMatchEvent{})
 		utils.BroadcastLobby(chID, "game_start", fiber.Map{
 			"quiz_id": quizID,
 			"message": "Game Started!",
-			"seed":    chSeed, // PENTING: Seed dikirim saat game start
 			"mode":    chMode,
 		})
-	}(challenge.ID, quizIDVal, challenge.Mode, seed)
+	}(challenge.ID, quizIDVal, challenge.Mode)
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Countdown started", nil)
 }
@@ -444,10 +765,14 @@ func formatParticipants(parts []models.ChallengeParticipant) []map[string]interf
 	var result []map[string]interface{}
 	for _, p := range parts {
 		result = append(result, map[string]interface{}{
-			"user_id": p.UserID,
-			"name":    p.User.Name,
-			"status":  p.Status,
-			"team":    p.Team,
+			"user_id":    p.UserID,
+			"name":       p.User.Name,
+			"status":     p.Status,
+			"team":       p.Team,
+			"ready":      p.IsReady,
+			"eliminated": p.Eliminated,
+			"placement":  p.Placement,
+			"online":     utils.IsInLobby(p.ChallengeID, p.UserID),
 		})
 	}
 	return result
@@ -466,15 +791,24 @@ func UpdateChallengeProgress(c *fiber.Ctx) error {
 
 	userID := c.Locals("user_id").(float64)
 
-	var user = &models.User{}
-	if err := config.DB.First(&user, uint(userID)).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", nil)
-	}
 	var input ProgressInput
 	if err := c.BodyParser(&input); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", nil)
 	}
 
+	if err := broadcastChallengeProgress(challengeID, uint(userID), input); err != nil {
+		return utils.ErrorResponse(c, err.Code, err.Message, nil)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Progress updated", nil)
+}
+
+func broadcastChallengeProgress(challengeID uint, userID uint, input ProgressInput) *fiber.Error {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+
 	// Hitung persentase progress
 	percentage := 0
 	if input.TotalSoal > 0 {
@@ -488,7 +822,7 @@ func UpdateChallengeProgress(c *fiber.Ctx) error {
 		"index":    input.CurrentIndex,
 	})
 
-	return utils.SuccessResponse(c, fiber.StatusOK, "Progress updated", nil)
+	return nil
 }
 
 func LeaveLobby(c *fiber.Ctx) error {
@@ -497,26 +831,50 @@ func LeaveLobby(c *fiber.Ctx) error {
 	challengeID := uint(challengeIDData)
 	userID := c.Locals("user_id").(float64)
 
+	if err := leaveChallengeLobby(challengeID, uint(userID)); err != nil {
+		return utils.ErrorResponse(c, err.Code, err.Message, nil)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Left lobby successfully", nil)
+}
+
+func leaveChallengeLobby(challengeID uint, userID uint) *fiber.Error {
 	// 1. Ambil Partisipan
 	var participant models.ChallengeParticipant
 	if err := config.DB.Where("challenge_id = ? AND user_id = ?", challengeID, userID).First(&participant).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "You are not in this challenge", nil)
+		return fiber.NewError(fiber.StatusNotFound, "You are not in this challenge")
 	}
 
 	// 2. Ubah status kembali ke 'pending'
 	participant.Status = "pending"
+	participant.IsReady = false
 	if err := config.DB.Save(&participant).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update status", err.Error())
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update status")
 	}
 
+	// Host keluar: pindahkan host ke pemain lain supaya game tetap bisa dimulai
+	utils.MigrateLobbyHost(challengeID, userID)
+
 	// 3. Ambil data challenge terbaru untuk broadcast
+	broadcastLobbyPlayers(challengeID)
+
+	return nil
+}
+
+// broadcastLobbyPlayers mengirim ulang daftar pemain terbaru ke lobby
+func broadcastLobbyPlayers(challengeID uint) {
 	var challenge models.Challenge
 	if err := config.DB.Preload("Participants.User").First(&challenge, challengeID).Error; err == nil {
-		// Broadcast ke lobby bahwa ada update player (user ini jadi pending/ilang dari list ready)
-		utils.BroadcastLobby(challenge.ID, "player_update", fiber.Map{
-			"players": formatParticipants(challenge.Participants),
-		})
+		utils.BroadcastLobby(challenge.ID, "player_update", lobbyPlayersPayload(challenge))
 	}
+}
 
-	return utils.SuccessResponse(c, fiber.StatusOK, "Left lobby successfully", nil)
+// lobbyPlayersPayload adalah isi event player_update: pemain, host, penonton, dan setting lobby
+func lobbyPlayersPayload(challenge models.Challenge) fiber.Map {
+	return fiber.Map{
+		"players":    formatParticipants(challenge.Participants),
+		"host_id":    challenge.CreatorID,
+		"spectators": utils.SpectatorCount(challenge.ID),
+		"settings":   lobbySettingsPayload(challenge),
+	}
 }
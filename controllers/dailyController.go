@@ -1,16 +1,23 @@
 package controllers
 
 import (
+	"errors"
+
 	"github.com/ROFL1ST/quizzes-backend/config"
 	"github.com/ROFL1ST/quizzes-backend/models"
 	"github.com/ROFL1ST/quizzes-backend/utils"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func GetDailyInfo(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(float64)
 	today := utils.StripTime(utils.GetJakartaTime())
 
+	// Terapkan Streak Freeze / putus streak untuk hari yang terlewat sebelum ditampilkan
+	utils.SettleStreak(uint(userID))
+
 	var user models.User
 	if err := config.DB.First(&user, userID).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", nil)
@@ -72,14 +79,21 @@ func GetDailyInfo(c *fiber.Ctx) error {
 		}
 	}
 
-	// 3. Misi Harian
+	// 3. Misi Harian & Mingguan
 	utils.AssignDailyMissions(uint(userID))
+	utils.AssignWeeklyMissions(uint(userID))
+	weekStart := utils.WeekStart(utils.GetJakartaTime())
+
 	var userMissions []models.UserMission
 	config.DB.Preload("Mission").
-		Where("user_id = ? AND reset_date = ?", userID, today).
+		Joins("JOIN missions ON missions.id = user_missions.mission_id").
+		Where("user_missions.user_id = ?", userID).
+		Where("(missions.period = ? AND user_missions.reset_date = ?) OR (missions.period <> ? AND user_missions.reset_date = ?)",
+			"weekly", weekStart, "weekly", today).
 		Find(&userMissions)
 
-	var missionResponse []fiber.Map
+	missionResponse := []fiber.Map{}
+	weeklyResponse := []fiber.Map{}
 	for _, um := range userMissions {
 		status := "locked"
 		if um.IsClaimed {
@@ -88,15 +102,23 @@ func GetDailyInfo(c *fiber.Ctx) error {
 			status = "claimable"
 		}
 
-		missionResponse = append(missionResponse, fiber.Map{
+		payload := fiber.Map{
 			"id":          um.MissionID,
 			"title":       um.Mission.Title,
 			"description": um.Mission.Description,
 			"reward":      um.Mission.Reward,
+			"pass_points": um.Mission.PassPoints,
 			"target":      um.Mission.Target,
+			"rarity":      um.Mission.Rarity,
 			"progress":    um.Progress,
 			"status":      status,
-		})
+		}
+		if um.Mission.Period == "weekly" {
+			payload["resets_at"] = weekStart.AddDate(0, 0, 7)
+			weeklyResponse = append(weeklyResponse, payload)
+		} else {
+			missionResponse = append(missionResponse, payload)
+		}
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Info retrieved", fiber.Map{
@@ -107,7 +129,8 @@ func GetDailyInfo(c *fiber.Ctx) error {
 			"quiz_streak":  quizStreakDisplay, //
 			"is_quiz_done": isQuizDone,        //
 		},
-		"missions": missionResponse,
+		"missions":        missionResponse,
+		"weekly_missions": weeklyResponse,
 	})
 }
 
@@ -117,46 +140,66 @@ func ClaimLoginReward(c *fiber.Ctx) error {
 	// Gunakan Waktu Jakarta
 	today := utils.StripTime(utils.GetJakartaTime())
 
-	// 1. Cek Duplikat
-	var exists int64
-	config.DB.Model(&models.DailyClaim{}).
-		Where("user_id = ? AND reward_type = ? AND claimed_date = ?", userID, "login", today).
-		Count(&exists)
+	var user models.User
+	var rewardConfig models.DailyRewardConfig
 
-	if exists > 0 {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Login reward already claimed today", nil)
-	}
+	// Row user dikunci supaya klaim paralel tidak dapat hadiah dua kali
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
 
-	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", nil)
-	}
+		// 1. Cek Duplikat
+		var exists int64
+		tx.Model(&models.DailyClaim{}).
+			Where("user_id = ? AND reward_type = ? AND claimed_date = ?", userID, "login", today).
+			Count(&exists)
 
-	// 2. UPDATE LOGIN STREAK
-	// Panggil fungsi yang sudah kita buat di utils/streak.go
-	utils.UpdateLoginStreak(&user)
+		if exists > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Login reward already claimed today")
+		}
 
-	// 3. Hitung Hadiah
-	cycleDay := user.LoginStreak % 100
-	if cycleDay == 0 {
-		cycleDay = 100
-	}
+		// 2. UPDATE LOGIN STREAK
+		// Panggil fungsi yang sudah kita buat di utils/streak.go
+		utils.UpdateLoginStreak(&user)
 
-	var rewardConfig models.DailyRewardConfig
-	if err := config.DB.Where("day = ?", cycleDay).First(&rewardConfig).Error; err != nil {
-		rewardConfig.Reward = 20
-	}
+		// 3. Hitung Hadiah
+		cycleDay := user.LoginStreak % 100
+		if cycleDay == 0 {
+			cycleDay = 100
+		}
 
-	// 4. Simpan Log & Update User
-	newClaim := models.DailyClaim{
-		UserID:      user.ID,
-		RewardType:  "login",
-		ClaimedDate: today,
-	}
-	config.DB.Create(&newClaim)
+		if err := tx.Where("day = ?", cycleDay).First(&rewardConfig).Error; err != nil {
+			rewardConfig.Reward = 20
+		}
 
-	user.Coins += rewardConfig.Reward
-	config.DB.Save(&user) // Menyimpan LoginStreak, LastClaimDate, dan Coins
+		// 4. Simpan Log & Update User
+		newClaim := models.DailyClaim{
+			UserID:      user.ID,
+			RewardType:  "login",
+			ClaimedDate: today,
+		}
+		if err := tx.Create(&newClaim).Error; err != nil {
+			return err
+		}
+
+		// Koin hanya lewat ledger, Save tidak boleh menimpa saldo
+		if err := tx.Omit("coins").Save(&user).Error; err != nil {
+			return err
+		}
+		if err := utils.MoveCoins(tx, utils.AccountRewards, utils.UserAccount(user.ID), rewardConfig.Reward,
+			"daily_login", utils.LedgerRef{Type: "daily_claim", ID: newClaim.ID}); err != nil {
+			return err
+		}
+		return tx.Select("coins").First(&user, user.ID).Error
+	})
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return utils.ErrorResponse(c, fiberErr.Code, fiberErr.Message, nil)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to claim reward", err.Error())
+	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Login reward claimed", fiber.Map{
 		"coins_gained": rewardConfig.Reward,
@@ -174,11 +217,16 @@ func ClaimMissionReward(c *fiber.Ctx) error {
 	}
 	c.BodyParser(&input)
 
-	today := utils.StripTime(utils.GetJakartaTime())
+	now := utils.GetJakartaTime()
 	var um models.UserMission
 
-	// Cari misi spesifik yg ditugaskan hari ini
-	if err := config.DB.Preload("Mission").Where("user_id = ? AND mission_id = ? AND reset_date = ?", userID, input.MissionID, today).First(&um).Error; err != nil {
+	// Cari misi spesifik yg ditugaskan hari ini / minggu ini
+	if err := config.DB.Preload("Mission").
+		Joins("JOIN missions ON missions.id = user_missions.mission_id").
+		Where("user_missions.user_id = ? AND user_missions.mission_id = ?", userID, input.MissionID).
+		Where("(missions.period = ? AND user_missions.reset_date = ?) OR (missions.period <> ? AND user_missions.reset_date = ?)",
+			"weekly", utils.WeekStart(now), "weekly", utils.StripTime(now)).
+		First(&um).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Mission not active today", nil)
 	}
 
@@ -189,14 +237,33 @@ func ClaimMissionReward(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Not finished", nil)
 	}
 
-	// Klaim
-	um.IsClaimed = true
-	config.DB.Save(&um)
+	// Klaim atomik: hanya satu request yang bisa mengubah is_claimed false -> true
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.UserMission{}).
+			Where("id = ? AND is_claimed = ?", um.ID, false).
+			Update("is_claimed", true)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Already claimed")
+		}
+		if err := utils.AddSeasonPoints(tx, um.UserID, um.Mission.PassPoints); err != nil {
+			return err
+		}
+		return utils.MoveCoins(tx, utils.AccountRewards, utils.UserAccount(um.UserID), um.Mission.Reward,
+			"mission_reward", utils.LedgerRef{Type: "user_mission", ID: um.ID})
+	})
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return utils.ErrorResponse(c, fiberErr.Code, fiberErr.Message, nil)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to claim mission", err.Error())
+	}
 
 	var user models.User
-	config.DB.First(&user, userID)
-	user.Coins += um.Mission.Reward
-	config.DB.Save(&user)
+	config.DB.Select("coins").First(&user, userID)
 
-	return utils.SuccessResponse(c, fiber.StatusOK, "Mission claimed", fiber.Map{"new_coins": user.Coins, "reward": um.Mission.Reward})
+	return utils.SuccessResponse(c, fiber.StatusOK, "Mission claimed", fiber.Map{"new_coins": user.Coins, "reward": um.Mission.Reward, "pass_points": um.Mission.PassPoints})
 }
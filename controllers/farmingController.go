@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// --- ANTI-FARMING RULES (Supervisor) ---
+
+// GET /api/admin/config/anti-farming
+func GetAntiFarmingConfig(c *fiber.Ctx) error {
+	return utils.SuccessResponse(c, fiber.StatusOK, "Config retrieved", utils.GetAntiFarmingRules())
+}
+
+// PUT /api/admin/config/anti-farming
+func UpdateAntiFarmingConfig(c *fiber.Ctx) error {
+	// Mulai dari aturan sekarang supaya admin bisa mengirim sebagian field saja
+	rules := utils.GetAntiFarmingRules()
+	if err := c.BodyParser(&rules); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	if err := utils.SaveAntiFarmingRules(rules); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Anti-farming rules updated", utils.GetAntiFarmingRules())
+}
+
+// --- REVIEW QUEUE ---
+
+// GET /api/admin/farming-flags?status=pending&user_id=
+func GetFarmingFlags(c *fiber.Ctx) error {
+	params := utils.GetPaginationParams(c)
+
+	query := config.DB.Model(&models.FarmingFlag{}).Where("status = ?", c.Query("status", "pending"))
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var flags []models.FarmingFlag
+	if err := query.Preload("User").
+		Order("created_at ASC").
+		Offset(params.Offset).
+		Limit(params.PageSize).
+		Find(&flags).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch flags", err.Error())
+	}
+
+	return utils.PaginatedSuccessResponse(c, fiber.StatusOK, "Flags retrieved", flags, total, params)
+}
+
+// PUT /api/admin/farming-flags/:id
+// status "dismissed" melepas XP & progress misi yang ditahan, "confirmed" membiarkannya hangus
+func ReviewFarmingFlag(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(float64)
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var input struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+
+	flag, err := utils.ReviewFarmingFlag(uint(id), uint(adminID), input.Status, input.Note)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Flag not found", nil)
+	case errors.Is(err, utils.ErrFarmingFlagStatus):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, utils.ErrFarmingFlagReviewed):
+		return utils.ErrorResponse(c, fiber.StatusConflict, err.Error(), nil)
+	case err != nil:
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to review flag", err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Flag reviewed", flag)
+}
@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"strconv"
+
 	"github.com/ROFL1ST/quizzes-backend/config"
 	"github.com/ROFL1ST/quizzes-backend/models"
 	"github.com/ROFL1ST/quizzes-backend/utils"
@@ -56,14 +58,7 @@ func RequestFriend(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to send request", err.Error())
 	}
 
-	var sentCount int64
-	config.DB.Model(&models.Friendship{}).
-		Where("user_id = ?", userID).
-		Count(&sentCount)
-
-	if sentCount >= 5 {
-		utils.UnlockAchievement(uint(userID), 16)
-	}
+	utils.FireAchievementEvent(uint(userID), utils.AchievementFriendRequestSent)
 
 	utils.SendNotification(friend.ID, "info", "Permintaan Teman Baru", "👋 Permintaan teman baru dari "+input.Username, "/friends")
 	return utils.SuccessResponse(c, fiber.StatusCreated, "Friend request sent", nil)
@@ -87,16 +82,11 @@ func ConfirmFriend(c *fiber.Ctx) error {
 	friendship.Status = "accepted"
 	config.DB.Save(&friendship)
 
-	var friendCount int64
-	config.DB.Model(&models.Friendship{}).
-		Where("(user_id = ? OR friend_id = ?) AND status = 'accepted'", myID, myID).
-		Count(&friendCount)
-
-	if friendCount >= 3 {
-		utils.UnlockAchievement(uint(myID), 7)
-	}
-	utils.CheckDailyMissions(uint(myID), "social", 1, "add") // Yang menerima
-	utils.CheckDailyMissions(input.RequesterID, "social", 1, "add")   // Yang meminta
+	utils.FireAchievementEvent(uint(myID), utils.AchievementFriendAdded)
+	utils.FireAchievementEvent(input.RequesterID, utils.AchievementFriendAdded)
+	friendKey := "friendship:" + strconv.Itoa(int(friendship.ID))
+	utils.TrackMissionEvent(uint(myID), utils.MissionEvent{Type: utils.MissionFriendAdded, Key: friendKey, Value: 1})          // Yang menerima
+	utils.TrackMissionEvent(input.RequesterID, utils.MissionEvent{Type: utils.MissionFriendAdded, Key: friendKey, Value: 1}) // Yang meminta
 	return utils.SuccessResponse(c, fiber.StatusOK, "Friend request accepted", nil)
 }
 
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"bufio"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type GhostAnswerInput struct {
+	QuestionID uint   `json:"question_id"`
+	Answer     string `json:"answer"`
+}
+
+func ghostErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Not found", nil)
+	case errors.Is(err, utils.ErrGhostNotInChallenge):
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error(), nil)
+	case errors.Is(err, utils.ErrGhostAlreadyAnswered):
+		return utils.ErrorResponse(c, fiber.StatusConflict, err.Error(), nil)
+	case errors.Is(err, utils.ErrGhostNotEnabled),
+		errors.Is(err, utils.ErrGhostNotStarted),
+		errors.Is(err, utils.ErrGhostFinished),
+		errors.Is(err, utils.ErrGhostWrongQuiz):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Ghost race failed", err.Error())
+}
+
+// StartGhostRace: mulai main challenge ghost, waktu timeline dihitung dari sini
+func StartGhostRace(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	userID := uint(c.Locals("user_id").(float64))
+
+	participant, err := utils.StartGhostRun(uint(id), userID)
+	if err != nil {
+		return ghostErrorResponse(c, err)
+	}
+
+	// Skor ghost tidak dikirim di awal, hanya siapa dan berapa soal
+	var ghosts []fiber.Map
+	for _, g := range utils.GetGhostTimelines(uint(id), userID) {
+		ghosts = append(ghosts, fiber.Map{
+			"user_id":  g.UserID,
+			"username": g.Username,
+			"name":     g.Name,
+			"total":    len(g.Entries),
+		})
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Ghost race started", fiber.Map{
+		"started_at": participant.StartedAt,
+		"ghosts":     ghosts,
+	})
+}
+
+// AnswerGhostRace: catat satu jawaban ke timeline (skor akhir tetap dikirim lewat /history)
+func AnswerGhostRace(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	userID := uint(c.Locals("user_id").(float64))
+
+	var input GhostAnswerInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+
+	entry, err := utils.RecordGhostAnswer(uint(id), userID, input.QuestionID, input.Answer)
+	if err != nil {
+		return ghostErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Answer recorded", fiber.Map{
+		"position":   entry.Position,
+		"elapsed_ms": entry.ElapsedMs,
+		"correct":    entry.Correct,
+	})
+}
+
+// GetGhostTimelines: timeline lengkap semua peserta, hanya setelah user selesai main
+func GetGhostTimelines(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	userID := uint(c.Locals("user_id").(float64))
+
+	var participant models.ChallengeParticipant
+	if err := config.DB.Where("challenge_id = ? AND user_id = ?", id, userID).First(&participant).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You are not in this challenge", nil)
+	}
+	if !participant.IsFinished {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Selesaikan challenge dulu untuk melihat timeline lengkap", nil)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Timelines retrieved", fiber.Map{
+		"timelines": utils.GetGhostTimelines(uint(id), 0),
+	})
+}
+
+type ghostReplayEvent struct {
+	At    time.Duration
+	Event utils.LobbyEvent
+}
+
+// StreamGhostReplay: SSE yang memutar ulang progres ghost dengan tempo aslinya,
+// relatif terhadap waktu mulai user (atau sejak stream dibuka jika user sudah selesai).
+func StreamGhostReplay(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	userID := uint(c.Locals("user_id").(float64))
+
+	var challenge models.Challenge
+	if err := config.DB.First(&challenge, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Challenge not found", nil)
+	}
+	if !challenge.Ghost {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, utils.ErrGhostNotEnabled.Error(), nil)
+	}
+
+	var participant models.ChallengeParticipant
+	if err := config.DB.Where("challenge_id = ? AND user_id = ?", challenge.ID, userID).First(&participant).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You are not in this challenge", nil)
+	}
+
+	base := time.Now()
+	if participant.StartedAt != nil && !participant.IsFinished {
+		base = *participant.StartedAt
+	}
+
+	ghosts := utils.GetGhostTimelines(challenge.ID, userID)
+
+	var schedule []ghostReplayEvent
+	var intro []fiber.Map
+	for _, g := range ghosts {
+		total := len(g.Entries)
+		intro = append(intro, fiber.Map{"user_id": g.UserID, "username": g.Username, "name": g.Name, "total": total})
+
+		for _, e := range g.Entries {
+			schedule = append(schedule, ghostReplayEvent{
+				At: time.Duration(e.ElapsedMs) * time.Millisecond,
+				Event: utils.LobbyEvent{Type: "ghost_progress", Data: fiber.Map{
+					"user_id":    g.UserID,
+					"username":   g.Username,
+					"position":   e.Position,
+					"total":      total,
+					"progress":   e.Position * 100 / total,
+					"correct":    e.Correct,
+					"elapsed_ms": e.ElapsedMs,
+				}},
+			})
+		}
+
+		last := g.Entries[total-1]
+		schedule = append(schedule, ghostReplayEvent{
+			At: time.Duration(last.ElapsedMs) * time.Millisecond,
+			Event: utils.LobbyEvent{Type: "ghost_finished", Data: fiber.Map{
+				"user_id":    g.UserID,
+				"username":   g.Username,
+				"score":      g.Score,
+				"time_taken": g.TimeTaken,
+			}},
+		})
+	}
+	sort.SliceStable(schedule, func(i, j int) bool { return schedule[i].At < schedule[j].At })
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("Transfer-Encoding", "chunked")
+
+	msgChan := make(chan utils.LobbyEvent, 10)
+	done := make(chan struct{})
+
+	// Producer: kirim event sesuai jadwal; event yang sudah lewat (reconnect) langsung dikirim
+	go func() {
+		defer close(msgChan)
+
+		send := func(event utils.LobbyEvent) bool {
+			select {
+			case msgChan <- event:
+				return true
+			case <-done:
+				return false
+			}
+		}
+
+		if !send(utils.LobbyEvent{Type: "ghost_start", Data: fiber.Map{"started_at": base, "ghosts": intro}}) {
+			return
+		}
+		for _, item := range schedule {
+			if wait := time.Until(base.Add(item.At)); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-done:
+					return
+				}
+			}
+			if !send(item.Event) {
+				return
+			}
+		}
+		send(utils.LobbyEvent{Type: "replay_end", Data: fiber.Map{"challenge_id": challenge.ID}})
+	}()
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer close(done)
+		writeLobbySSE(w, msgChan)
+	})
+
+	return nil
+}
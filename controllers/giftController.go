@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type SendGiftInput struct {
+	Username string `json:"username"` // Username teman penerima
+	Type     string `json:"type"`     // coins, item
+	Amount   int    `json:"amount"`
+	ItemID   uint   `json:"item_id"`
+	Quantity int    `json:"quantity"`
+	Message  string `json:"message"`
+}
+
+func giftErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, utils.ErrInsufficientCoins):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Koin kamu tidak cukup", nil)
+	case errors.Is(err, utils.ErrGiftNotFriends):
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Hadiah hanya bisa dikirim ke teman", nil)
+	case errors.Is(err, utils.ErrGiftDailyLimit), errors.Is(err, utils.ErrGiftReceiverLimit):
+		return utils.ErrorResponse(c, fiber.StatusTooManyRequests, "Batas hadiah hari ini sudah tercapai", err.Error())
+	case errors.Is(err, utils.ErrItemOwned):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Temanmu sudah memiliki item ini", nil)
+	case errors.Is(err, utils.ErrShopLevelLocked), errors.Is(err, utils.ErrShopAchievementLocked):
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Temanmu belum memenuhi syarat item ini", nil)
+	case errors.Is(err, utils.ErrItemStackFull):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Stok item temanmu sudah maksimal", nil)
+	case errors.Is(err, utils.ErrGiftSelf), errors.Is(err, utils.ErrGiftFriendshipTooNew),
+		errors.Is(err, utils.ErrGiftAccountTooNew), errors.Is(err, utils.ErrGiftLevelTooLow),
+		errors.Is(err, utils.ErrGiftInvalidAmount), errors.Is(err, utils.ErrGiftItemNotAvailable),
+		errors.Is(err, utils.ErrGiftMessageTooLong), errors.Is(err, utils.ErrGiftReceiverNotActive):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to send gift", err.Error())
+}
+
+// POST /api/gifts
+func SendGift(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+
+	var input SendGiftInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+
+	var receiver models.User
+	if err := config.DB.Select("id").Where("username = ?", input.Username).First(&receiver).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", nil)
+	}
+
+	gift, err := utils.SendGift(userID, receiver.ID, utils.GiftInput{
+		Type:     input.Type,
+		Amount:   input.Amount,
+		ItemID:   input.ItemID,
+		Quantity: input.Quantity,
+		Message:  input.Message,
+	})
+	if err != nil {
+		return giftErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Gift sent", fiber.Map{
+		"gift":   gift,
+		"limits": utils.GetGiftLimits(config.DB, userID),
+	})
+}
+
+// GET /api/gifts?direction=received|sent
+func GetMyGifts(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	params := utils.GetPaginationParams(c)
+
+	query := config.DB.Model(&models.Gift{})
+	if c.Query("direction") == "sent" {
+		query = query.Where("sender_id = ?", userID)
+	} else {
+		query = query.Where("receiver_id = ?", userID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to count gifts", err.Error())
+	}
+
+	var gifts []models.Gift
+	if err := query.Preload("Item").
+		Preload("Sender", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name", "username") }).
+		Preload("Receiver", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name", "username") }).
+		Order("id DESC").
+		Offset(params.Offset).
+		Limit(params.PageSize).
+		Find(&gifts).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch gifts", err.Error())
+	}
+
+	return utils.PaginatedSuccessResponse(c, fiber.StatusOK, "Gifts retrieved", gifts, total, params)
+}
+
+// GET /api/gifts/limits
+func GetGiftLimits(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	return utils.SuccessResponse(c, fiber.StatusOK, "Gift limits retrieved", utils.GetGiftLimits(config.DB, userID))
+}
@@ -2,10 +2,9 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"math"
-	"sort"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -25,9 +24,10 @@ type CreateHistoryInput struct {
 	Snapshot     json.RawMessage `json:"snapshot"`
 	TimeTaken    int             `json:"time_taken"`
 	ChallengeID  uint            `json:"challenge_id"`
-	QuestionIDs  []uint          `json:"question_ids"`
+	QuestionIDs  []uint          `json:"question_ids"`  // Remedial dari client lama (tanpa attempt)
 	AssignmentID *uint           `json:"assignment_id"` // New
 	ClassroomID  *uint           `json:"classroom_id"`  // New
+	AttemptID    uint            `json:"attempt_id"`    // Attempt dari /api/quizzes/:id/attempts atau /api/quizzes/remedial/start; tanpa attempt = tanpa XP
 }
 
 func SaveHistory(c *fiber.Ctx) error {
@@ -41,17 +41,53 @@ func SaveHistory(c *fiber.Ctx) error {
 	// =================================================================
 	// 1. LOGIKA PENILAIAN (GRADING)
 	// =================================================================
+	// Survival (QuizID == 0 tanpa attempt & daftar soal) disimpan otomatis oleh server saat run selesai
+	if input.QuizID == 0 && input.AttemptID == 0 && len(input.QuestionIDs) == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Survival result is saved by the server, use /api/survival endpoints", nil)
+	}
+	// Skor challenge (pemenang, taruhan, tie-break waktu) hanya dari waktu server
+	if input.AttemptID == 0 && input.ChallengeID != 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "attempt_id is required for challenges, start the quiz via /api/quizzes/:id/attempts", nil)
+	}
+
+	// Client lama tanpa attempt: hasil tetap disimpan, tapi tanpa XP, progress misi & achievement
+	legacy := input.AttemptID == 0
+
+	// Attempt ditutup di server; soal yang di-skip (power-up) tetap dihitung sebagai salah
+	skipped := map[uint]bool{}
+	var attempt models.QuizAttempt
+	if !legacy {
+		var challengeID *uint
+		if input.ChallengeID != 0 {
+			challengeID = &input.ChallengeID
+		}
+		submission, err := utils.SubmitQuizAttempt(uint(userID), input.AttemptID, input.QuizID, challengeID, input.AssignmentID)
+		if errors.Is(err, utils.ErrAttemptExpired) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Waktu pengerjaan sudah habis", err.Error())
+		}
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid quiz attempt", err.Error())
+		}
+		skipped = submission.Skipped
+		attempt = submission.Attempt
+	}
+
 	var questions []models.Question
 	if input.QuizID != 0 {
 		// Kuis Normal
 		if err := config.DB.Where("quiz_id = ?", input.QuizID).Find(&questions).Error; err != nil {
 			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch questions", err.Error())
 		}
-	} else if len(input.QuestionIDs) > 0 {
-		// Remedial (Ambil dari list ID)
+	} else if legacy {
+		// Remedial client lama (Ambil dari list ID)
 		if err := config.DB.Where("id IN ?", input.QuestionIDs).Find(&questions).Error; err != nil {
 			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch remedial questions", err.Error())
 		}
+	} else {
+		// Remedial: soal diambil dari attempt yang dibuat server
+		if err := config.DB.Where("id IN ?", []int64(attempt.QuestionIDs)).Find(&questions).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch remedial questions", err.Error())
+		}
 	}
 	questionMap := make(map[uint]models.Question)
 	for _, q := range questions {
@@ -62,60 +98,22 @@ func SaveHistory(c *fiber.Ctx) error {
 	if err := json.Unmarshal(input.Snapshot, &userAnswers); err != nil {
 		userAnswers = make(map[string]string)
 	}
+	// Jawaban untuk soal yang di-skip diabaikan: tidak dinilai benar & tidak masuk statistik soal,
+	// tapi soalnya tetap di penyebut supaya skip tidak bisa mendongkrak nilai
+	for qIDStr := range userAnswers {
+		if qID, _ := strconv.Atoi(qIDStr); skipped[uint(qID)] {
+			delete(userAnswers, qIDStr)
+		}
+	}
 
 	correctCount := 0
-	totalQuestions := len(questions)
+	totalQuestions := len(questionMap)
 
 	// Hitung Benar/Salah
 	for qIDStr, answer := range userAnswers {
 		qID, _ := strconv.Atoi(qIDStr)
 		if q, exists := questionMap[uint(qID)]; exists {
-			isCorrect := false
-
-			switch q.Type {
-			case "short_answer":
-				// Case Insensitive & Trim Space
-				userAns := strings.ToLower(strings.TrimSpace(answer))
-				correctAns := strings.ToLower(strings.TrimSpace(q.CorrectAnswer))
-				if userAns == correctAns {
-					isCorrect = true
-				}
-
-			case "multi_select":
-				var userAns []string
-				var correctAns []string
-
-				err1 := json.Unmarshal([]byte(answer), &userAns)
-				err2 := json.Unmarshal([]byte(q.CorrectAnswer), &correctAns)
-
-				if err1 == nil && err2 == nil {
-					if len(userAns) == len(correctAns) {
-						sort.Strings(userAns)
-						sort.Strings(correctAns)
-
-						match := true
-						for i := range userAns {
-							if userAns[i] != correctAns[i] {
-								match = false
-								break
-							}
-						}
-						if match {
-							isCorrect = true
-						}
-					}
-				}
-
-			case "boolean", "mcq":
-				if answer == q.CorrectAnswer {
-					isCorrect = true
-				}
-
-			default:
-				if answer == q.CorrectAnswer {
-					isCorrect = true
-				}
-			}
+			isCorrect := utils.IsAnswerCorrect(q, answer)
 
 			if isCorrect {
 				correctCount++
@@ -123,17 +121,51 @@ func SaveHistory(c *fiber.Ctx) error {
 		}
 	}
 
-	// Hitung Final Score (0-100)
+	// Hitung Final Score (0-100), kuis normal & remedial dinilai di server
 	finalScore := 0
-	// Survival Mode (QuizID == 0): Trust Client Score (Streak)
-	if input.QuizID == 0 {
-		finalScore = input.Score
-		totalQuestions = input.TotalSoal
-	} else {
-		if totalQuestions > 0 {
-			finalScore = int(math.Round(float64(correctCount) / float64(totalQuestions) * 100))
+	if totalQuestions > 0 {
+		finalScore = int(math.Round(float64(correctCount) / float64(totalQuestions) * 100))
+	}
+
+	// Waktu pengerjaan diukur server sejak attempt dibuka. time_taken kiriman client hanya dipakai
+	// untuk tampilan di submission lama, yang memang tidak memberi XP & tidak bisa ikut challenge.
+	timeTaken := input.TimeTaken
+	if !legacy {
+		timeTaken = max(1, int(time.Since(attempt.CreatedAt).Seconds()))
+	}
+
+	// Hitung XP sebelum history disimpan supaya pengulangan dihitung dari riwayat sebelumnya
+	xpInput := utils.QuizXPInput{
+		UserID:    uint(userID),
+		QuizID:    input.QuizID,
+		SetKey:    attempt.QuestionSetKey,
+		Score:     finalScore,
+		TimeTaken: timeTaken,
+	}
+	for _, q := range questionMap {
+		xpInput.Questions = append(xpInput.Questions, q)
+	}
+	if input.QuizID != 0 {
+		var quiz models.Quiz
+		if config.DB.Select("topic_id").First(&quiz, input.QuizID).Error == nil {
+			xpInput.TopicID = quiz.TopicID
 		}
 	}
+	var xpBreakdown utils.XPBreakdown
+	var farmingReason, farmingDetails string
+	if legacy {
+		xpBreakdown = utils.XPBreakdown{NoAttempt: true, Events: []string{}}
+	} else {
+		xpBreakdown = utils.CalculateQuizXP(xpInput)
+		farmingReason, farmingDetails = utils.DetectFarming(totalQuestions, timeTaken, correctCount == totalQuestions, utils.GetAntiFarmingRules())
+		xpBreakdown.Flagged = farmingReason != ""
+	}
+
+	xpGained := xpBreakdown.Total
+	if xpBreakdown.Flagged {
+		xpGained = 0
+	}
+	xpBreakdownJSON, _ := json.Marshal(xpBreakdown)
 
 	history := models.History{
 		UserID:       uint(userID),
@@ -141,54 +173,43 @@ func SaveHistory(c *fiber.Ctx) error {
 		QuizTitle:    input.QuizTitle,
 		Score:        finalScore,
 		Snapshot:     datatypes.JSON(input.Snapshot),
-		TimeTaken:    input.TimeTaken,
+		TimeTaken:    timeTaken,
 		TotalSoal:    totalQuestions,
 		AssignmentID: input.AssignmentID, // Save field
 		ClassroomID:  input.ClassroomID,  // Save field
+		XPGained:     xpGained,
+		XPBreakdown:  datatypes.JSON(xpBreakdownJSON),
+		Flagged:      xpBreakdown.Flagged,
+
+		QuestionSetKey: attempt.QuestionSetKey,
 	}
 
 	if err := config.DB.Create(&history).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed save history", err.Error())
 	}
+	if !legacy {
+		config.DB.Model(&models.QuizAttempt{}).Where("id = ?", attempt.ID).Update("history_id", history.ID)
+	}
+	if xpBreakdown.Flagged {
+		// XP & progress misi ditahan sampai admin me-review
+		utils.FlagFarming(models.FarmingFlag{
+			UserID:          history.UserID,
+			HistoryID:       &history.ID,
+			Source:          utils.XPSourceQuiz,
+			Reason:          farmingReason,
+			Details:         farmingDetails,
+			WithheldXP:      xpBreakdown.Total,
+			MissionWithheld: !xpBreakdown.Cooldown,
+		})
+	}
 
-	// A. Update Misi Harian
-	go func(uid uint, score int) {
-		today := utils.StripTime(time.Now())
-		var activeMissions []models.UserMission
-		config.DB.Preload("Mission").
-			Where("user_id = ? AND reset_date = ?", uid, today).
-			Find(&activeMissions)
-
-		for _, um := range activeMissions {
-			if um.IsClaimed {
-				continue
-			}
-
-			key := um.Mission.Key
-			shouldSave := false
-
-			if key == "play_quiz_1" || key == "play_quiz_3" || key == "play_quiz_5" {
-				um.Progress++
-				shouldSave = true
-			} else if key == "score_100" && score == 100 {
-				um.Progress++
-				shouldSave = true
-			} else if key == "total_score_500" {
-				um.Progress += score
-				shouldSave = true
-			}
-
-			if shouldSave {
-				config.DB.Save(&um)
-			}
-		}
-	}(uint(userID), finalScore)
+	// A. Misi harian diproses sekali oleh utils.TrackMissionEvent di akhir
 
 	var currentUser models.User
 	if err := config.DB.First(&currentUser, uint(userID)).Error; err == nil {
 
 		// utils.UpdateQuizStreak(&currentUser)
-		config.DB.Save(&currentUser)
+		config.DB.Omit("coins").Save(&currentUser)
 	}
 	if err := config.DB.First(&currentUser, uint(userID)).Error; err == nil {
 		// Broadcast Lobby (Realtime) - Memberitahu pemain lain bahwa user ini selesai
@@ -213,42 +234,8 @@ func SaveHistory(c *fiber.Ctx) error {
 			return
 		}
 
-		var participant models.ChallengeParticipant
-
-		// Cari partisipan spesifik untuk challenge ini
-		if err := config.DB.Where("challenge_id = ? AND user_id = ?", challengeID, uid).First(&participant).Error; err != nil {
-			return // Data partisipan tidak ditemukan, abaikan
-		}
-
-		// Update Score & Status Selesai User Ini
-		participant.Score = score
-		participant.TimeTaken = timeTaken
-		participant.IsFinished = true
-		config.DB.Save(&participant)
-
-		// Cek apakah SEMUA peserta (accepted) sudah selesai?
-		var challenge models.Challenge
-		if err := config.DB.Preload("Participants").First(&challenge, challengeID).Error; err == nil {
-			allFinished := true
-
-			// Loop semua peserta
-			for _, p := range challenge.Participants {
-				// Hanya cek yang sudah ACCEPT challenge (yang pending/reject ga dihitung)
-				if p.Status == "accepted" {
-					if !p.IsFinished {
-						allFinished = false
-						break
-					}
-				}
-			}
-
-			// Jika semua sudah selesai, tutup challenge & tentukan pemenang
-			if allFinished {
-				challenge.Status = "finished"
-				config.DB.Save(&challenge)
-				utils.DetermineWinner(challenge.ID)
-			}
-		}
+		// Simpan skor; jika semua peserta sudah selesai, tutup challenge & tentukan pemenang
+		utils.SubmitChallengeScore(challengeID, uid, score, timeTaken)
 	}(uint(userID), finalScore, history.TimeTaken, input.ChallengeID)
 
 	// C. Update Statistik Soal
@@ -256,35 +243,7 @@ func SaveHistory(c *fiber.Ctx) error {
 		for qIDStr, answer := range uAns {
 			qID, _ := strconv.Atoi(qIDStr)
 			if q, exists := qMap[uint(qID)]; exists {
-				isCorrect := false
-				switch q.Type {
-				case "short_answer":
-					if strings.EqualFold(strings.TrimSpace(answer), strings.TrimSpace(q.CorrectAnswer)) {
-						isCorrect = true
-					}
-				case "multi_select":
-					var ua, ca []string
-					json.Unmarshal([]byte(answer), &ua)
-					json.Unmarshal([]byte(q.CorrectAnswer), &ca)
-					if len(ua) == len(ca) {
-						sort.Strings(ua)
-						sort.Strings(ca)
-						match := true
-						for i := range ua {
-							if ua[i] != ca[i] {
-								match = false
-								break
-							}
-						}
-						if match {
-							isCorrect = true
-						}
-					}
-				default:
-					if answer == q.CorrectAnswer {
-						isCorrect = true
-					}
-				}
+				isCorrect := utils.IsAnswerCorrect(q, answer)
 
 				if isCorrect {
 					config.DB.Model(&models.Question{}).Where("id = ?", qID).UpdateColumn("correct_count", gorm.Expr("correct_count + 1"))
@@ -296,30 +255,28 @@ func SaveHistory(c *fiber.Ctx) error {
 	}(questionMap, userAnswers)
 
 	// D. Level Up & Notification
-	if currentUser.ID != 0 {
-		xpGained := finalScore
-		currentUser.XP += int64(xpGained)
-		newLevel := utils.CalculateLevel(currentUser.XP)
-
-		if newLevel > currentUser.Level {
-			currentUser.Level = newLevel
-			activity := models.Activity{UserID: currentUser.ID, Type: "level_up", Description: "Naik ke Level " + strconv.Itoa(newLevel)}
-			config.DB.Create(&activity)
-
-			utils.SendNotification(currentUser.ID, "success", "Naik Level!", "⭐ Level Up! Kamu naik ke Level "+strconv.Itoa(newLevel), "/@"+currentUser.Username)
-			utils.CheckDailyMissions(currentUser.ID, "level", 0, "levelup")
+	if currentUser.ID != 0 && !xpBreakdown.Flagged && !legacy {
+		granted := utils.AwardXP(currentUser.ID, utils.XPSourceQuiz, xpBreakdown.Total)
+		if granted < xpBreakdown.Total {
+			xpBreakdown.DailyCapped = xpBreakdown.Total - granted
+			xpBreakdownJSON, _ = json.Marshal(xpBreakdown)
+			history.XPGained = granted
+			history.XPBreakdown = datatypes.JSON(xpBreakdownJSON)
+			config.DB.Model(&history).Updates(map[string]interface{}{"xp_gained": granted, "xp_breakdown": history.XPBreakdown})
 		}
-		// utils.UpdateQuizStreak(&currentUser)
-		config.DB.Save(&currentUser)
 	}
 
-	go func() {
-		wg.Wait()
-		utils.CheckQuizAchievements(history.UserID, finalScore)
-	}()
+	if !legacy {
+		go func() {
+			wg.Wait()
+			utils.FireAchievementEvent(history.UserID, utils.AchievementQuizFinished)
+		}()
+	}
 	utils.RecordActivity(uint(userID))
-	utils.CheckDailyMissions(currentUser.ID, "quiz", finalScore, history.QuizTitle)
-	utils.CheckDailyMissions(currentUser.ID, "level", finalScore, "xp_gain")
+	// Kuis yang diulang dalam cooldown, di-flag, atau dikirim tanpa attempt tidak memajukan misi
+	if !xpBreakdown.Cooldown && !xpBreakdown.Flagged && !legacy {
+		utils.TrackMissionEvent(history.UserID, utils.QuizFinishedMissionEvent(history))
+	}
 
 	return utils.SuccessResponse(c, fiber.StatusCreated, "History saved", history)
 }
@@ -50,7 +50,7 @@ func GetLeaderboardByTopic(c *fiber.Ctx) error {
 
 		results[i].EquippedItems = items
 	}
-	utils.CheckDailyMissions(uint(userID), "social", 1, "view")
+	utils.TrackMissionEvent(uint(userID), utils.MissionEvent{Type: utils.MissionLeaderboardView, Value: 1})
 	return utils.SuccessResponse(c, fiber.StatusOK, "Leaderboard retrieved", results)
 }
 
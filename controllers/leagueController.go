@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GET /api/leagues/current
+// Klasemen cohort liga user minggu ini beserta zona naik & turun
+func GetCurrentLeague(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	week := utils.WeekStart(utils.GetJakartaTime())
+	endsAt := week.AddDate(0, 0, 7)
+
+	var me models.LeagueMember
+	if err := config.DB.Where("user_id = ? AND week_start = ?", userID, week).First(&me).Error; err != nil {
+		// Belum dapat XP minggu ini: belum masuk cohort
+		tier := utils.NextLeagueTier(userID, week)
+		return utils.SuccessResponse(c, fiber.StatusOK, "Dapatkan XP untuk bergabung ke liga minggu ini", fiber.Map{
+			"joined":    false,
+			"tier":      tier,
+			"tier_name": utils.LeagueTierName(tier),
+			"ends_at":   endsAt,
+			"ends_in":   int(time.Until(endsAt).Seconds()),
+		})
+	}
+
+	var members []models.LeagueMember
+	config.DB.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "name", "username", "level", "title")
+	}).Where("cohort_id = ?", me.CohortID).Order("weekly_xp DESC, updated_at ASC").Find(&members)
+
+	promote, demote := utils.LeagueMovement(me.Tier, len(members))
+
+	standings := []fiber.Map{}
+	myRank := 0
+	for i, m := range members {
+		rank := i + 1
+		zone := "safe"
+		if rank <= promote {
+			zone = "promotion"
+		} else if rank > len(members)-demote {
+			zone = "demotion"
+		}
+		if m.UserID == userID {
+			myRank = rank
+		}
+
+		standings = append(standings, fiber.Map{
+			"rank":      rank,
+			"user_id":   m.UserID,
+			"name":      m.User.Name,
+			"username":  m.User.Username,
+			"level":     m.User.Level,
+			"title":     m.User.Title,
+			"weekly_xp": m.WeeklyXP,
+			"zone":      zone,
+		})
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "League retrieved", fiber.Map{
+		"joined":        true,
+		"tier":          me.Tier,
+		"tier_name":     utils.LeagueTierName(me.Tier),
+		"my_rank":       myRank,
+		"my_weekly_xp":  me.WeeklyXP,
+		"promote_count": promote,
+		"demote_count":  demote,
+		"ends_at":       endsAt,
+		"ends_in":       int(time.Until(endsAt).Seconds()),
+		"standings":     standings,
+	})
+}
+
+// GET /api/leagues/history
+func GetLeagueHistory(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+
+	var history []models.LeagueMember
+	config.DB.Where("user_id = ? AND result <> ''", userID).Order("week_start DESC").Limit(20).Find(&history)
+
+	response := []fiber.Map{}
+	for _, h := range history {
+		response = append(response, fiber.Map{
+			"week_start": h.WeekStart,
+			"tier":       h.Tier,
+			"tier_name":  utils.LeagueTierName(h.Tier),
+			"weekly_xp":  h.WeeklyXP,
+			"rank":       h.Rank,
+			"result":     h.Result,
+			"reward":     h.Reward,
+		})
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "League history retrieved", response)
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// lobbyChatError memetakan error chat ke status HTTP (dipakai HTTP & WebSocket)
+func lobbyChatError(err error) *fiber.Error {
+	switch {
+	case errors.Is(err, utils.ErrChatRateLimited):
+		return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+	case errors.Is(err, utils.ErrChatNotInLobby):
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	case errors.Is(err, utils.ErrChatEmpty), errors.Is(err, utils.ErrChatTooLong), errors.Is(err, utils.ErrChatUnknownEmote):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, "Failed to send message")
+}
+
+// SendLobbyChat: kirim chat ke lobby (untuk client SSE; client WebSocket pakai pesan "chat")
+func SendLobbyChat(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	userID := uint(c.Locals("user_id").(float64))
+
+	var input LobbyChatInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", nil)
+	}
+
+	msg, err := utils.SendLobbyChat(uint(id), userID, input.Message)
+	if err != nil {
+		fiberErr := lobbyChatError(err)
+		return utils.ErrorResponse(c, fiberErr.Code, fiberErr.Message, nil)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Message sent", utils.LobbyMessagePayload(msg))
+}
+
+// SendLobbyEmote: kirim emote ke lobby (untuk client SSE)
+func SendLobbyEmote(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	userID := uint(c.Locals("user_id").(float64))
+
+	var input LobbyEmoteInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", nil)
+	}
+
+	msg, err := utils.SendLobbyEmote(uint(id), userID, input.Emote)
+	if err != nil {
+		fiberErr := lobbyChatError(err)
+		return utils.ErrorResponse(c, fiberErr.Code, fiberErr.Message, nil)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Emote sent", utils.LobbyMessagePayload(msg))
+}
+
+// GetLobbyChat: riwayat chat lobby untuk peserta dan penonton
+func GetLobbyChat(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userID := uint(c.Locals("user_id").(float64))
+
+	var challenge models.Challenge
+	if err := config.DB.First(&challenge, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Challenge not found", nil)
+	}
+
+	var participants int64
+	config.DB.Model(&models.ChallengeParticipant{}).
+		Where("challenge_id = ? AND user_id = ?", challenge.ID, userID).
+		Count(&participants)
+	if participants == 0 && !challenge.AllowSpectators {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You are not in this challenge", nil)
+	}
+
+	var messages []models.LobbyMessage
+	config.DB.Preload("User").
+		Where("challenge_id = ?", challenge.ID).
+		Order("id DESC").
+		Limit(100).
+		Find(&messages)
+
+	// Urutkan dari yang terlama supaya langsung bisa ditampilkan
+	history := make([]map[string]interface{}, 0, len(messages))
+	for i := len(messages) - 1; i >= 0; i-- {
+		history = append(history, utils.LobbyMessagePayload(messages[i]))
+	}
+
+	emotes := make([]string, 0, len(utils.LobbyEmotes))
+	for emote := range utils.LobbyEmotes {
+		emotes = append(emotes, emote)
+	}
+	sort.Strings(emotes)
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Chat retrieved", fiber.Map{
+		"messages": history,
+		"emotes":   emotes,
+	})
+}
@@ -0,0 +1,437 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type LobbyReadyInput struct {
+	Ready bool `json:"ready"`
+}
+
+type KickLobbyInput struct {
+	UserID uint `json:"user_id"`
+}
+
+type LobbySettingsInput struct {
+	QuizID    *uint   `json:"quiz_id"`
+	TimeLimit *int    `json:"time_limit"`
+	Mode      *string `json:"mode"`
+
+	DisablePowerups *bool `json:"disable_powerups"`
+}
+
+var lobbyModes = map[string]bool{
+	"1v1":      true,
+	"2v2":      true,
+	"survival": true,
+
+	utils.BattleRoyaleMode: true,
+}
+
+// minReadyPlayers: jumlah pemain ready minimal (host dihitung ready) sebelum game boleh dimulai
+func minReadyPlayers(mode string) int {
+	switch mode {
+	case "2v2":
+		return 4
+	case utils.BattleRoyaleMode:
+		return utils.MinBattleRoyalePlayers
+	}
+	return 2
+}
+
+func lobbySettingsPayload(challenge models.Challenge) fiber.Map {
+	settings := fiber.Map{
+		"quiz_id":          challenge.QuizID,
+		"time_limit":       challenge.TimeLimit,
+		"mode":             challenge.Mode,
+		"wager_amount":     challenge.WagerAmount,
+		"allow_spectators": challenge.AllowSpectators,
+		"visibility":       challenge.Visibility,
+		"max_players":      challenge.MaxPlayers,
+		"disable_powerups": challenge.DisablePowerups,
+	}
+	if challenge.Mode == utils.BattleRoyaleMode {
+		settings["elimination_rule"] = challenge.EliminationRule
+		settings["eliminate_per_round"] = challenge.EliminatePerRound
+		settings["safe_rounds"] = challenge.SafeRounds
+	}
+	return settings
+}
+
+// SetLobbyReady: pemain menandai siap / batal siap di lobby
+func SetLobbyReady(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	userID := c.Locals("user_id").(float64)
+
+	input := LobbyReadyInput{Ready: true}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", nil)
+		}
+	}
+
+	if err := setLobbyReady(uint(id), uint(userID), input.Ready); err != nil {
+		return utils.ErrorResponse(c, err.Code, err.Message, nil)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Ready status updated", fiber.Map{"ready": input.Ready})
+}
+
+func setLobbyReady(challengeID uint, userID uint, ready bool) *fiber.Error {
+	var participant models.ChallengeParticipant
+	if err := config.DB.Where("challenge_id = ? AND user_id = ?", challengeID, userID).First(&participant).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "You are not in this challenge")
+	}
+
+	var challenge models.Challenge
+	if err := config.DB.First(&challenge, challengeID).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Challenge not found")
+	}
+	if challenge.Status != "pending" {
+		return fiber.NewError(fiber.StatusBadRequest, "Game already started")
+	}
+
+	// Ready sekaligus menerima undangan (perilaku lama pesan "ready" di WebSocket)
+	if ready && participant.Status == "pending" {
+		if err := acceptChallengeParticipant(challengeID, userID); err != nil {
+			return err
+		}
+		participant.Status = "accepted"
+	}
+	if participant.Status != "accepted" {
+		return fiber.NewError(fiber.StatusBadRequest, "You are not in this lobby")
+	}
+
+	config.DB.Model(&models.ChallengeParticipant{}).Where("id = ?", participant.ID).Update("is_ready", ready)
+	broadcastLobbyPlayers(challengeID)
+
+	return nil
+}
+
+// KickLobbyPlayer: host mengeluarkan pemain dari lobby sebelum game dimulai
+func KickLobbyPlayer(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userID := uint(c.Locals("user_id").(float64))
+
+	var input KickLobbyInput
+	if err := c.BodyParser(&input); err != nil || input.UserID == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "user_id wajib diisi", nil)
+	}
+
+	var challenge models.Challenge
+	if err := config.DB.First(&challenge, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Challenge not found", nil)
+	}
+	if challenge.CreatorID != userID {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Only host can kick players", nil)
+	}
+	if challenge.Status != "pending" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Game already started", nil)
+	}
+	if input.UserID == userID {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Host tidak bisa kick diri sendiri", nil)
+	}
+
+	res := config.DB.Model(&models.ChallengeParticipant{}).
+		Where("challenge_id = ? AND user_id = ? AND status IN ?", challenge.ID, input.UserID, []string{"pending", "accepted"}).
+		Updates(map[string]interface{}{"status": "kicked", "is_ready": false})
+	if res.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Player not in lobby", nil)
+	}
+
+	utils.RefundWagerStake(challenge.ID, input.UserID)
+	utils.KickLobbyClient(challenge.ID, input.UserID, "kicked", fiber.Map{
+		"challenge_id": challenge.ID,
+		"message":      "Kamu dikeluarkan dari lobby oleh host",
+	})
+	utils.SendNotification(input.UserID, "warning", "Dikeluarkan dari Lobby", "Host mengeluarkan kamu dari lobby challenge.", "/challenges")
+
+	broadcastLobbyPlayers(challenge.ID)
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Player kicked", nil)
+}
+
+// UpdateLobbySettings: host mengubah quiz, waktu, atau mode sebelum game dimulai.
+// Semua status ready direset supaya pemain menyetujui setting baru.
+func UpdateLobbySettings(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userID := uint(c.Locals("user_id").(float64))
+
+	var input LobbySettingsInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+
+	var challenge models.Challenge
+	if err := config.DB.Preload("Participants").First(&challenge, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Challenge not found", nil)
+	}
+	if challenge.CreatorID != userID {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Only host can change lobby settings", nil)
+	}
+	if challenge.Status != "pending" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Game already started", nil)
+	}
+	if challenge.TournamentID != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Setting match turnamen tidak bisa diubah", nil)
+	}
+
+	updates := map[string]interface{}{}
+
+	if input.QuizID != nil {
+		if *input.QuizID == 0 {
+			updates["quiz_id"] = nil
+			challenge.QuizID = nil
+		} else {
+			var quiz models.Quiz
+			if err := config.DB.First(&quiz, *input.QuizID).Error; err != nil {
+				return utils.ErrorResponse(c, fiber.StatusNotFound, "Quiz not found", nil)
+			}
+			updates["quiz_id"] = *input.QuizID
+			challenge.QuizID = input.QuizID
+		}
+	}
+
+	if input.TimeLimit != nil {
+		if *input.TimeLimit < 0 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "time_limit tidak valid", nil)
+		}
+		updates["time_limit"] = *input.TimeLimit
+		challenge.TimeLimit = *input.TimeLimit
+	}
+
+	if input.DisablePowerups != nil {
+		updates["disable_powerups"] = *input.DisablePowerups
+		challenge.DisablePowerups = *input.DisablePowerups
+	}
+
+	// Pembagian tim ikut berubah kalau mode berganti dari/ke 2v2
+	teams := map[uint]string{}
+	if input.Mode != nil && *input.Mode != challenge.Mode {
+		mode := *input.Mode
+		if !lobbyModes[mode] {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Mode tidak valid", nil)
+		}
+
+		var active []models.ChallengeParticipant
+		for _, p := range challenge.Participants {
+			if p.Status == "pending" || p.Status == "accepted" {
+				active = append(active, p)
+			}
+		}
+
+		if mode == "2v2" {
+			if len(active) != 4 {
+				return utils.ErrorResponse(c, fiber.StatusBadRequest, "Mode 2v2 butuh tepat 4 pemain di lobby", nil)
+			}
+			// Host + pemain pertama Tim A, sisanya Tim B
+			teams[challenge.CreatorID] = "A"
+			for _, p := range active {
+				if _, ok := teams[p.UserID]; ok {
+					continue
+				}
+				if len(teams) < 2 {
+					teams[p.UserID] = "A"
+				} else {
+					teams[p.UserID] = "B"
+				}
+			}
+		} else if challenge.Mode == "2v2" {
+			for _, p := range active {
+				teams[p.UserID] = "solo"
+			}
+		}
+
+		// Kapasitas lobby terbuka mengikuti mode baru
+		if challenge.Visibility != "private" {
+			capacity := utils.LobbyCapacity(mode, challenge.MaxPlayers)
+			if len(active) > capacity {
+				return utils.ErrorResponse(c, fiber.StatusBadRequest, "Pemain di lobby melebihi kapasitas mode ini", nil)
+			}
+			updates["max_players"] = capacity
+			challenge.MaxPlayers = capacity
+		}
+
+		if mode == utils.BattleRoyaleMode && challenge.EliminationRule == "" {
+			challenge.EliminationRule = utils.DefaultEliminationRule
+			challenge.EliminatePerRound = 1
+			updates["elimination_rule"] = challenge.EliminationRule
+			updates["eliminate_per_round"] = challenge.EliminatePerRound
+		}
+
+		if mode == "survival" && challenge.Seed == "" {
+			challenge.Seed = utils.NewSurvivalSeed()
+			updates["seed"] = challenge.Seed
+		}
+
+		updates["mode"] = mode
+		challenge.Mode = mode
+	}
+
+	if len(updates) == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Tidak ada setting yang diubah", nil)
+	}
+
+	res := config.DB.Model(&models.Challenge{}).
+		Where("id = ? AND status = ?", challenge.ID, "pending").
+		Updates(updates)
+	if res.Error != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update settings", res.Error.Error())
+	}
+	if res.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Game already started", nil)
+	}
+
+	for uid, team := range teams {
+		config.DB.Model(&models.ChallengeParticipant{}).
+			Where("challenge_id = ? AND user_id = ?", challenge.ID, uid).
+			Update("team", team)
+	}
+	config.DB.Model(&models.ChallengeParticipant{}).
+		Where("challenge_id = ?", challenge.ID).
+		Update("is_ready", false)
+
+	settings := lobbySettingsPayload(challenge)
+	utils.BroadcastLobby(challenge.ID, "settings_updated", settings)
+	broadcastLobbyPlayers(challenge.ID)
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Lobby settings updated", settings)
+}
+
+// --- LOBBY TERBUKA ---
+
+// openLobbyPayload: ringkasan lobby terbuka untuk daftar & preview link undangan
+func openLobbyPayload(challenge models.Challenge) fiber.Map {
+	players := 0
+	for _, p := range challenge.Participants {
+		if p.Status == "pending" || p.Status == "accepted" {
+			players++
+		}
+	}
+
+	payload := fiber.Map{
+		"id":               challenge.ID,
+		"mode":             challenge.Mode,
+		"visibility":       challenge.Visibility,
+		"quiz":             nil,
+		"time_limit":       challenge.TimeLimit,
+		"wager_amount":     challenge.WagerAmount,
+		"allow_spectators": challenge.AllowSpectators,
+		"player_count":     players,
+		"max_players":      challenge.MaxPlayers,
+		"accept_deadline":  challenge.AcceptDeadline,
+		"host": fiber.Map{
+			"id":       challenge.Creator.ID,
+			"username": challenge.Creator.Username,
+			"name":     challenge.Creator.Name,
+		},
+	}
+	if challenge.QuizID != nil {
+		payload["quiz"] = fiber.Map{"id": challenge.Quiz.ID, "title": challenge.Quiz.Title, "topic_id": challenge.Quiz.TopicID}
+	}
+	if challenge.JoinCode != nil {
+		payload["join_code"] = *challenge.JoinCode
+		payload["invite_link"] = utils.LobbyInviteLink(*challenge.JoinCode)
+	}
+	return payload
+}
+
+// GetOpenLobbies: daftar lobby terbuka yang masih menunggu pemain.
+// Filter: ?topic=slug, ?mode=1v1|2v2|survival. Lobby friends-only hanya tampil untuk teman host.
+func GetOpenLobbies(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	params := utils.GetPaginationParams(c)
+
+	friendIDs := config.DB.Model(&models.Friendship{}).
+		Select("CASE WHEN user_id = ? THEN friend_id ELSE user_id END", userID).
+		Where("status = ? AND (user_id = ? OR friend_id = ?)", "accepted", userID, userID)
+
+	query := config.DB.Model(&models.Challenge{}).
+		Where("status = ? AND is_realtime = ? AND join_code IS NOT NULL", "pending", true).
+		Where("accept_deadline IS NULL OR accept_deadline > ?", time.Now()).
+		Where("visibility = ? OR (visibility = ? AND creator_id IN (?))", "public", "friends", friendIDs)
+
+	if mode := c.Query("mode"); mode != "" {
+		query = query.Where("mode = ?", mode)
+	}
+	if slug := c.Query("topic"); slug != "" {
+		var topic models.Topic
+		if err := config.DB.Where("slug = ?", slug).First(&topic).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Topic not found", nil)
+		}
+		query = query.Where("quiz_id IN (?)", config.DB.Model(&models.Quiz{}).Select("id").Where("topic_id = ?", topic.ID))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to count lobbies", err.Error())
+	}
+
+	var challenges []models.Challenge
+	if err := query.Preload("Creator").Preload("Quiz").Preload("Participants").
+		Order("created_at DESC").
+		Offset(params.Offset).
+		Limit(params.PageSize).
+		Find(&challenges).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch lobbies", err.Error())
+	}
+
+	lobbies := []fiber.Map{}
+	for _, ch := range challenges {
+		lobbies = append(lobbies, openLobbyPayload(ch))
+	}
+
+	return utils.PaginatedSuccessResponse(c, fiber.StatusOK, "Lobbies retrieved", lobbies, total, params)
+}
+
+// GetLobbyByCode: preview lobby dari kode / link undangan sebelum join
+func GetLobbyByCode(c *fiber.Ctx) error {
+	code := strings.ToUpper(c.Params("code"))
+
+	var challenge models.Challenge
+	if err := config.DB.Preload("Creator").Preload("Quiz").Preload("Participants").
+		Where("join_code = ?", code).First(&challenge).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Lobby not found", nil)
+	}
+
+	payload := openLobbyPayload(challenge)
+	payload["status"] = challenge.Status
+	return utils.SuccessResponse(c, fiber.StatusOK, "Lobby retrieved", payload)
+}
+
+// JoinLobby: join lobby terbuka lewat kode. Peserta baru langsung accepted dan
+// semua yang ada di lobby menerima player_update.
+func JoinLobby(c *fiber.Ctx) error {
+	code := strings.ToUpper(c.Params("code"))
+	userID := uint(c.Locals("user_id").(float64))
+
+	challenge, err := utils.JoinLobbyByCode(code, userID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Lobby not found", nil)
+	case errors.Is(err, utils.ErrInsufficientCoins):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Koin kamu kurang untuk taruhan lobby ini!", nil)
+	case errors.Is(err, utils.ErrLobbyFriendsOnly), errors.Is(err, utils.ErrLobbyKicked):
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error(), nil)
+	case errors.Is(err, utils.ErrLobbyNotOpen), errors.Is(err, utils.ErrLobbyFull):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	case err != nil:
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to join lobby", err.Error())
+	}
+
+	broadcastLobbyPlayers(challenge.ID)
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Joined lobby", fiber.Map{
+		"challenge_id": challenge.ID,
+		"mode":         challenge.Mode,
+	})
+}
@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+)
+
+const (
+	lobbyPongWait   = 40 * time.Second // Batas waktu tanpa pesan/pong sebelum dianggap disconnect
+	lobbyPingPeriod = 15 * time.Second // Harus lebih kecil dari lobbyPongWait
+	lobbyWriteWait  = 10 * time.Second
+)
+
+// LobbySocketMessage adalah format pesan dari client:
+// {"type": "ready" | "unready" | "progress" | "answer" | "chat" | "emote" | "leave" | "ping", "data": {...}}
+type LobbySocketMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type LobbyAnswerInput struct {
+	QuestionID uint   `json:"question_id"`
+	Answer     string `json:"answer"`
+	Index      int    `json:"index"`
+}
+
+type LobbyEmoteInput struct {
+	Emote string `json:"emote"`
+}
+
+type LobbyChatInput struct {
+	Message string `json:"message"`
+}
+
+// UpgradeLobbySocket menolak request biasa ke endpoint WebSocket lobby
+func UpgradeLobbySocket(c *fiber.Ctx) error {
+	if websocket.IsWebSocketUpgrade(c) {
+		return c.Next()
+	}
+	return utils.ErrorResponse(c, fiber.StatusUpgradeRequired, "WebSocket upgrade required", nil)
+}
+
+// ChallengeLobbySocket adalah transport WebSocket untuk lobby challenge.
+// Event server sama dengan StreamChallengeLobby (SSE), dikirim sebagai {"type": ..., "data": ...}
+func ChallengeLobbySocket(conn *websocket.Conn) {
+	challengeIDData, _ := strconv.Atoi(conn.Params("id"))
+	challengeID := uint(challengeIDData)
+	userID := uint(conn.Locals("user_id").(float64))
+
+	var participant models.ChallengeParticipant
+	if err := config.DB.Where("challenge_id = ? AND user_id = ? AND status IN ?", challengeID, userID, []string{"pending", "accepted"}).First(&participant).Error; err != nil {
+		conn.SetWriteDeadline(time.Now().Add(lobbyWriteWait))
+		conn.WriteJSON(utils.LobbyEvent{Type: "error", Data: fiber.Map{"message": "You are not in this challenge"}})
+		conn.Close()
+		return
+	}
+
+	msgChan := utils.AddClientToLobby(challengeID, userID)
+	utils.CancelDisconnectForfeit(challengeID, userID)
+	done := make(chan struct{})
+	go writeLobbySocket(conn, msgChan, done)
+
+	// Kirim daftar pemain awal sekaligus kabari yang lain bahwa user ini online
+	broadcastLobbyPlayers(challengeID)
+
+	conn.SetReadLimit(4096)
+	conn.SetReadDeadline(time.Now().Add(lobbyPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(lobbyPongWait))
+	})
+
+	for {
+		var msg LobbySocketMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(lobbyPongWait))
+
+		if !handleLobbySocketMessage(challengeID, userID, msg) {
+			break
+		}
+	}
+
+	utils.RemoveClientFromLobby(challengeID, userID, msgChan)
+	<-done
+
+	// Jika koneksi tidak digantikan koneksi baru, user ini offline
+	if !utils.IsInLobby(challengeID, userID) {
+		broadcastLobbyPlayers(challengeID)
+		utils.ScheduleDisconnectForfeit(challengeID, userID)
+		utils.ScheduleHostMigration(challengeID, userID)
+	}
+}
+
+// writeLobbySocket adalah satu-satunya goroutine yang menulis ke koneksi
+// (websocket tidak boleh ditulis secara paralel), termasuk ping heartbeat.
+func writeLobbySocket(conn *websocket.Conn, msgChan chan utils.LobbyEvent, done chan struct{}) {
+	ticker := time.NewTicker(lobbyPingPeriod)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+		close(done)
+	}()
+
+	for {
+		select {
+		case msg, ok := <-msgChan:
+			conn.SetWriteDeadline(time.Now().Add(lobbyWriteWait))
+			if !ok {
+				// Channel ditutup: koneksi digantikan koneksi baru atau user keluar
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(lobbyWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleLobbySocketMessage memproses satu pesan client. Return false jika koneksi harus ditutup.
+func handleLobbySocketMessage(challengeID uint, userID uint, msg LobbySocketMessage) bool {
+	switch msg.Type {
+	case "ping":
+		utils.SendToLobbyClient(challengeID, userID, "pong", fiber.Map{"time": time.Now().Unix()})
+
+	case "ready", "unready":
+		if err := setLobbyReady(challengeID, userID, msg.Type == "ready"); err != nil {
+			sendLobbySocketError(challengeID, userID, msg.Type, err)
+		}
+
+	case "progress":
+		var input ProgressInput
+		if err := json.Unmarshal(msg.Data, &input); err != nil {
+			sendLobbySocketError(challengeID, userID, msg.Type, fiber.NewError(fiber.StatusBadRequest, "Invalid input"))
+			return true
+		}
+		if err := broadcastChallengeProgress(challengeID, userID, input); err != nil {
+			sendLobbySocketError(challengeID, userID, msg.Type, err)
+		}
+
+	case "answer":
+		var input LobbyAnswerInput
+		if err := json.Unmarshal(msg.Data, &input); err != nil {
+			sendLobbySocketError(challengeID, userID, msg.Type, fiber.NewError(fiber.StatusBadRequest, "Invalid input"))
+			return true
+		}
+		if err := submitLobbyAnswer(challengeID, userID, input); err != nil {
+			sendLobbySocketError(challengeID, userID, msg.Type, err)
+		}
+
+	case "chat":
+		var input LobbyChatInput
+		if err := json.Unmarshal(msg.Data, &input); err != nil {
+			sendLobbySocketError(challengeID, userID, msg.Type, fiber.NewError(fiber.StatusBadRequest, "Invalid chat payload"))
+			return true
+		}
+		if _, err := utils.SendLobbyChat(challengeID, userID, input.Message); err != nil {
+			sendLobbySocketError(challengeID, userID, msg.Type, lobbyChatError(err))
+		}
+
+	case "emote":
+		var input LobbyEmoteInput
+		if err := json.Unmarshal(msg.Data, &input); err != nil {
+			sendLobbySocketError(challengeID, userID, msg.Type, fiber.NewError(fiber.StatusBadRequest, "Unknown emote"))
+			return true
+		}
+		if _, err := utils.SendLobbyEmote(challengeID, userID, input.Emote); err != nil {
+			sendLobbySocketError(challengeID, userID, msg.Type, lobbyChatError(err))
+		}
+
+	case "leave":
+		if err := leaveChallengeLobby(challengeID, userID); err != nil {
+			sendLobbySocketError(challengeID, userID, msg.Type, err)
+			return true
+		}
+		return false
+
+	default:
+		sendLobbySocketError(challengeID, userID, msg.Type, fiber.NewError(fiber.StatusBadRequest, "Unknown message type"))
+	}
+
+	return true
+}
+
+// submitLobbyAnswer menilai jawaban di server. Jawaban benar tidak pernah dikirim ke lobby,
+// pemain lain hanya tahu benar/salah.
+func submitLobbyAnswer(challengeID uint, userID uint, input LobbyAnswerInput) *fiber.Error {
+	var challenge models.Challenge
+	if err := config.DB.First(&challenge, challengeID).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Challenge not found")
+	}
+	if challenge.Status != "active" {
+		return fiber.NewError(fiber.StatusBadRequest, "Game is not running")
+	}
+
+	var question models.Question
+	if err := config.DB.First(&question, input.QuestionID).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Question not found")
+	}
+	if challenge.QuizID != nil && question.QuizID != *challenge.QuizID {
+		return fiber.NewError(fiber.StatusBadRequest, "Question is not part of this challenge")
+	}
+
+	var participant models.ChallengeParticipant
+	if err := config.DB.Where("challenge_id = ? AND user_id = ? AND status = ?", challengeID, userID, "accepted").First(&participant).Error; err != nil {
+		return fiber.NewError(fiber.StatusForbidden, "You are not in this challenge")
+	}
+	if participant.Eliminated || participant.IsFinished {
+		return fiber.NewError(fiber.StatusBadRequest, "Kamu sudah tidak bermain di match ini")
+	}
+
+	// Satu jawaban per soal: jawaban ulang ditolak tanpa hasil supaya benar/salah tidak bisa ditebak satu per satu
+	if utils.MatchAnswerLogged(challengeID, userID, question.ID) {
+		return fiber.NewError(fiber.StatusConflict, "Soal ini sudah dijawab")
+	}
+	isCorrect := utils.IsAnswerCorrect(question, input.Answer)
+	results, closed, recorded := utils.RecordRoundAnswer(challengeID, question.ID, userID, isCorrect)
+	if !recorded {
+		return fiber.NewError(fiber.StatusConflict, "Soal ini sudah dijawab")
+	}
+
+	utils.LogMatchEvent(challengeID, userID, "answer", models.ChallengeMatchEvent{
+		QuestionID: &question.ID,
+		Answer:     input.Answer,
+		Correct:    &isCorrect,
+	})
+
+	utils.SendToLobbyClient(challengeID, userID, "answer_result", fiber.Map{
+		"question_id": question.ID,
+		"correct":     isCorrect,
+	})
+	utils.BroadcastLobby(challengeID, "opponent_answer", fiber.Map{
+		"user_id": userID,
+		"index":   input.Index,
+		"correct": isCorrect,
+	})
+
+	// Kunci jawaban baru dibuka setelah semua pemain menjawab soal ini
+	if closed {
+		utils.CloseLobbyRound(challengeID, question.ID, results)
+	}
+
+	return nil
+}
+
+func sendLobbySocketError(challengeID uint, userID uint, msgType string, err *fiber.Error) {
+	utils.SendToLobbyClient(challengeID, userID, "error", fiber.Map{
+		"for":     msgType,
+		"code":    err.Code,
+		"message": err.Message,
+	})
+}
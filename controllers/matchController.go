@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// loadFinishedChallenge: challenge selesai yang boleh dilihat user (peserta, atau penonton jika diizinkan)
+func loadFinishedChallenge(c *fiber.Ctx) (models.Challenge, bool, error) {
+	id := c.Params("id")
+	userID := uint(c.Locals("user_id").(float64))
+
+	var challenge models.Challenge
+	if err := config.DB.Preload("Participants.User").First(&challenge, id).Error; err != nil {
+		return challenge, false, utils.ErrorResponse(c, fiber.StatusNotFound, "Challenge not found", nil)
+	}
+
+	isPlayer := false
+	for _, p := range challenge.Participants {
+		if p.UserID == userID {
+			isPlayer = true
+			break
+		}
+	}
+	if !isPlayer && !challenge.AllowSpectators {
+		return challenge, false, utils.ErrorResponse(c, fiber.StatusForbidden, "You are not in this challenge", nil)
+	}
+	if challenge.Status != "finished" {
+		return challenge, false, utils.ErrorResponse(c, fiber.StatusBadRequest, "Challenge belum selesai", nil)
+	}
+	return challenge, true, nil
+}
+
+// GetChallengeSummary: ringkasan pasca-game per soal, pergantian pemimpin, MVP, comeback & head-to-head
+func GetChallengeSummary(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+
+	challenge, ok, err := loadFinishedChallenge(c)
+	if !ok {
+		return err
+	}
+
+	summary := utils.BuildMatchSummary(challenge)
+
+	// Head-to-head dari sisi user yang melihat; penonton melihat rekor host
+	viewer := challenge.CreatorID
+	for _, p := range challenge.Participants {
+		if p.UserID == userID {
+			viewer = userID
+			break
+		}
+	}
+	var headToHead []utils.HeadToHeadRecord
+	for _, p := range challenge.Participants {
+		if p.UserID == viewer || p.Status != "accepted" {
+			continue
+		}
+		headToHead = append(headToHead, utils.HeadToHead(viewer, p.UserID))
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Summary retrieved", fiber.Map{
+		"summary":      summary,
+		"head_to_head": headToHead,
+		"viewer_id":    viewer,
+	})
+}
+
+// GetChallengeReplay: log event match realtime urut waktu untuk diputar ulang di client
+func GetChallengeReplay(c *fiber.Ctx) error {
+	challenge, ok, err := loadFinishedChallenge(c)
+	if !ok {
+		return err
+	}
+	if !challenge.IsRealtime {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Replay hanya tersedia untuk challenge realtime", nil)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Replay retrieved", fiber.Map{
+		"challenge_id": challenge.ID,
+		"started_at":   challenge.StartedAt,
+		"players":      formatParticipants(challenge.Participants),
+		"events":       utils.GetMatchEvents(challenge.ID),
+	})
+}
+
+// RematchChallenge: buat ulang challenge selesai dengan peserta, tim, mode, quiz & taruhan yang sama.
+// Yang menekan rematch menjadi host; peserta lain diundang ulang.
+func RematchChallenge(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userID := uint(c.Locals("user_id").(float64))
+
+	var original models.Challenge
+	if err := config.DB.Preload("Participants.User").First(&original, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Challenge not found", nil)
+	}
+	if original.Status != "finished" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Rematch hanya untuk challenge yang sudah selesai", nil)
+	}
+	if original.TournamentID != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Match turnamen tidak bisa di-rematch", nil)
+	}
+
+	var me *models.ChallengeParticipant
+	var others []models.ChallengeParticipant
+	for i, p := range original.Participants {
+		if p.Status != "accepted" {
+			continue
+		}
+		if p.UserID == userID {
+			me = &original.Participants[i]
+		} else {
+			others = append(others, p)
+		}
+	}
+	if me == nil {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You are not in this challenge", nil)
+	}
+	if len(others) == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Tidak ada lawan untuk rematch", nil)
+	}
+
+	// Satu rematch aktif per challenge, tekan dua kali = challenge yang sama
+	var existing models.Challenge
+	if err := config.DB.Where("rematch_of_id = ? AND status IN ?", original.ID, []string{"pending", "active"}).
+		First(&existing).Error; err == nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Rematch sudah dibuat", fiber.Map{"challenge_id": existing.ID})
+	}
+
+	now := time.Now()
+	acceptDeadline := now.Add(utils.DefaultAcceptWindow)
+	completeDeadline := now.Add(utils.DefaultCompleteWindow)
+
+	rematch := models.Challenge{
+		CreatorID:        userID,
+		QuizID:           original.QuizID,
+		Mode:             original.Mode,
+		TimeLimit:        original.TimeLimit,
+		IsRealtime:       original.IsRealtime,
+		Status:           "pending",
+		WagerAmount:      original.WagerAmount,
+		AcceptDeadline:   &acceptDeadline,
+		CompleteDeadline: &completeDeadline,
+		AllowSpectators:  original.AllowSpectators,
+		Visibility:       "private",
+		Ghost:            original.Ghost,
+		DisablePowerups:  original.DisablePowerups,
+		SurvivalLives:    original.SurvivalLives,
+		MaxPlayers:       original.MaxPlayers,
+		RematchOfID:      &original.ID,
+
+		EliminationRule:   original.EliminationRule,
+		EliminatePerRound: original.EliminatePerRound,
+		SafeRounds:        original.SafeRounds,
+	}
+	if rematch.Mode == "survival" {
+		rematch.Seed = utils.NewSurvivalSeed()
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&rematch).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&models.ChallengeParticipant{
+			ChallengeID: rematch.ID,
+			UserID:      userID,
+			Status:      "accepted",
+			Team:        me.Team,
+		}).Error; err != nil {
+			return err
+		}
+		for _, p := range others {
+			if err := tx.Create(&models.ChallengeParticipant{
+				ChallengeID: rematch.ID,
+				UserID:      p.UserID,
+				Status:      "pending",
+				Team:        p.Team,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if rematch.WagerAmount > 0 {
+			if err := utils.OpenWagerEscrow(tx, rematch.ID); err != nil {
+				return err
+			}
+			return utils.HoldWagerStake(tx, rematch, userID)
+		}
+		return nil
+	})
+	if errors.Is(err, utils.ErrInsufficientCoins) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Koin tidak cukup untuk taruhan!", nil)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed create rematch", err.Error())
+	}
+
+	msg := "🔁 " + me.User.Name + " mengajak rematch " + rematch.Mode + "!"
+	if rematch.WagerAmount > 0 {
+		msg += fmt.Sprintf(" (Taruhan: %d Koin)", rematch.WagerAmount)
+	}
+	for _, p := range others {
+		utils.SendNotification(p.UserID, "warning", "Ajakan Rematch!", msg, "/challenges")
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Rematch created", rematch)
+}
+
+// GetHeadToHead: rekor user login melawan :username di semua challenge selesai
+func GetHeadToHead(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+
+	var opponent models.User
+	if err := config.DB.Where("username = ?", c.Params("username")).First(&opponent).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", nil)
+	}
+	if opponent.ID == userID {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Tidak bisa melihat head-to-head dengan diri sendiri", nil)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Head-to-head retrieved", utils.HeadToHead(userID, opponent.ID))
+}
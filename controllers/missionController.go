@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GET /api/admin/missions
+func GetAllMissionsAdmin(c *fiber.Ctx) error {
+	var missions []models.Mission
+	query := config.DB.Order("id asc")
+	if eventType := c.Query("event_type"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	if period := c.Query("period"); period != "" {
+		query = query.Where("period = ?", period)
+	}
+	query.Find(&missions)
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Missions retrieved", missions)
+}
+
+// POST /api/admin/missions
+func CreateMission(c *fiber.Ctx) error {
+	mission := models.Mission{Aggregation: "count", Weight: 10, Rarity: "common", Period: "daily"}
+	if err := c.BodyParser(&mission); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	mission.ID = 0
+	mission.IsActive = true
+
+	if err := utils.ValidateMissionRule(mission); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	var count int64
+	config.DB.Model(&models.Mission{}).Where("key = ?", mission.Key).Count(&count)
+	if count > 0 {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Mission key already exists", nil)
+	}
+
+	if err := config.DB.Create(&mission).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create mission", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Mission created", mission)
+}
+
+// PUT /api/admin/missions/:id
+// Perubahan aturan berlaku untuk event berikutnya, progress yang sudah ada tidak dihitung ulang
+func UpdateMission(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var mission models.Mission
+	if err := config.DB.First(&mission, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Mission not found", nil)
+	}
+	key := mission.Key
+
+	if err := c.BodyParser(&mission); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	mission.ID = uint(id)
+	mission.Key = key
+
+	if err := utils.ValidateMissionRule(mission); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	if err := config.DB.Save(&mission).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update mission", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Mission updated", mission)
+}
+
+// DELETE /api/admin/missions/:id
+// Misi hanya dinonaktifkan supaya riwayat misi user tetap utuh
+func DeleteMission(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	res := config.DB.Model(&models.Mission{}).Where("id = ?", id).Update("is_active", false)
+	if res.Error != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to deactivate mission", res.Error.Error())
+	}
+	if res.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Mission not found", nil)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Mission deactivated", nil)
+}
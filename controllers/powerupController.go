@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type StartAttemptInput struct {
+	ChallengeID  *uint `json:"challenge_id"`
+	AssignmentID *uint `json:"assignment_id"`
+}
+
+type UsePowerUpInput struct {
+	Type       string `json:"type"` // fifty_fifty, time_plus, skip, extra_life
+	QuestionID uint   `json:"question_id"`
+	RunID      uint   `json:"run_id"` // Khusus survival
+}
+
+var quizPowerUps = []string{utils.PowerUpFiftyFifty, utils.PowerUpTimePlus, utils.PowerUpSkip}
+
+// normalizePowerUp menerima nama pendek (fifty_fifty) maupun tipe item (powerup_fifty_fifty)
+func normalizePowerUp(t string) string {
+	if !strings.HasPrefix(t, "powerup_") {
+		return "powerup_" + t
+	}
+	return t
+}
+
+func powerUpErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Attempt not found", nil)
+	case errors.Is(err, utils.ErrPowerUpsDisabled), errors.Is(err, utils.ErrPowerUpRankedRun):
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Power-up tidak boleh dipakai di sini", nil)
+	case errors.Is(err, utils.ErrItemNotOwned):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Stok power-up kamu habis", nil)
+	case errors.Is(err, utils.ErrPowerUpUsed):
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Power-up ini sudah dipakai", nil)
+	case errors.Is(err, utils.ErrPowerUpInvalid), errors.Is(err, utils.ErrPowerUpUnsupported),
+		errors.Is(err, utils.ErrPowerUpNoQuestion), errors.Is(err, utils.ErrAttemptNotActive),
+		errors.Is(err, utils.ErrSurvivalRunNotActive):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to use power-up", err.Error())
+}
+
+// POST /api/quizzes/:id/attempts
+// Membuka attempt kuis; attempt_id dikirim lagi saat memakai power-up dan saat menyimpan history
+func StartQuizAttempt(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	quizID, _ := strconv.Atoi(c.Params("id"))
+
+	var input StartAttemptInput
+	c.BodyParser(&input)
+
+	var quiz models.Quiz
+	if err := config.DB.First(&quiz, quizID).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Quiz not found", nil)
+	}
+
+	if input.ChallengeID != nil {
+		var count int64
+		config.DB.Model(&models.ChallengeParticipant{}).
+			Joins("JOIN challenges ON challenges.id = challenge_participants.challenge_id").
+			Where("challenge_participants.challenge_id = ? AND challenge_participants.user_id = ? AND challenges.quiz_id = ?", *input.ChallengeID, userID, quiz.ID).
+			Count(&count)
+		if count == 0 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Challenge tidak cocok dengan kuis ini", nil)
+		}
+	}
+	if input.AssignmentID != nil {
+		var count int64
+		config.DB.Model(&models.Assignment{}).Where("id = ? AND quiz_id = ?", *input.AssignmentID, quiz.ID).Count(&count)
+		if count == 0 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Tugas tidak cocok dengan kuis ini", nil)
+		}
+	}
+
+	attempt, err := utils.StartQuizAttempt(userID, quiz, input.ChallengeID, input.AssignmentID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to start attempt", err.Error())
+	}
+
+	inventory := fiber.Map{}
+	if attempt.PowerupsAllowed {
+		for _, t := range quizPowerUps {
+			inventory[strings.TrimPrefix(t, "powerup_")] = utils.CountUserItems(config.DB, userID, t)
+		}
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Attempt started", fiber.Map{
+		"attempt_id":       attempt.ID,
+		"powerups_allowed": attempt.PowerupsAllowed,
+		"time_limit":       attempt.TimeLimit,
+		"powerups":         inventory,
+	})
+}
+
+// POST /api/attempts/:id/powerups
+// Body: {"type": "fifty_fifty" | "time_plus" | "skip", "question_id": 12}
+func UseQuizPowerUp(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	attemptID, _ := strconv.Atoi(c.Params("id"))
+
+	var input UsePowerUpInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+
+	result, err := utils.UseQuizPowerUp(userID, uint(attemptID), input.QuestionID, normalizePowerUp(input.Type))
+	if err != nil {
+		return powerUpErrorResponse(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Power-up used", result)
+}
+
+// POST /api/survival/powerup
+// Body: {"run_id": 3, "type": "extra_life"}
+func UseSurvivalPowerUp(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+
+	var input UsePowerUpInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+
+	result, err := utils.UseSurvivalPowerUp(userID, input.RunID, normalizePowerUp(input.Type))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Survival run not found", nil)
+	}
+	if err != nil {
+		return powerUpErrorResponse(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Power-up used", result)
+}
@@ -92,6 +92,37 @@ func GetMyCommunityQuizzes(c *fiber.Ctx) error {
 func GetRemedialQuestions(c *fiber.Ctx) error {
     userID := c.Locals("user_id").(float64)
 
+    questions, wrongQIDs := remedialQuestions(userID)
+    if len(wrongQIDs) == 0 {
+        return utils.ErrorResponse(c, fiber.StatusNotFound, "Tidak ada soal remedial. Kamu hebat!", nil)
+    }
+
+    return utils.SuccessResponse(c, fiber.StatusOK, "Sesi Remedial Dimulai", questions)
+}
+
+// POST /api/quizzes/remedial/start
+// Sama seperti GET, tapi set soal dikunci server di attempt; attempt_id dikirim lagi ke POST /api/history
+func StartRemedialAttempt(c *fiber.Ctx) error {
+    userID := c.Locals("user_id").(float64)
+
+    questions, wrongQIDs := remedialQuestions(userID)
+    if len(wrongQIDs) == 0 {
+        return utils.ErrorResponse(c, fiber.StatusNotFound, "Tidak ada soal remedial. Kamu hebat!", nil)
+    }
+
+    attempt, err := utils.StartRemedialAttempt(uint(userID), wrongQIDs)
+    if err != nil {
+        return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Gagal memulai sesi remedial", err.Error())
+    }
+
+    return utils.SuccessResponse(c, fiber.StatusCreated, "Sesi Remedial Dimulai", fiber.Map{
+        "attempt_id": attempt.ID,
+        "questions":  questions,
+    })
+}
+
+// remedialQuestions mencari maks 10 soal yang dijawab salah di 10 history terakhir
+func remedialQuestions(userID float64) ([]models.Question, []uint) {
     // 1. Ambil 10 history terakhir user
     var histories []models.History
     config.DB.Where("user_id = ?", userID).Order("created_at desc").Limit(10).Find(&histories)
@@ -127,12 +158,11 @@ func GetRemedialQuestions(c *fiber.Ctx) error {
     }
 
     if len(wrongQIDs) == 0 {
-        return utils.ErrorResponse(c, fiber.StatusNotFound, "Tidak ada soal remedial. Kamu hebat!", nil)
+        return nil, nil
     }
 
     // 3. Ambil data soal lengkap
     var questions []models.Question
     config.DB.Preload("Quiz").Where("id IN ?", wrongQIDs).Find(&questions)
-
-    return utils.SuccessResponse(c, fiber.StatusOK, "Sesi Remedial Dimulai", questions)
+    return questions, wrongQIDs
 }
\ No newline at end of file
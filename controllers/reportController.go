@@ -21,6 +21,24 @@ func CreateReport(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
 	}
 
+	// Chat lobby hanya bisa dilaporkan oleh peserta lobby yang sama
+	if input.TargetType == "chat_message" {
+		var msg models.LobbyMessage
+		if err := config.DB.First(&msg, input.TargetID).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Message not found", nil)
+		}
+		if msg.UserID == userId {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Cannot report your own message", nil)
+		}
+		var count int64
+		config.DB.Model(&models.ChallengeParticipant{}).
+			Where("challenge_id = ? AND user_id = ?", msg.ChallengeID, userId).
+			Count(&count)
+		if count == 0 {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "You are not in this lobby", nil)
+		}
+	}
+
 	report := models.Report{
 		ReporterID: userId,
 		TargetID:   input.TargetID,
@@ -46,17 +64,21 @@ func GetAllReports(c *fiber.Ctx) error {
 	// Enrich reports with Target Details
 	var userIDs []uint
 	var questionIDs []uint
+	var messageIDs []uint
 
 	for _, r := range reports {
 		if r.TargetType == "user" {
 			userIDs = append(userIDs, r.TargetID)
 		} else if r.TargetType == "question" {
 			questionIDs = append(questionIDs, r.TargetID)
+		} else if r.TargetType == "chat_message" {
+			messageIDs = append(messageIDs, r.TargetID)
 		}
 	}
 
 	usersMap := make(map[uint]string)
 	questionsMap := make(map[uint]string)
+	messagesMap := make(map[uint]string)
 
 	if len(userIDs) > 0 {
 		var users []models.User
@@ -74,6 +96,14 @@ func GetAllReports(c *fiber.Ctx) error {
 		}
 	}
 
+	if len(messageIDs) > 0 {
+		var messages []models.LobbyMessage
+		config.DB.Unscoped().Preload("User").Where("id IN ?", messageIDs).Find(&messages)
+		for _, m := range messages {
+			messagesMap[m.ID] = m.User.Username + ": " + m.Content
+		}
+	}
+
 	type ReportResponse struct {
 		models.Report
 		TargetDetail string `json:"target_detail"`
@@ -99,6 +129,12 @@ func GetAllReports(c *fiber.Ctx) error {
 			} else {
 				detail = "Unknown Question"
 			}
+		} else if r.TargetType == "chat_message" {
+			if val, ok := messagesMap[r.TargetID]; ok {
+				detail = val
+			} else {
+				detail = "Unknown Message"
+			}
 		}
 		response = append(response, ReportResponse{
 			Report:       r,
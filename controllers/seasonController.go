@@ -0,0 +1,364 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type SeasonInput struct {
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	StartAt      time.Time `json:"start_at"`
+	EndAt        time.Time `json:"end_at"`
+	PremiumPrice int       `json:"premium_price"`
+}
+
+func seasonErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, utils.ErrSeasonNotActive):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Tidak ada season yang sedang berjalan", nil)
+	case errors.Is(err, utils.ErrSeasonTierLocked):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Poin pass belum cukup untuk tier ini", nil)
+	case errors.Is(err, utils.ErrSeasonTierClaimed):
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Tier ini sudah diklaim", nil)
+	case errors.Is(err, utils.ErrSeasonPremiumRequired):
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Tier premium butuh premium pass", nil)
+	case errors.Is(err, utils.ErrSeasonPremiumOwned):
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Premium pass sudah dimiliki", nil)
+	case errors.Is(err, utils.ErrInsufficientCoins):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Koin kamu tidak cukup", nil)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Tier not found", nil)
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Season request failed", err.Error())
+}
+
+// --- ADMIN ---
+
+func validateSeasonInput(input SeasonInput, excludeID uint) error {
+	if input.Name == "" {
+		return errors.New("Nama season wajib diisi")
+	}
+	if input.StartAt.IsZero() || !input.EndAt.After(input.StartAt) {
+		return errors.New("end_at harus setelah start_at")
+	}
+	if input.PremiumPrice < 0 {
+		return errors.New("premium_price tidak boleh negatif")
+	}
+
+	// Season tidak boleh tumpang tindih, supaya hanya ada satu season aktif
+	var overlap int64
+	config.DB.Model(&models.Season{}).
+		Where("id <> ? AND status <> ? AND start_at < ? AND end_at > ?", excludeID, "archived", input.EndAt, input.StartAt).
+		Count(&overlap)
+	if overlap > 0 {
+		return errors.New("Jadwal season bertabrakan dengan season lain")
+	}
+	return nil
+}
+
+// GET /api/admin/seasons
+func GetAllSeasonsAdmin(c *fiber.Ctx) error {
+	var seasons []models.Season
+	config.DB.Preload("Tiers", func(db *gorm.DB) *gorm.DB {
+		return db.Order("tier ASC, track ASC")
+	}).Order("start_at DESC").Find(&seasons)
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Seasons retrieved", seasons)
+}
+
+// POST /api/admin/seasons
+func CreateSeason(c *fiber.Ctx) error {
+	var input SeasonInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	if err := validateSeasonInput(input, 0); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	season := models.Season{
+		Name:         input.Name,
+		Description:  input.Description,
+		StartAt:      input.StartAt,
+		EndAt:        input.EndAt,
+		PremiumPrice: input.PremiumPrice,
+		Status:       "upcoming",
+	}
+	if err := config.DB.Create(&season).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create season", err.Error())
+	}
+
+	// Season yang jadwalnya sudah mulai langsung diaktifkan
+	utils.RolloverSeasons()
+	config.DB.First(&season, season.ID)
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Season created", season)
+}
+
+// PUT /api/admin/seasons/:id
+func UpdateSeason(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var season models.Season
+	if err := config.DB.First(&season, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Season not found", nil)
+	}
+	if season.Status == "archived" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Season sudah diarsipkan", nil)
+	}
+
+	input := SeasonInput{
+		Name:         season.Name,
+		Description:  season.Description,
+		StartAt:      season.StartAt,
+		EndAt:        season.EndAt,
+		PremiumPrice: season.PremiumPrice,
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	if err := validateSeasonInput(input, season.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	season.Name = input.Name
+	season.Description = input.Description
+	season.StartAt = input.StartAt
+	season.EndAt = input.EndAt
+	season.PremiumPrice = input.PremiumPrice
+	if err := config.DB.Save(&season).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update season", err.Error())
+	}
+
+	utils.RolloverSeasons()
+	config.DB.First(&season, season.ID)
+	return utils.SuccessResponse(c, fiber.StatusOK, "Season updated", season)
+}
+
+// POST /api/admin/seasons/:id/archive
+// Menutup season lebih awal (progress user diarsipkan)
+func ArchiveSeasonAdmin(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	if !utils.ArchiveSeason(uint(id)) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Season tidak sedang aktif", nil)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Season archived", nil)
+}
+
+// POST /api/admin/seasons/:id/tiers
+func CreateSeasonTier(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var season models.Season
+	if err := config.DB.First(&season, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Season not found", nil)
+	}
+	if season.Status == "archived" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Season sudah diarsipkan", nil)
+	}
+
+	tier := models.SeasonTier{Track: "free"}
+	if err := c.BodyParser(&tier); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	tier.ID = 0
+	tier.SeasonID = season.ID
+
+	if err := utils.ValidateSeasonTier(tier); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	var count int64
+	config.DB.Model(&models.SeasonTier{}).Where("season_id = ? AND tier = ? AND track = ?", season.ID, tier.Tier, tier.Track).Count(&count)
+	if count > 0 {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Tier untuk track ini sudah ada", nil)
+	}
+
+	if err := config.DB.Create(&tier).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create tier", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Tier created", tier)
+}
+
+// PUT /api/admin/seasons/tiers/:tierId
+func UpdateSeasonTier(c *fiber.Ctx) error {
+	tierID, _ := strconv.Atoi(c.Params("tierId"))
+
+	var tier models.SeasonTier
+	if err := config.DB.First(&tier, tierID).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Tier not found", nil)
+	}
+	seasonID, tierNo, track := tier.SeasonID, tier.Tier, tier.Track
+
+	if err := c.BodyParser(&tier); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	// Posisi tier tetap, hanya syarat & hadiah yang bisa diubah
+	tier.ID = uint(tierID)
+	tier.SeasonID = seasonID
+	tier.Tier = tierNo
+	tier.Track = track
+
+	if err := utils.ValidateSeasonTier(tier); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+	if err := config.DB.Omit("Item").Save(&tier).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update tier", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Tier updated", tier)
+}
+
+// DELETE /api/admin/seasons/tiers/:tierId
+func DeleteSeasonTier(c *fiber.Ctx) error {
+	tierID, _ := strconv.Atoi(c.Params("tierId"))
+
+	var claimed int64
+	config.DB.Model(&models.UserSeasonClaim{}).Where("season_tier_id = ?", tierID).Count(&claimed)
+	if claimed > 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Tier sudah diklaim user, tidak bisa dihapus", nil)
+	}
+
+	if err := config.DB.Unscoped().Delete(&models.SeasonTier{}, tierID).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete tier", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Tier deleted", nil)
+}
+
+// --- USER ---
+
+// GET /api/seasons/current
+// Season aktif, semua tier (free & premium) beserta progress dan status klaim user
+func GetCurrentSeason(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+
+	season, err := utils.GetActiveSeason()
+	if err != nil {
+		return seasonErrorResponse(c, err)
+	}
+	pass := utils.GetSeasonPass(userID, season.ID)
+
+	var tiers []models.SeasonTier
+	config.DB.Preload("Item").Where("season_id = ?", season.ID).Order("tier ASC, track ASC").Find(&tiers)
+
+	var claimedIDs []uint
+	config.DB.Model(&models.UserSeasonClaim{}).Where("user_id = ? AND season_id = ?", userID, season.ID).Pluck("season_tier_id", &claimedIDs)
+	claimed := make(map[uint]bool)
+	for _, id := range claimedIDs {
+		claimed[id] = true
+	}
+
+	currentTier := 0
+	tierResponse := []fiber.Map{}
+	for _, t := range tiers {
+		status := "locked"
+		if claimed[t.ID] {
+			status = "claimed"
+		} else if pass.Points >= t.PointsRequired {
+			status = "claimable"
+			if t.Track == "premium" && !pass.Premium {
+				status = "premium_required"
+			}
+		}
+		if t.Track == "free" && pass.Points >= t.PointsRequired && t.Tier > currentTier {
+			currentTier = t.Tier
+		}
+
+		tierResponse = append(tierResponse, fiber.Map{
+			"tier":   t,
+			"status": status,
+		})
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Season retrieved", fiber.Map{
+		"season":       season,
+		"points":       pass.Points,
+		"premium":      pass.Premium,
+		"current_tier": currentTier,
+		"ends_in":      int(time.Until(season.EndAt).Seconds()),
+		"tiers":        tierResponse,
+	})
+}
+
+// POST /api/seasons/current/premium
+func BuySeasonPremium(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+
+	pass, err := utils.BuySeasonPremium(userID)
+	if err != nil {
+		return seasonErrorResponse(c, err)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Premium pass unlocked", pass)
+}
+
+// POST /api/seasons/tiers/:tierId/claim
+func ClaimSeasonTier(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	tierID, _ := strconv.Atoi(c.Params("tierId"))
+
+	tier, err := utils.ClaimSeasonTier(userID, uint(tierID))
+	if err != nil {
+		return seasonErrorResponse(c, err)
+	}
+
+	utils.SendNotification(userID, "success", "Hadiah Season Diklaim", "🎁 Hadiah tier "+strconv.Itoa(tier.Tier)+" berhasil diklaim!", "/season")
+	return utils.SuccessResponse(c, fiber.StatusOK, "Tier claimed", tier)
+}
+
+// GET /api/seasons/history
+// Progress season yang sudah diarsipkan
+func GetSeasonHistory(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+
+	var passes []models.UserSeasonPass
+	config.DB.Preload("Season").Where("user_id = ? AND archived = ?", userID, true).Order("season_id DESC").Find(&passes)
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Season history retrieved", passes)
+}
+
+// GET /api/seasons/titles
+func GetMyTitles(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+
+	var titles []models.UserTitle
+	config.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&titles)
+
+	var user models.User
+	config.DB.Select("id", "title").First(&user, userID)
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Titles retrieved", fiber.Map{
+		"equipped": user.Title,
+		"titles":   titles,
+	})
+}
+
+// PUT /api/seasons/titles/equip
+// Body: {"title": "..."}; string kosong untuk melepas gelar
+func EquipTitle(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	var input struct {
+		Title string `json:"title"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+
+	if input.Title != "" {
+		var count int64
+		config.DB.Model(&models.UserTitle{}).Where("user_id = ? AND title = ?", userID, input.Title).Count(&count)
+		if count == 0 {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Kamu belum memiliki gelar ini", nil)
+		}
+	}
+
+	config.DB.Model(&models.User{}).Where("id = ?", userID).Update("title", input.Title)
+	return utils.SuccessResponse(c, fiber.StatusOK, "Title equipped", fiber.Map{"title": input.Title})
+}
@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type ShopOfferInput struct {
+	ItemID          uint      `json:"item_id"`
+	DiscountPercent int       `json:"discount_percent"`
+	StartAt         time.Time `json:"start_at"`
+	EndAt           time.Time `json:"end_at"`
+}
+
+type ShopBundleInput struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Price       int        `json:"price"`
+	AssetURL    string     `json:"asset_url"`
+	Rarity      string     `json:"rarity"`
+	IsActive    *bool      `json:"is_active"`
+	StartAt     *time.Time `json:"start_at"`
+	EndAt       *time.Time `json:"end_at"`
+	Items       []struct {
+		ItemID   uint `json:"item_id"`
+		Quantity int  `json:"quantity"`
+	} `json:"items"`
+}
+
+// --- OFFERS (Diskon berbatas waktu) ---
+
+// GET /api/admin/shop/offers?active=true
+func GetShopOffersAdmin(c *fiber.Ctx) error {
+	query := config.DB.Preload("Item").Order("end_at DESC")
+	if c.Query("active") == "true" {
+		now := time.Now()
+		query = query.Where("start_at <= ? AND end_at > ?", now, now)
+	}
+
+	var offers []models.ShopOffer
+	query.Find(&offers)
+	return utils.SuccessResponse(c, fiber.StatusOK, "Offers retrieved", offers)
+}
+
+// POST /api/admin/shop/offers
+func CreateShopOffer(c *fiber.Ctx) error {
+	var input ShopOfferInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	if input.DiscountPercent < 1 || input.DiscountPercent > 90 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "discount_percent harus 1-90", nil)
+	}
+	if input.StartAt.IsZero() {
+		input.StartAt = time.Now()
+	}
+	if !input.EndAt.After(input.StartAt) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "end_at harus setelah start_at", nil)
+	}
+
+	var item models.Item
+	if err := config.DB.First(&item, input.ItemID).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Item not found", nil)
+	}
+
+	offer := models.ShopOffer{
+		ItemID:          item.ID,
+		DiscountPercent: input.DiscountPercent,
+		StartAt:         input.StartAt,
+		EndAt:           input.EndAt,
+	}
+	if err := config.DB.Create(&offer).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create offer", err.Error())
+	}
+	offer.Item = item
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Offer created", offer)
+}
+
+// DELETE /api/admin/shop/offers/:id
+func DeleteShopOffer(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	if err := config.DB.Delete(&models.ShopOffer{}, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete offer", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Offer deleted", nil)
+}
+
+// --- BUNDLES ---
+
+func validateBundleInput(input ShopBundleInput) error {
+	if input.Name == "" || input.Price < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Nama wajib diisi dan harga minimal 1")
+	}
+	if _, ok := utils.ShopRarityWeights[input.Rarity]; input.Rarity != "" && !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "rarity tidak valid")
+	}
+	if input.StartAt != nil && input.EndAt != nil && !input.EndAt.After(*input.StartAt) {
+		return fiber.NewError(fiber.StatusBadRequest, "end_at harus setelah start_at")
+	}
+	if len(input.Items) < 2 {
+		return fiber.NewError(fiber.StatusBadRequest, "Bundle minimal berisi 2 item")
+	}
+
+	seen := map[uint]bool{}
+	for _, it := range input.Items {
+		if seen[it.ItemID] {
+			return fiber.NewError(fiber.StatusBadRequest, "Item dalam bundle tidak boleh duplikat")
+		}
+		seen[it.ItemID] = true
+
+		var count int64
+		config.DB.Model(&models.Item{}).Where("id = ?", it.ItemID).Count(&count)
+		if count == 0 {
+			return fiber.NewError(fiber.StatusNotFound, "Item "+strconv.Itoa(int(it.ItemID))+" not found")
+		}
+	}
+	return nil
+}
+
+func saveBundleItems(tx *gorm.DB, bundleID uint, input ShopBundleInput) error {
+	if err := tx.Where("bundle_id = ?", bundleID).Delete(&models.ShopBundleItem{}).Error; err != nil {
+		return err
+	}
+	for _, it := range input.Items {
+		qty := it.Quantity
+		if qty < 1 {
+			qty = 1
+		}
+		if err := tx.Create(&models.ShopBundleItem{BundleID: bundleID, ItemID: it.ItemID, Quantity: qty}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GET /api/admin/shop/bundles
+func GetShopBundlesAdmin(c *fiber.Ctx) error {
+	var bundles []models.ShopBundle
+	config.DB.Preload("Items.Item").Order("id DESC").Find(&bundles)
+	return utils.SuccessResponse(c, fiber.StatusOK, "Bundles retrieved", bundles)
+}
+
+// POST /api/admin/shop/bundles
+func CreateShopBundle(c *fiber.Ctx) error {
+	var input ShopBundleInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	if err := validateBundleInput(input); err != nil {
+		fiberErr := err.(*fiber.Error)
+		return utils.ErrorResponse(c, fiberErr.Code, fiberErr.Message, nil)
+	}
+
+	bundle := models.ShopBundle{
+		Name:        input.Name,
+		Description: input.Description,
+		Price:       input.Price,
+		AssetURL:    input.AssetURL,
+		Rarity:      input.Rarity,
+		IsActive:    input.IsActive == nil || *input.IsActive,
+		StartAt:     input.StartAt,
+		EndAt:       input.EndAt,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// is_active tidak punya default di model, jadi nilai false dari admin tetap tersimpan
+		if err := tx.Omit("Items").Create(&bundle).Error; err != nil {
+			return err
+		}
+		return saveBundleItems(tx, bundle.ID, input)
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create bundle", err.Error())
+	}
+
+	config.DB.Preload("Items.Item").First(&bundle, bundle.ID)
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Bundle created", bundle)
+}
+
+// PUT /api/admin/shop/bundles/:id
+func UpdateShopBundle(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var bundle models.ShopBundle
+	if err := config.DB.Preload("Items").First(&bundle, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Bundle not found", nil)
+	}
+
+	var input ShopBundleInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	if err := validateBundleInput(input); err != nil {
+		fiberErr := err.(*fiber.Error)
+		return utils.ErrorResponse(c, fiberErr.Code, fiberErr.Message, nil)
+	}
+
+	bundle.Name = input.Name
+	bundle.Description = input.Description
+	bundle.Price = input.Price
+	bundle.AssetURL = input.AssetURL
+	bundle.Rarity = input.Rarity
+	bundle.StartAt = input.StartAt
+	bundle.EndAt = input.EndAt
+	if input.IsActive != nil {
+		bundle.IsActive = *input.IsActive
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Items").Save(&bundle).Error; err != nil {
+			return err
+		}
+		return saveBundleItems(tx, bundle.ID, input)
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update bundle", err.Error())
+	}
+
+	config.DB.Preload("Items.Item").First(&bundle, bundle.ID)
+	return utils.SuccessResponse(c, fiber.StatusOK, "Bundle updated", bundle)
+}
+
+// DELETE /api/admin/shop/bundles/:id
+func DeleteShopBundle(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	if err := config.DB.Delete(&models.ShopBundle{}, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete bundle", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Bundle deleted", nil)
+}
+
+// --- ROTATION ---
+
+// POST /api/admin/shop/rotation
+// Acak ulang item unggulan hari ini
+func RegenerateShopRotation(c *fiber.Ctx) error {
+	rotation := utils.RegenerateShopRotation()
+	return utils.SuccessResponse(c, fiber.StatusOK, "Rotation regenerated", rotation)
+}
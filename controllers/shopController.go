@@ -2,6 +2,9 @@
 package controllers
 
 import (
+	"errors"
+	"strconv"
+
 	"github.com/ROFL1ST/quizzes-backend/config"
 	"github.com/ROFL1ST/quizzes-backend/models"
 	"github.com/ROFL1ST/quizzes-backend/utils"
@@ -9,73 +12,83 @@ import (
 )
 
 
+// GetShopItems: item yang sedang dijual (harga akhir, diskon, rarity, status kunci)
 func GetShopItems(c *fiber.Ctx) error {
-	var items []models.Item
+	userID := c.Locals("user_id").(float64)
+	return utils.SuccessResponse(c, fiber.StatusOK, "Shop items retrieved", utils.ShopCatalog(uint(userID), false))
+}
 
-	if err := config.DB.Where("is_active = ?", true).Find(&items).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch items", nil)
-	}
-	return utils.SuccessResponse(c, fiber.StatusOK, "Shop items retrieved", items)
+// GetFeaturedShop: rotasi item unggulan hari ini (sama untuk semua user, reset 00:00 WIB)
+func GetFeaturedShop(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(float64)
+	rotation := utils.GetShopRotation(config.DB)
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Featured items retrieved", fiber.Map{
+		"items":   utils.ShopCatalog(uint(userID), true),
+		"ends_at": rotation.Date.AddDate(0, 0, 1),
+	})
+}
+
+// GetShopBundles: bundle yang sedang dijual
+func GetShopBundles(c *fiber.Ctx) error {
+	return utils.SuccessResponse(c, fiber.StatusOK, "Bundles retrieved", utils.ActiveShopBundles(config.DB))
 }
 
-// BuyItem: Membeli barang
+// BuyItem: Membeli barang atau bundle
 func BuyItem(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(float64) // Dari JWT Middleware
 	var input struct {
-		ItemID uint `json:"item_id"`
+		ItemID   uint `json:"item_id"`
+		BundleID uint `json:"bundle_id"` // Diisi untuk membeli bundle
+		Quantity int  `json:"quantity"`  // Khusus consumable, default 1
 	}
 
 	if err := c.BodyParser(&input); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", nil)
 	}
-
-	// 1. Cek User & Saldo
-	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", nil)
+	if input.Quantity < 1 {
+		input.Quantity = 1
 	}
 
-	// 2. Cek Barang
-	var item models.Item
-	if err := config.DB.First(&item, input.ItemID).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Item not found", nil)
+	// Harga (diskon), syarat & pemotongan koin diproses dalam satu transaksi di utils
+	var purchase utils.ShopPurchase
+	var err error
+	if input.BundleID != 0 {
+		purchase, err = utils.BuyShopBundle(uint(userID), input.BundleID)
+	} else {
+		purchase, err = utils.BuyShopItem(uint(userID), input.ItemID, input.Quantity)
 	}
-
-	// 3. Cek apakah sudah punya
-	var existingItem models.UserItem
-	if err := config.DB.Where("user_id = ? AND item_id = ?", userID, input.ItemID).First(&existingItem).Error; err == nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "You already own this item", nil)
-	}
-
-	// 4. Cek Cukup Uang
-	if user.Coins < item.Price {
+	switch {
+	case errors.Is(err, utils.ErrInsufficientCoins):
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Not enough coins", nil)
-	}
-
-	// 5. Transaksi (Kurangi Koin & Tambah Item)
-	tx := config.DB.Begin()
-
-	user.Coins -= item.Price
-	if err := tx.Save(&user).Error; err != nil {
-		tx.Rollback()
+	case errors.Is(err, utils.ErrItemOwned):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "You already own this item", nil)
+	case errors.Is(err, utils.ErrItemStackFull):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Stok item ini sudah maksimal", nil)
+	case errors.Is(err, utils.ErrShopItemUnavailable), errors.Is(err, utils.ErrShopBundleUnavailable):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, utils.ErrShopLevelLocked), errors.Is(err, utils.ErrShopAchievementLocked):
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error(), nil)
+	case err != nil:
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Transaction failed", nil)
 	}
 
-	userItem := models.UserItem{
-		UserID: user.ID,
-		ItemID: item.ID,
-	}
-	if err := tx.Create(&userItem).Error; err != nil {
-		tx.Rollback()
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to add item", nil)
+	// Item biasa hanya bisa dibeli sekali; consumable & bundle boleh dibeli berulang jadi tanpa key
+	buyKey := ""
+	itemType := "bundle"
+	if purchase.Item != nil {
+		itemType = purchase.Item.Type
+		if !purchase.Item.Consumable {
+			buyKey = "shop_buy:item:" + strconv.Itoa(int(purchase.Item.ID))
+		}
 	}
-
-	tx.Commit()
-	utils.CheckDailyMissions(uint(userID), "shop", 1, "buy")
-	return utils.SuccessResponse(c, fiber.StatusOK, "Item purchased successfully", fiber.Map{
-		"coins_left": user.Coins,
-		"item":       item,
+	utils.TrackMissionEvent(uint(userID), utils.MissionEvent{
+		Type:  utils.MissionShopBuy,
+		Key:   buyKey,
+		Value: purchase.Price,
+		Attrs: map[string]interface{}{"item_type": itemType, "price": purchase.Price},
 	})
+	return utils.SuccessResponse(c, fiber.StatusOK, "Item purchased successfully", purchase)
 }
 
 // GetMyInventory: Melihat barang yang sudah dibeli
@@ -83,7 +96,8 @@ func GetMyInventory(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(float64)
 	var myItems []models.UserItem
 	
-	config.DB.Preload("Item").Where("user_id = ?", userID).Find(&myItems)
+	// Consumable yang sudah habis tidak ditampilkan
+	config.DB.Preload("Item").Where("user_id = ? AND quantity > 0", userID).Find(&myItems)
 	
 	return utils.SuccessResponse(c, fiber.StatusOK, "Inventory retrieved", myItems)
 }
@@ -103,6 +117,9 @@ func EquipItem(c *fiber.Ctx) error {
 	if err := config.DB.Preload("Item").Where("user_id = ? AND item_id = ?", userID, input.ItemID).First(&userItem).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "You don't own this item", nil)
 	}
+	if userItem.Item.Consumable {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Consumable item cannot be equipped", nil)
+	}
 
 	// 2. Un-equip semua item lain dengan TIPE yang sama (misal: copot frame lama)
 	// Query: Update user_items set is_equipped = false where user_id = X and item_id IN (select id from items where type = Y)
@@ -113,6 +130,10 @@ func EquipItem(c *fiber.Ctx) error {
 	// 3. Equip item yang baru dipilih
 	userItem.IsEquipped = true
 	config.DB.Save(&userItem)
-	utils.CheckDailyMissions(uint(userID), "shop", 1, "equip")
+	utils.TrackMissionEvent(uint(userID), utils.MissionEvent{
+		Type:  utils.MissionShopEquip,
+		Value: 1,
+		Attrs: map[string]interface{}{"item_type": userItem.Item.Type},
+	})
 	return utils.SuccessResponse(c, fiber.StatusOK, "Item equipped successfully", userItem.Item)
 }
\ No newline at end of file
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GET /api/streak
+// Status streak kuis, stok Streak Freeze / Repair dan info repair jika streak baru putus
+func GetStreakStatus(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	utils.SettleStreak(userID)
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", nil)
+	}
+
+	response := fiber.Map{
+		"streak_count":   user.StreakCount,
+		"streak_freezes": utils.CountUserItems(config.DB, userID, utils.ItemStreakFreeze),
+		"streak_repairs": utils.CountUserItems(config.DB, userID, utils.ItemStreakRepair),
+		"repairable":     false,
+	}
+	if deadline, ok := utils.StreakRepairDeadline(user); ok {
+		response["repairable"] = true
+		response["lost_streak"] = user.LostStreak
+		response["repair_deadline"] = deadline
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Streak status retrieved", response)
+}
+
+// POST /api/streak/repair
+func RepairStreak(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	utils.SettleStreak(userID)
+
+	streak, err := utils.RepairStreak(userID)
+	switch {
+	case errors.Is(err, utils.ErrStreakNotRepairable):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Tidak ada streak yang perlu dipulihkan", nil)
+	case errors.Is(err, utils.ErrStreakRepairExpired):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Batas waktu repair streak (48 jam) sudah lewat", nil)
+	case errors.Is(err, utils.ErrItemNotOwned):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Kamu tidak punya Streak Repair, beli di toko", nil)
+	case err != nil:
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to repair streak", err.Error())
+	}
+
+	utils.SendNotification(userID, "success", "Streak Dipulihkan", "🔥 Streak kamu kembali!", "/")
+	return utils.SuccessResponse(c, fiber.StatusOK, "Streak repaired", fiber.Map{"streak_count": streak})
+}
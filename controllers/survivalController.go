@@ -1,108 +1,266 @@
 package controllers
 
 import (
+	"errors"
+
 	"github.com/ROFL1ST/quizzes-backend/config"
 	"github.com/ROFL1ST/quizzes-backend/models"
 	"github.com/ROFL1ST/quizzes-backend/utils"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
+type StartSurvivalInput struct {
+	Mode        string `json:"mode"`         // classic (default) atau daily
+	TopicSlug   string `json:"topic_slug"`   // Opsional: soal hanya dari satu topik
+	Lives       int    `json:"lives"`        // 1-3, default 1 (daily selalu 1)
+	ChallengeID uint   `json:"challenge_id"` // Opsional: run untuk challenge mode survival
+}
+
+type AnswerSurvivalInput struct {
+	RunID      uint   `json:"run_id"`
+	QuestionID uint   `json:"question_id"`
+	Answer     string `json:"answer"`
+}
+
+// survivalQuestionPayload menyembunyikan kunci jawaban dari soal yang dikirim ke client
+func survivalQuestionPayload(q models.Question) fiber.Map {
+	return fiber.Map{
+		"id":       q.ID,
+		"quiz_id":  q.QuizID,
+		"question": q.QuestionText,
+		"options":  q.Options,
+		"type":     q.Type,
+		"hint":     q.Hint,
+	}
+}
+
+func survivalRunPayload(run models.SurvivalRun) fiber.Map {
+	return fiber.Map{
+		"run_id":      run.ID,
+		"mode":        run.Mode,
+		"topic_id":    run.TopicID,
+		"ranked":      !run.Unranked,
+		"status":      run.Status,
+		"score":       run.Score,
+		"streak":      run.Streak,
+		"best_streak": run.BestStreak,
+		"answered":    run.Answered,
+		"lives":       run.Lives,
+		"max_lives":   run.MaxLives,
+		"shields":     run.Shields,
+		"difficulty":  run.Difficulty,
+	}
+}
+
 // StartSurvival starts a survival game session
 func StartSurvival(c *fiber.Ctx) error {
-	// Accept optional seed for deterministic random (multiplayer)
-	seed := c.Query("seed", "")
+	userID := c.Locals("user_id").(float64)
+
+	var input StartSurvivalInput
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+		}
+	}
+	if input.Mode == "" {
+		input.Mode = "classic"
+	}
+	if input.Mode != "classic" && input.Mode != "daily" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "mode must be classic or daily", nil)
+	}
 
-	var question models.Question
+	// Seed tidak lagi diterima dari client; urutan soal seeded hanya dari server
+	opts := utils.SurvivalRunOptions{Mode: input.Mode, Lives: input.Lives}
 
-	query := config.DB
-	if seed != "" {
-		// Deterministic Order for Multiplayer
-		// MD5(id || seed)
-		query = query.Order(config.DB.Raw("MD5(CAST(id AS TEXT) || ?)", seed))
-	} else {
-		// Pure Random for Singleplayer
-		query = query.Order("RANDOM()")
+	if input.TopicSlug != "" && input.Mode == "classic" {
+		var topic models.Topic
+		if err := config.DB.Where("slug = ?", input.TopicSlug).First(&topic).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Topic not found", nil)
+		}
+		opts.TopicID = &topic.ID
 	}
 
-	if err := query.First(&question).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get question", err.Error())
+	if input.ChallengeID != 0 && input.Mode == "classic" {
+		var participant models.ChallengeParticipant
+		if err := config.DB.Where("challenge_id = ? AND user_id = ? AND status = ?", input.ChallengeID, uint(userID), "accepted").First(&participant).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "You are not in this challenge", nil)
+		}
+		if participant.IsFinished {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Already played", nil)
+		}
+
+		var challenge models.Challenge
+		if err := config.DB.First(&challenge, input.ChallengeID).Error; err != nil || challenge.Mode != "survival" {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Challenge is not a survival challenge", nil)
+		}
+		if challenge.Status != "active" && challenge.Status != "pending" {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Challenge is not running", nil)
+		}
+
+		// Satu run per challenge: run yang masih aktif dilanjutkan, selain itu ditolak
+		var existing models.SurvivalRun
+		if err := config.DB.Where("user_id = ? AND challenge_id = ?", uint(userID), challenge.ID).Order("id DESC").First(&existing).Error; err == nil {
+			if existing.Status != "active" || existing.CurrentQuestionID == nil {
+				return utils.ErrorResponse(c, fiber.StatusBadRequest, "Already played", nil)
+			}
+			response := fiber.Map{"run": survivalRunPayload(existing), "streak": existing.Streak}
+			var question models.Question
+			if err := config.DB.First(&question, *existing.CurrentQuestionID).Error; err == nil {
+				response["question"] = survivalQuestionPayload(question)
+			}
+			return utils.SuccessResponse(c, fiber.StatusOK, "Survival resumed", response)
+		}
+		if challenge.Seed == "" {
+			challenge.Seed = utils.NewSurvivalSeed()
+			config.DB.Model(&challenge).Update("seed", challenge.Seed)
+		}
+		opts.Mode = "classic"
+		opts.ChallengeID = &challenge.ID
+		opts.Seed = challenge.Seed
+		// Nyawa ditentukan challenge supaya semua peserta main dengan aturan yang sama
+		opts.Lives = max(challenge.SurvivalLives, 1)
+		opts.TopicID = nil
+	}
+
+	run, question, err := utils.StartSurvivalRun(uint(userID), opts)
+	if errors.Is(err, utils.ErrSurvivalNoQuestions) {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "No questions available", nil)
+	}
+	if errors.Is(err, utils.ErrSurvivalAlreadyPlayed) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Already played", nil)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to start survival", err.Error())
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Survival Started", fiber.Map{
-		"question": question,
+		"run":      survivalRunPayload(run),
+		"question": survivalQuestionPayload(question),
 		"streak":   0,
 	})
 }
 
 // AnswerSurvival processes the answer for survival mode
 func AnswerSurvival(c *fiber.Ctx) error {
-	// user := c.Locals("user").(*models.User) // Not used yet
-	var input struct {
-		QuestionID uint   `json:"question_id"`
-		Answer     string `json:"answer"`
-		Streak     int    `json:"streak"`
-		Seed       string `json:"seed"` // New: Seed for deterministic next question
-	}
+	userID := c.Locals("user_id").(float64)
 
+	var input AnswerSurvivalInput
 	if err := c.BodyParser(&input); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
 	}
 
-	var question models.Question
-	if err := config.DB.First(&question, input.QuestionID).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Question not found", nil)
-	}
-
-	// Normalize Answer (trim space)
-	if question.CorrectAnswer != input.Answer {
-		// GAME OVER
-		return utils.SuccessResponse(c, fiber.StatusOK, "Game Over", fiber.Map{
-			"correct":        false,
-			"correct_answer": question.CorrectAnswer,
-			"final_streak":   input.Streak,
-		})
-	}
-
-	// Correct! Get next question
-	// Use Offset = streak + 1 (assuming current was at offset 'streak')
-	// Wait, if users passed 'streak' as current count of correct answers (e.g. 0 initially),
-	// then the question they just answered was at offset 0?
-	// If input.Streak is 5 (they have 5 correct answers), they just answered the 6th question (index 5)?
-	// Let's assume input.Streak is the CURRENT streak count BEFORE this answer.
-	// So if input.Streak = 0. They answered question #1. Result new streak = 1. Next question is #2 (offset 1).
-
-	nextOffset := input.Streak + 1
-
-	var nextQuestion models.Question
-
-	query := config.DB
-	if input.Seed != "" {
-		// Deterministic
-		query = query.Order(config.DB.Raw("MD5(CAST(id AS TEXT) || ?)", input.Seed))
-	} else {
-		// Random but Exclude previous IDs?
-		// In pure random mode, we can't easily exclude ALL previous without sending them ALL.
-		// For simplicity in single player, just ensuring it's not the SAME question is often enough,
-		// or we can live with true random.
-		// A better approach for single player is also generating a random seed client side?
-		// For now, keep existing single player logic (just != current) + Random.
-		query = query.Where("id != ?", question.ID).Order("RANDOM()")
-	}
-
-	// If seeded, use offset. If random, just pick one.
-	if input.Seed != "" {
-		if err := query.Offset(nextOffset).First(&nextQuestion).Error; err != nil {
-			// Might run out of questions? Reset offset?
-			// For now, assume ample questions. If error, maybe wrap around.
-			query.Offset(0).First(&nextQuestion)
+	result, err := utils.AnswerSurvivalRun(input.RunID, uint(userID), input.QuestionID, input.Answer)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Survival run not found", nil)
+	case errors.Is(err, utils.ErrSurvivalRunNotActive), errors.Is(err, utils.ErrSurvivalWrongQuestion):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	case err != nil:
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to process answer", err.Error())
+	}
+
+	response := fiber.Map{
+		"correct":     result.Correct,
+		"shield_used": result.ShieldUsed,
+		"game_over":   result.GameOver,
+		"run":         survivalRunPayload(result.Run),
+		"new_streak":  result.Run.Streak,
+	}
+	if !result.Correct {
+		response["correct_answer"] = result.CorrectAnswer
+	}
+
+	if result.GameOver {
+		response["final_streak"] = result.Run.BestStreak
+		response["final_score"] = result.Run.Score
+		response["history_id"] = result.Run.HistoryID
+		response["share_text"] = utils.SurvivalShareText(result.Run)
+		return utils.SuccessResponse(c, fiber.StatusOK, "Game Over", response)
+	}
+
+	response["next_question"] = survivalQuestionPayload(*result.NextQuestion)
+	message := "Correct!"
+	if !result.Correct {
+		message = "Wrong answer"
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, message, response)
+}
+
+// GetActiveSurvival mengembalikan run aktif user (untuk melanjutkan setelah refresh)
+func GetActiveSurvival(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(float64)
+
+	var run models.SurvivalRun
+	if err := config.DB.Where("user_id = ? AND status = ?", uint(userID), "active").Order("id DESC").First(&run).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "No active survival run", nil)
+	}
+
+	response := fiber.Map{"run": survivalRunPayload(run)}
+	if run.CurrentQuestionID != nil {
+		var question models.Question
+		if err := config.DB.First(&question, *run.CurrentQuestionID).Error; err == nil {
+			response["question"] = survivalQuestionPayload(question)
+		}
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Survival run retrieved", response)
+}
+
+// GetSurvivalLeaderboard: ?period=daily|weekly|all_time (default all_time), ?topic=slug opsional,
+// ?lives=1-3 (default 1), tiap jumlah nyawa punya leaderboard sendiri
+func GetSurvivalLeaderboard(c *fiber.Ctx) error {
+	period := c.Query("period", "all_time")
+	if period != "daily" && period != "weekly" && period != "all_time" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "period must be daily, weekly or all_time", nil)
+	}
+	lives := c.QueryInt("lives", 1)
+	if lives < 1 || lives > utils.SurvivalMaxLives {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "lives must be between 1 and 3", nil)
+	}
+
+	var topicID *uint
+	if slug := c.Query("topic"); slug != "" {
+		var topic models.Topic
+		if err := config.DB.Where("slug = ?", slug).First(&topic).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Topic not found", nil)
 		}
-	} else {
-		query.First(&nextQuestion)
+		topicID = &topic.ID
 	}
 
-	return utils.SuccessResponse(c, fiber.StatusOK, "Correct!", fiber.Map{
-		"correct":       true,
-		"new_streak":    input.Streak + 1,
-		"next_question": nextQuestion,
+	return utils.SuccessResponse(c, fiber.StatusOK, "Survival leaderboard retrieved", fiber.Map{
+		"period":  period,
+		"topic":   c.Query("topic"),
+		"lives":   lives,
+		"entries": utils.GetSurvivalLeaderboard(period, topicID, lives),
 	})
 }
+
+// GetDailySurvival: info Daily Survival hari ini, hasil ranked user (dengan teks share), dan leaderboard
+func GetDailySurvival(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(float64)
+
+	daily, err := utils.GetDailySurvivalSeed()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get daily survival", err.Error())
+	}
+
+	response := fiber.Map{
+		"date":        daily.Date.Format("2006-01-02"),
+		"number":      utils.SurvivalDailyNumber(daily.Date),
+		"played":      false,
+		"leaderboard": utils.GetDailySurvivalLeaderboard(daily.Date),
+	}
+
+	var ranked models.SurvivalRun
+	if err := config.DB.Where("user_id = ? AND mode = ? AND daily_date = ? AND unranked = ?", uint(userID), "daily", daily.Date, false).
+		First(&ranked).Error; err == nil {
+		response["played"] = true
+		response["my_run"] = survivalRunPayload(ranked)
+		if ranked.Status == "finished" {
+			response["share_text"] = utils.SurvivalShareText(ranked)
+		}
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Daily survival retrieved", response)
+}
@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type CreateTournamentInput struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Format      string     `json:"format"`  // single_elimination, double_elimination, swiss
+	SeedBy      string     `json:"seed_by"` // level, rating
+	QuizID      uint       `json:"quiz_id"`
+	TimeLimit   int        `json:"time_limit"`
+	MaxPlayers  int        `json:"max_players"`
+	SwissRounds int        `json:"swiss_rounds"`
+	RoundHours  int        `json:"round_hours"`
+	EntryFee    int        `json:"entry_fee"`
+	PrizePool   int        `json:"prize_pool"` // Hadiah dasar dari admin, entry fee ditambahkan otomatis
+	PrizeSplit  string     `json:"prize_split"`
+	StartAt     *time.Time `json:"start_at"`
+}
+
+var tournamentFormats = map[string]bool{
+	"single_elimination": true,
+	"double_elimination": true,
+	"swiss":              true,
+}
+
+// --- ADMIN ---
+
+func CreateTournament(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(float64)
+
+	var input CreateTournamentInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+
+	if input.Name == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Nama turnamen wajib diisi", nil)
+	}
+	if input.Format == "" {
+		input.Format = "single_elimination"
+	}
+	if !tournamentFormats[input.Format] {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Format tidak valid", nil)
+	}
+	if input.SeedBy == "" {
+		input.SeedBy = "level"
+	}
+	if input.SeedBy != "level" && input.SeedBy != "rating" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "seed_by harus level atau rating", nil)
+	}
+	if input.EntryFee < 0 || input.PrizePool < 0 || input.MaxPlayers < 0 || input.RoundHours < 0 || input.SwissRounds < 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Nilai tidak boleh negatif", nil)
+	}
+	if input.MaxPlayers == 0 {
+		input.MaxPlayers = 32
+	}
+	if input.RoundHours == 0 {
+		input.RoundHours = 24
+	}
+	if input.PrizeSplit == "" {
+		input.PrizeSplit = "50,30,20"
+	}
+	total := 0
+	for _, pct := range utils.ParsePrizeSplit(input.PrizeSplit) {
+		total += pct
+	}
+	if total > 100 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Total prize_split tidak boleh lebih dari 100", nil)
+	}
+
+	var quizIDPtr *uint
+	if input.QuizID != 0 {
+		var quiz models.Quiz
+		if err := config.DB.First(&quiz, input.QuizID).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Quiz not found", nil)
+		}
+		quizIDPtr = &input.QuizID
+	}
+
+	tournament := models.Tournament{
+		Name:        input.Name,
+		Description: input.Description,
+		Format:      input.Format,
+		SeedBy:      input.SeedBy,
+		QuizID:      quizIDPtr,
+		TimeLimit:   input.TimeLimit,
+		MaxPlayers:  input.MaxPlayers,
+		SwissRounds: input.SwissRounds,
+		RoundHours:  input.RoundHours,
+		EntryFee:    input.EntryFee,
+		PrizePool:   input.PrizePool,
+		PrizeSplit:  input.PrizeSplit,
+		Status:      "registration",
+		StartAt:     input.StartAt,
+		AdminID:     uint(adminID),
+	}
+
+	// Hadiah dasar admin dicatat di ledger sebagai saldo awal akun turnamen
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tournament).Error; err != nil {
+			return err
+		}
+		return utils.MoveCoins(tx, utils.AccountRewards, utils.TournamentAccount(tournament.ID), tournament.PrizePool,
+			"tournament_base_pool", utils.LedgerRef{Type: "tournament", ID: tournament.ID})
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create tournament", err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Tournament created", tournament)
+}
+
+func StartTournamentAdmin(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	if err := utils.StartTournament(uint(id)); err != nil {
+		return tournamentErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Tournament started", nil)
+}
+
+func CancelTournamentAdmin(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	if err := utils.CancelTournament(uint(id)); err != nil {
+		return tournamentErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Tournament cancelled, entry fees refunded", nil)
+}
+
+// --- USER ---
+
+func GetTournaments(c *fiber.Ctx) error {
+	params := utils.GetPaginationParams(c)
+	status := c.Query("status")
+
+	query := config.DB.Model(&models.Tournament{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to count tournaments", err.Error())
+	}
+
+	var tournaments []models.Tournament
+	if err := query.Preload("Quiz").
+		Order("created_at desc").
+		Offset(params.Offset).
+		Limit(params.PageSize).
+		Find(&tournaments).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch tournaments", err.Error())
+	}
+
+	return utils.PaginatedSuccessResponse(c, fiber.StatusOK, "Tournaments retrieved", tournaments, total, params)
+}
+
+func GetTournamentDetail(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userID := uint(c.Locals("user_id").(float64))
+
+	var tournament models.Tournament
+	if err := config.DB.Preload("Quiz").First(&tournament, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Tournament not found", nil)
+	}
+
+	var playerCount int64
+	config.DB.Model(&models.TournamentEntry{}).Where("tournament_id = ?", tournament.ID).Count(&playerCount)
+
+	var myEntry models.TournamentEntry
+	joined := config.DB.Where("tournament_id = ? AND user_id = ?", tournament.ID, userID).First(&myEntry).Error == nil
+
+	response := fiber.Map{
+		"tournament":   tournament,
+		"player_count": playerCount,
+		"joined":       joined,
+	}
+	if joined {
+		response["my_entry"] = myEntry
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Tournament retrieved", response)
+}
+
+func JoinTournament(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	userID := c.Locals("user_id").(float64)
+
+	if err := utils.JoinTournament(uint(id), uint(userID)); err != nil {
+		return tournamentErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Joined tournament", nil)
+}
+
+func LeaveTournament(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	userID := c.Locals("user_id").(float64)
+
+	if err := utils.LeaveTournament(uint(id), uint(userID)); err != nil {
+		return tournamentErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Left tournament, entry fee refunded", nil)
+}
+
+// GetTournamentBracket mengembalikan semua match dikelompokkan per ronde
+func GetTournamentBracket(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var tournament models.Tournament
+	if err := config.DB.First(&tournament, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Tournament not found", nil)
+	}
+
+	var matches []models.TournamentMatch
+	config.DB.Preload("PlayerA").Preload("PlayerB").
+		Where("tournament_id = ?", tournament.ID).
+		Order("round ASC, position ASC").
+		Find(&matches)
+
+	roundMap := make(map[int][]models.TournamentMatch)
+	var rounds []int
+	for _, m := range matches {
+		if _, ok := roundMap[m.Round]; !ok {
+			rounds = append(rounds, m.Round)
+		}
+		roundMap[m.Round] = append(roundMap[m.Round], m)
+	}
+	sort.Ints(rounds)
+
+	var bracket []fiber.Map
+	for _, r := range rounds {
+		bracket = append(bracket, fiber.Map{
+			"round":   r,
+			"matches": roundMap[r],
+		})
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Bracket retrieved", fiber.Map{
+		"format":        tournament.Format,
+		"status":        tournament.Status,
+		"current_round": tournament.CurrentRound,
+		"rounds":        bracket,
+	})
+}
+
+func GetTournamentStandings(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var tournament models.Tournament
+	if err := config.DB.First(&tournament, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Tournament not found", nil)
+	}
+
+	var entries []models.TournamentEntry
+	config.DB.Preload("User").Where("tournament_id = ?", tournament.ID).Find(&entries)
+
+	var standings []fiber.Map
+	for i, e := range utils.TournamentStandings(tournament, entries) {
+		standings = append(standings, fiber.Map{
+			"rank":       i + 1,
+			"user_id":    e.UserID,
+			"username":   e.User.Username,
+			"name":       e.User.Name,
+			"seed":       e.Seed,
+			"wins":       e.Wins,
+			"losses":     e.Losses,
+			"draws":      e.Draws,
+			"points":     e.Points,
+			"byes":       e.Byes,
+			"eliminated": e.Eliminated,
+			"prize":      e.Prize,
+		})
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Standings retrieved", fiber.Map{
+		"status":     tournament.Status,
+		"prize_pool": tournament.PrizePool,
+		"standings":  standings,
+	})
+}
+
+func tournamentErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Tournament not found", nil)
+	case errors.Is(err, utils.ErrInsufficientCoins):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Koin tidak cukup untuk entry fee!", nil)
+	case errors.Is(err, utils.ErrTournamentJoined):
+		return utils.ErrorResponse(c, fiber.StatusConflict, err.Error(), nil)
+	case errors.Is(err, utils.ErrTournamentNotOpen),
+		errors.Is(err, utils.ErrTournamentFull),
+		errors.Is(err, utils.ErrTournamentNotJoined),
+		errors.Is(err, utils.ErrTournamentTooFew),
+		errors.Is(err, utils.ErrTournamentNotRunning):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Tournament operation failed", err.Error())
+}
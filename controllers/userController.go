@@ -117,7 +117,7 @@ func GetMyProfile(c *fiber.Ctx) error {
 
 	if calculatedLevel > user.Level {
 		user.Level = calculatedLevel
-		config.DB.Save(&user) // Simpan perbaikan ke database
+		config.DB.Omit("coins").Save(&user) // Simpan perbaikan ke database
 	}
 	currentLevel := user.Level
 	nextLevel := currentLevel + 1
@@ -230,7 +230,7 @@ func UpdateProfile(c *fiber.Ctx) error {
 		}(user.Email, user.EmailVerificationToken)
 	}
 
-	if err := config.DB.Save(&user).Error; err != nil {
+	if err := config.DB.Omit("coins").Save(&user).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update profile", err.Error())
 	}
 
@@ -256,7 +256,7 @@ func VerifyEmail(c *fiber.Ctx) error {
 
 	user.IsEmailVerified = true
 	user.EmailVerificationToken = "" // Hapus token setelah dipakai
-	config.DB.Save(&user)
+	config.DB.Omit("coins").Save(&user)
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Email verified successfully", nil)
 }
@@ -335,13 +335,28 @@ func GetMyAchievements(c *fiber.Ctx) error {
 	type AchievementResponse struct {
 		models.Achievement
 		IsUnlocked bool `json:"is_unlocked"`
+		Progress   int  `json:"progress"`
 	}
 
 	var response []AchievementResponse
 	for _, ach := range allAchievements {
+		// Achievement yang sudah pensiun hanya tampil untuk yang sudah membukanya
+		if !ach.IsActive && !unlockedMap[ach.ID] {
+			continue
+		}
+
+		progress := ach.Threshold
+		if !unlockedMap[ach.ID] {
+			progress = utils.AchievementProgress(ach, uint(userID))
+			if progress > ach.Threshold {
+				progress = ach.Threshold
+			}
+		}
+
 		response = append(response, AchievementResponse{
 			Achievement: ach,
 			IsUnlocked:  unlockedMap[ach.ID],
+			Progress:    progress,
 		})
 	}
 
@@ -352,7 +367,7 @@ func GetMyAchievements(c *fiber.Ctx) error {
 func ShareProfileTrigger(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(float64)
 
-	utils.CheckDailyMissions(uint(userID), "social", 1, "share")
+	utils.TrackMissionEvent(uint(userID), utils.MissionEvent{Type: utils.MissionProfileShare, Value: 1})
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Share event recorded", nil)
 }
@@ -402,3 +417,26 @@ func GetActivityCalendar(c *fiber.Ctx) error {
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Kalender Aktivitas", dates)
 }
+
+// GET /api/users/activity/calendar/details
+// Sama seperti kalender biasa, tapi tiap tanggal membawa type supaya client lama tetap menerima []string
+func GetActivityCalendarDetails(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(float64)
+	// type: active (main kuis), frozen (Streak Freeze), repaired (Streak Repair)
+	var dates []struct {
+		Date string `json:"date"`
+		Type string `json:"type"`
+	}
+
+	err := config.DB.Model(&models.StreakLog{}).
+		Select("TO_CHAR(date, 'YYYY-MM-DD') AS date, COALESCE(NULLIF(type, ''), 'active') AS type").
+		Where("user_id = ?", userID).
+		Order("date desc").
+		Scan(&dates).Error
+
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Gagal mengambil kalender", err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Kalender Aktivitas", dates)
+}
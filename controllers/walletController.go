@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GET /api/wallet/transactions?reason=shop_purchase
+// Riwayat mutasi koin user dari ledger, terbaru dulu
+func GetWalletTransactions(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	params := utils.GetPaginationParams(c)
+
+	query := config.DB.Model(&models.CoinTransaction{}).Where("user_id = ?", userID)
+	if reason := c.Query("reason"); reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to count transactions", err.Error())
+	}
+
+	var transactions []models.CoinTransaction
+	if err := query.Order("id DESC").
+		Offset(params.Offset).
+		Limit(params.PageSize).
+		Find(&transactions).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch transactions", err.Error())
+	}
+
+	return utils.PaginatedSuccessResponse(c, fiber.StatusOK, "Transactions retrieved", transactions, total, params)
+}
+
+// GET /api/wallet
+// Saldo koin user beserta saldo menurut ledger
+func GetWallet(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+
+	var user models.User
+	if err := config.DB.Select("id", "coins").First(&user, userID).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", nil)
+	}
+
+	var ledgerBalance int
+	config.DB.Model(&models.CoinTransaction{}).Select("COALESCE(SUM(amount), 0)").Where("user_id = ?", userID).Scan(&ledgerBalance)
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Wallet retrieved", fiber.Map{
+		"coins":          user.Coins,
+		"ledger_balance": ledgerBalance,
+		"in_sync":        user.Coins == ledgerBalance,
+	})
+}
+
+// GET /api/admin/wallet/reconciliation
+// Laporan rekonsiliasi saldo user vs ledger koin
+func GetLedgerReconciliation(c *fiber.Ctx) error {
+	return utils.SuccessResponse(c, fiber.StatusOK, "Reconciliation report", utils.ReconcileLedger())
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/ROFL1ST/quizzes-backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type XPEventInput struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Multiplier  float64   `json:"multiplier"`
+	TopicID     *uint     `json:"topic_id"`
+	StartAt     time.Time `json:"start_at"`
+	EndAt       time.Time `json:"end_at"`
+}
+
+// --- XP FORMULA (Supervisor) ---
+
+// GET /api/admin/config/xp-formula
+func GetXPFormulaConfig(c *fiber.Ctx) error {
+	return utils.SuccessResponse(c, fiber.StatusOK, "Config retrieved", utils.GetXPFormula())
+}
+
+// PUT /api/admin/config/xp-formula
+func UpdateXPFormulaConfig(c *fiber.Ctx) error {
+	// Mulai dari rumus sekarang supaya admin bisa mengirim sebagian field saja
+	formula := utils.GetXPFormula()
+	if err := c.BodyParser(&formula); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	if err := utils.SaveXPFormula(formula); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "XP formula updated", utils.GetXPFormula())
+}
+
+// --- XP EVENTS ---
+
+func validateXPEventInput(input XPEventInput) (string, bool) {
+	if input.Name == "" {
+		return "Nama event wajib diisi", false
+	}
+	if input.Multiplier <= 1 || input.Multiplier > 5 {
+		return "multiplier harus lebih dari 1 dan maksimal 5", false
+	}
+	if input.StartAt.IsZero() || !input.EndAt.After(input.StartAt) {
+		return "end_at harus setelah start_at", false
+	}
+	if input.TopicID != nil {
+		var count int64
+		config.DB.Model(&models.Topic{}).Where("id = ?", *input.TopicID).Count(&count)
+		if count == 0 {
+			return "Topic not found", false
+		}
+	}
+	return "", true
+}
+
+// GET /api/admin/xp-events
+func GetXPEventsAdmin(c *fiber.Ctx) error {
+	var events []models.XPEvent
+	config.DB.Preload("Topic").Order("start_at DESC").Find(&events)
+	return utils.SuccessResponse(c, fiber.StatusOK, "XP events retrieved", events)
+}
+
+// POST /api/admin/xp-events
+func CreateXPEvent(c *fiber.Ctx) error {
+	var input XPEventInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	if msg, ok := validateXPEventInput(input); !ok {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, msg, nil)
+	}
+
+	event := models.XPEvent{
+		Name:        input.Name,
+		Description: input.Description,
+		Multiplier:  input.Multiplier,
+		TopicID:     input.TopicID,
+		StartAt:     input.StartAt,
+		EndAt:       input.EndAt,
+	}
+	if err := config.DB.Create(&event).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create XP event", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusCreated, "XP event created", event)
+}
+
+// PUT /api/admin/xp-events/:id
+func UpdateXPEvent(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var event models.XPEvent
+	if err := config.DB.First(&event, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "XP event not found", nil)
+	}
+
+	var input XPEventInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid input", err.Error())
+	}
+	if msg, ok := validateXPEventInput(input); !ok {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, msg, nil)
+	}
+
+	event.Name = input.Name
+	event.Description = input.Description
+	event.Multiplier = input.Multiplier
+	event.TopicID = input.TopicID
+	event.StartAt = input.StartAt
+	event.EndAt = input.EndAt
+	if err := config.DB.Omit("Topic").Save(&event).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update XP event", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "XP event updated", event)
+}
+
+// DELETE /api/admin/xp-events/:id
+func DeleteXPEvent(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	if err := config.DB.Delete(&models.XPEvent{}, id).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete XP event", err.Error())
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "XP event deleted", nil)
+}
+
+// GET /api/xp-events
+// Event yang sedang berjalan dan yang mulai dalam 7 hari ke depan
+func GetXPEvents(c *fiber.Ctx) error {
+	now := time.Now()
+
+	var active, upcoming []models.XPEvent
+	config.DB.Preload("Topic").Where("start_at <= ? AND end_at > ?", now, now).Order("end_at ASC").Find(&active)
+	config.DB.Preload("Topic").Where("start_at > ? AND start_at <= ?", now, now.AddDate(0, 0, 7)).Order("start_at ASC").Find(&upcoming)
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "XP events retrieved", fiber.Map{
+		"active":   active,
+		"upcoming": upcoming,
+	})
+}
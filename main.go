@@ -5,6 +5,7 @@ import (
 
 	"github.com/ROFL1ST/quizzes-backend/config"
 	"github.com/ROFL1ST/quizzes-backend/routes"
+	"github.com/ROFL1ST/quizzes-backend/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -17,7 +18,12 @@ func main() {
 	config.SeedAchievements()
 	config.SeedShopItems()
 	config.SeedDailyData()
+	utils.SeedOpeningBalances()
 	// config.MigrateOldChallenges()
+	utils.StartChallengeExpiryJob()
+	utils.StartTournamentJob()
+	utils.StartSeasonJob()
+	utils.StartLeagueJob()
 	app := fiber.New()
 
 	app.Use(cors.New(cors.Config{
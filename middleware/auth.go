@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -26,9 +27,11 @@ func Protected() fiber.Handler {
 			tokenString = c.Cookies("token")
 		}
 
-		// if tokenString == "" {
-		// 	tokenString = c.Query("token")
-		// }
+		// Browser tidak bisa kirim header Authorization saat membuka WebSocket,
+		// jadi khusus request upgrade token boleh lewat query string
+		if tokenString == "" && websocket.IsWebSocketUpgrade(c) {
+			tokenString = c.Query("token")
+		}
 
 		if tokenString == "" {
 			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
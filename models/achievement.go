@@ -4,15 +4,36 @@ import (
 	"time"
 )
 
+// Achievement didefinisikan sebagai aturan data: dievaluasi saat event Trigger terjadi,
+// unlock jika Metric (dengan filter opsional) mencapai Threshold.
 type Achievement struct {
 	ID          uint   `json:"id" gorm:"primaryKey"`
+	Key         string `json:"key" gorm:"uniqueIndex;size:64"` // Identitas stabil, tidak bergantung urutan seed
 	Name        string `json:"name"`
-	Description string `json:"description"` 
+	Description string `json:"description"`
 	IconURL     string `json:"icon_url"`
+
+	Trigger    string `json:"trigger" gorm:"index"`  // quiz_finished, challenge_won, challenge_played, friend_request_sent, friend_added, level_up, xp_gained, login
+	Metric     string `json:"metric"`                // count | sum | distinct | streak | max
+	DistinctBy string `json:"distinct_by,omitempty"` // Untuk metric distinct: quiz | topic | mode | day
+	Threshold  int    `json:"threshold"`
+
+	// Filter opsional
+	TopicID  *uint  `json:"topic_id,omitempty"`
+	Mode     string `json:"mode,omitempty"`      // Mode challenge (1v1, 2v2, battle_royale, ...)
+	HourFrom *int   `json:"hour_from,omitempty"` // Jam WIB (inklusif)
+	HourTo   *int   `json:"hour_to,omitempty"`   // Jam WIB (eksklusif), boleh melewati tengah malam
+	MinValue *int   `json:"min_value,omitempty"` // Nilai per event (skor, dll) minimal
+	MaxValue *int   `json:"max_value,omitempty"` // Nilai per event maksimal
+
+	IsActive  bool       `json:"is_active" gorm:"default:true"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
 
 type UserAchievement struct {
 	UserID        uint      `json:"user_id" gorm:"primaryKey"`
 	AchievementID uint      `json:"achievement_id" gorm:"primaryKey"`
 	UnlockedAt    time.Time `json:"unlocked_at"`
-}
\ No newline at end of file
+}
@@ -1,6 +1,10 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Challenge struct {
 	gorm.Model
@@ -13,11 +17,40 @@ type Challenge struct {
 	Mode         string                 `json:"mode" gorm:"default:'1v1'"`
 	TimeLimit    int                    `json:"time_limit"`
 	IsRealtime   bool                   `json:"is_realtime" gorm:"default:false"`
-	Status       string                 `json:"status" gorm:"default:'pending'"` // pending, active, finished
+	Status       string                 `json:"status" gorm:"default:'pending'"` // pending, active, finished, rejected, expired
 	Participants []ChallengeParticipant `json:"participants" gorm:"foreignKey:ChallengeID"`
 	WagerAmount  int                    `json:"wager_amount" gorm:"default:0"`
 	WinnerID     *uint                  `json:"winner_id"`    // Nullable (Pointer) karena bisa DRAW atau Team Win
 	WinningTeam  string                 `json:"winning_team"` // "A", "B", atau "DRAW" (Khusus 2v2)
+
+	// Deadline: lewat AcceptDeadline undangan yang belum dijawab kedaluwarsa,
+	// lewat CompleteDeadline peserta yang belum main dianggap kalah (forfeit)
+	AcceptDeadline   *time.Time `json:"accept_deadline"`
+	CompleteDeadline *time.Time `json:"complete_deadline"`
+
+	TournamentID    *uint  `json:"tournament_id,omitempty" gorm:"index"`  // Diisi jika challenge adalah match turnamen
+	AllowSpectators bool   `json:"allow_spectators" gorm:"default:false"` // Host mengizinkan user lain menonton lobby
+	Seed            string `json:"-"`                                     // Seed soal mode survival, dibuat & disimpan server
+
+	// Lobby terbuka: siapa pun (atau hanya teman host) bisa join lewat kode / link undangan
+	Visibility string  `json:"visibility" gorm:"default:'private'"` // private, public, friends
+	JoinCode   *string `json:"join_code,omitempty" gorm:"uniqueIndex"`
+	MaxPlayers int     `json:"max_players" gorm:"default:0"` // 0 = tidak dibatasi (lobby private)
+	InviteLink string  `json:"invite_link,omitempty" gorm:"-"`
+
+	Ghost bool `json:"ghost" gorm:"default:false"` // Async: lawan melihat replay progres (ghost) peserta sebelumnya
+
+	DisablePowerups bool `json:"disable_powerups" gorm:"default:false"` // Host mematikan power-up untuk challenge ini
+	SurvivalLives   int  `json:"survival_lives" gorm:"default:1"`       // Mode survival: nyawa tiap peserta (1-3), sama untuk semua
+
+	StartedAt   *time.Time `json:"started_at"`                 // Realtime: waktu game_start, acuan log event match
+	RematchOfID *uint      `json:"rematch_of_id" gorm:"index"` // Challenge asal jika dibuat lewat rematch
+
+	// Battle royale: aturan eliminasi per challenge
+	EliminationRule   string `json:"elimination_rule,omitempty"`           // slowest_wrong, lowest_score
+	EliminatePerRound int    `json:"eliminate_per_round" gorm:"default:0"` // Pemain tereliminasi per ronde
+	SafeRounds        int    `json:"safe_rounds" gorm:"default:0"`         // Ronde awal tanpa eliminasi
+	CurrentRound      int    `json:"current_round" gorm:"default:0"`
 }
 
 type ChallengeParticipant struct {
@@ -26,8 +59,18 @@ type ChallengeParticipant struct {
 	UserID      uint   `json:"user_id"`
 	User        User   `json:"user" gorm:"foreignKey:UserID"`
 	Team        string `json:"team" gorm:"default:'solo'"`
-	Status      string `json:"status" gorm:"default:'pending'"` // pending, accepted, rejected
+	Status      string `json:"status" gorm:"default:'pending'"` // pending, accepted, rejected, expired, kicked
 	Score       int    `json:"score" gorm:"default:-1"`         // -1 artinya belum main
 	TimeTaken   int    `json:"time_taken" gorm:"default:0"`
 	IsFinished  bool   `json:"is_finished" gorm:"default:false"`
+	Forfeited   bool   `json:"forfeited" gorm:"default:false"` // Kalah WO (timeout / disconnect)
+	IsReady     bool   `json:"is_ready" gorm:"default:false"`  // Ready check di lobby realtime
+
+	StartedAt *time.Time `json:"started_at"` // Mulai main (challenge ghost), acuan waktu timeline
+
+	// Battle royale
+	RoundScore      int  `json:"round_score" gorm:"default:0"` // Jawaban benar selama bertahan
+	Eliminated      bool `json:"eliminated" gorm:"default:false"`
+	EliminatedRound int  `json:"eliminated_round" gorm:"default:0"`
+	Placement       int  `json:"placement" gorm:"default:0"` // Peringkat akhir (1 = juara)
 }
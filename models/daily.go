@@ -29,6 +29,24 @@ type Mission struct {
 	Target      int    `json:"target"`
 	Reward      int    `json:"reward"`
 	IsActive    bool   `json:"is_active" gorm:"default:true"`
+
+	// Aturan misi (dievaluasi oleh utils.TrackMissionEvent)
+	EventType   string `json:"event_type" gorm:"index"`            // quiz_finished, login, shop_buy, xp_gained, ...
+	Filter      string `json:"filter"`                             // Contoh: score == 100 && topic == "Matematika"
+	Aggregation string `json:"aggregation" gorm:"default:'count'"` // count | sum | max
+	Weight      int    `json:"weight" gorm:"default:10"`           // Bobot peluang terpilih saat pembagian misi harian
+	Rarity      string `json:"rarity" gorm:"default:'common'"`     // common | rare | epic
+	Period      string `json:"period" gorm:"default:'daily'"`      // daily | weekly
+	PassPoints  int    `json:"pass_points" gorm:"default:0"`       // Poin season pass saat misi diklaim
+}
+
+// MissionEventLog mencatat event yang sudah diproses supaya tidak dihitung dua kali
+type MissionEventLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_mission_event"`
+	EventKey  string    `json:"event_key" gorm:"uniqueIndex:idx_mission_event;size:100"`
+	EventType string    `json:"event_type"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 
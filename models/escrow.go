@@ -0,0 +1,24 @@
+package models
+
+import "gorm.io/gorm"
+
+// WagerEscrow menahan semua taruhan satu challenge sampai challenge selesai
+type WagerEscrow struct {
+	gorm.Model
+	ChallengeID uint         `json:"challenge_id" gorm:"uniqueIndex"`
+	Status      string       `json:"status" gorm:"default:'holding'"` // holding, released, refunded
+	TotalAmount int          `json:"total_amount" gorm:"default:0"`
+	HouseFee    int          `json:"house_fee" gorm:"default:0"`
+	Stakes      []WagerStake `json:"stakes" gorm:"foreignKey:EscrowID"`
+}
+
+// WagerStake adalah taruhan satu peserta di dalam escrow
+type WagerStake struct {
+	gorm.Model
+	EscrowID    uint   `json:"escrow_id" gorm:"index"`
+	ChallengeID uint   `json:"challenge_id" gorm:"uniqueIndex:idx_wager_stake_user"`
+	UserID      uint   `json:"user_id" gorm:"uniqueIndex:idx_wager_stake_user"`
+	Amount      int    `json:"amount"`
+	Status      string `json:"status" gorm:"default:'held'"` // held, paid_out, lost, refunded
+	Payout      int    `json:"payout" gorm:"default:0"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserDailyXP mencatat XP yang sudah didapat user per sumber per hari (batas harian anti-farming)
+type UserDailyXP struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_user_daily_xp"`
+	Date      time.Time `json:"date" gorm:"type:date;uniqueIndex:idx_user_daily_xp"`
+	Source    string    `json:"source" gorm:"uniqueIndex:idx_user_daily_xp"` // quiz, survival, challenge
+	Amount    int       `json:"amount" gorm:"default:0"`
+	Capped    int       `json:"capped" gorm:"default:0"` // XP yang terpotong batas harian
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FarmingFlag adalah hasil mencurigakan yang masuk antrean review admin.
+// XP & progress misi dari hasil ini ditahan sampai flag di-review.
+type FarmingFlag struct {
+	gorm.Model
+	UserID          uint       `json:"user_id" gorm:"index"`
+	User            User       `json:"user" gorm:"foreignKey:UserID"`
+	HistoryID       *uint      `json:"history_id" gorm:"index"`
+	Source          string     `json:"source"`  // quiz, survival
+	Reason          string     `json:"reason"`  // too_fast, impossible_perfect
+	Details         string     `json:"details"` // Ringkasan angka yang memicu flag
+	WithheldXP      int        `json:"withheld_xp" gorm:"default:0"`
+	MissionWithheld bool       `json:"mission_withheld" gorm:"default:false"`
+	Status          string     `json:"status" gorm:"default:'pending';index"` // pending, dismissed, confirmed
+	ReviewedBy      *uint      `json:"reviewed_by"`                           // ID admin
+	ReviewedAt      *time.Time `json:"reviewed_at"`
+	Note            string     `json:"note"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "gorm.io/gorm"
+
+// ChallengeTimelineEntry adalah satu jawaban dalam timeline peserta challenge async.
+// Waktu dihitung server sejak peserta mulai main, dipakai untuk replay "ghost".
+type ChallengeTimelineEntry struct {
+	gorm.Model
+	ChallengeID   uint `json:"challenge_id" gorm:"index"`
+	ParticipantID uint `json:"participant_id" gorm:"uniqueIndex:idx_timeline_question"`
+	UserID        uint `json:"user_id"`
+	QuestionID    uint `json:"question_id" gorm:"uniqueIndex:idx_timeline_question"`
+	Position      int  `json:"position"`   // Urutan jawaban (1, 2, 3, ...)
+	ElapsedMs     int  `json:"elapsed_ms"` // Milidetik sejak peserta mulai
+	Correct       bool `json:"correct"`
+
+	Answer string `json:"answer"` // Jawaban yang dikirim (untuk ringkasan pasca-game)
+}
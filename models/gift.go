@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Gift adalah kiriman koin atau item dari satu user ke temannya
+type Gift struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	SenderID   uint      `json:"sender_id" gorm:"index"`
+	Sender     User      `json:"sender" gorm:"foreignKey:SenderID"`
+	ReceiverID uint      `json:"receiver_id" gorm:"index"`
+	Receiver   User      `json:"receiver" gorm:"foreignKey:ReceiverID"`
+	Type       string    `json:"type"`                    // coins, item
+	Amount     int       `json:"amount" gorm:"default:0"` // Jumlah koin (type coins) atau harga total item
+	ItemID     *uint     `json:"item_id"`                 // Diisi untuk type item
+	Item       *Item     `json:"item,omitempty" gorm:"foreignKey:ItemID"`
+	Quantity   int       `json:"quantity" gorm:"default:0"` // Jumlah item consumable
+	Message    string    `json:"message" gorm:"size:200"`   // Pesan opsional (sudah difilter)
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
@@ -17,4 +17,9 @@ type History struct {
 	Snapshot     datatypes.JSON `json:"snapshot"`
 	AssignmentID *uint          `json:"assignment_id,omitempty"`
 	ClassroomID  *uint          `json:"classroom_id,omitempty"`
+	XPGained     int            `json:"xp_gained" gorm:"default:0"`
+	XPBreakdown  datatypes.JSON `json:"xp_breakdown,omitempty"`       // Rincian perhitungan XP (utils.XPBreakdown)
+	Flagged      bool           `json:"flagged" gorm:"default:false"` // Masuk antrean review anti-farming
+
+	QuestionSetKey string `json:"-" gorm:"index"` // Remedial: ID soal terurut, acuan cooldown & replay decay
 }
@@ -10,11 +10,20 @@ type Item struct {
 	Type        string `json:"type"`
 	AssetURL    string `json:"asset_url"`
 	IsActive    bool   `json:"is_active" gorm:"default:true"`
+	Consumable  bool   `json:"consumable" gorm:"default:false"` // Habis pakai, dimiliki dalam jumlah (quantity)
+	MaxStack    int    `json:"max_stack" gorm:"default:0"`      // Batas quantity consumable (0 = bebas)
+	Rarity      string `json:"rarity" gorm:"default:'common'"`  // common, rare, epic, legendary
+
+	// Syarat beli: level minimal dan/atau achievement tertentu
+	MinLevel              int   `json:"min_level" gorm:"default:0"`
+	RequiredAchievementID *uint `json:"required_achievement_id"`
+	RotationOnly          bool  `json:"rotation_only" gorm:"default:false"` // Hanya dijual saat masuk rotasi harian
 }
 
 type UserItem struct {
 	UserID     uint `json:"user_id" gorm:"primaryKey"`
 	ItemID     uint `json:"item_id" gorm:"primaryKey"`
 	IsEquipped bool `json:"is_equipped" gorm:"default:false"` // Sedang dipakai atau tidak
+	Quantity   int  `json:"quantity" gorm:"default:1"`        // Sisa stok untuk item consumable
 	Item       Item `json:"item" gorm:"foreignKey:ItemID"`
 }
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// LeagueCohort adalah satu grup liga mingguan (maks ~30 user) di satu tier
+type LeagueCohort struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	WeekStart   time.Time  `json:"week_start" gorm:"index:idx_league_cohort_week"`
+	Tier        int        `json:"tier" gorm:"index:idx_league_cohort_week"` // 0 = Bronze ... 4 = Diamond
+	MemberCount int        `json:"member_count" gorm:"default:0"`
+	FinalizedAt *time.Time `json:"finalized_at"` // Diisi saat rollover mingguan
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// LeagueMember adalah keanggotaan user di cohort minggu tertentu beserta XP minggu itu
+type LeagueMember struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_league_member_week"`
+	User      User      `json:"user" gorm:"foreignKey:UserID"`
+	WeekStart time.Time `json:"week_start" gorm:"uniqueIndex:idx_league_member_week"`
+	CohortID  uint      `json:"cohort_id" gorm:"index"`
+	Tier      int       `json:"tier"`
+	WeeklyXP  int64     `json:"weekly_xp" gorm:"default:0"`
+	Rank      int       `json:"rank" gorm:"default:0"`   // Diisi saat rollover
+	Result    string    `json:"result"`                  // promoted, demoted, stayed
+	Reward    int       `json:"reward" gorm:"default:0"` // Koin hadiah peringkat
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// CoinTransaction adalah satu baris ledger koin (double-entry, append-only).
+// Setiap perpindahan koin = dua baris dengan TxnID sama: akun asal (-) dan akun tujuan (+).
+// Tidak memakai gorm.Model supaya baris tidak bisa di-soft-delete / diubah.
+type CoinTransaction struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt    time.Time `json:"created_at"`
+	TxnID        string    `json:"txn_id" gorm:"index;size:32"`
+	Account      string    `json:"account" gorm:"index"` // user:1, escrow:challenge:5, tournament:2, system:rewards, ...
+	UserID       *uint     `json:"user_id" gorm:"index"` // Diisi untuk akun user
+	Amount       int       `json:"amount"`               // Positif = masuk, negatif = keluar
+	BalanceAfter *int      `json:"balance_after"`        // Saldo user setelah entri (akun user saja)
+	Reason       string    `json:"reason" gorm:"index"`  // wager_stake, shop_purchase, daily_login, ...
+	RefType      string    `json:"ref_type"`             // challenge, tournament, item, mission, ...
+	RefID        uint      `json:"ref_id"`
+	Counterparty string    `json:"counterparty"` // Akun lawan transaksi
+}
@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// LobbyMessage adalah chat / emote di lobby realtime. Disimpan selama lobby berjalan,
+// dihapus setelah challenge selesai kecuali pesan yang dilaporkan.
+type LobbyMessage struct {
+	gorm.Model
+	ChallengeID uint   `json:"challenge_id" gorm:"index"`
+	UserID      uint   `json:"user_id"`
+	User        User   `json:"user" gorm:"foreignKey:UserID"`
+	Kind        string `json:"kind" gorm:"default:'chat'"` // chat, emote
+	Content     string `json:"content"`
+	Filtered    bool   `json:"filtered" gorm:"default:false"` // Ada kata yang disensor
+}
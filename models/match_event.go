@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// ChallengeMatchEvent adalah log event match realtime (untuk replay & ringkasan pasca-game)
+type ChallengeMatchEvent struct {
+	gorm.Model
+	ChallengeID uint   `json:"challenge_id" gorm:"index"`
+	UserID      uint   `json:"user_id"` // 0 untuk event match (game_start)
+	Type        string `json:"type"`    // game_start, answer, disconnect, reconnect, forfeit, finish
+	QuestionID  *uint  `json:"question_id,omitempty"`
+	Answer      string `json:"answer,omitempty"`
+	Correct     *bool  `json:"correct,omitempty"`
+	Score       *int   `json:"score,omitempty"`
+	ElapsedMs   int    `json:"elapsed_ms"` // Milidetik sejak game_start
+}
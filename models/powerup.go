@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// QuizAttempt adalah sesi pengerjaan kuis di server, acuan validasi power-up saat history dikirim
+type QuizAttempt struct {
+	gorm.Model
+	UserID          uint   `json:"user_id" gorm:"index"`
+	QuizID          uint   `json:"quiz_id" gorm:"index"`
+	ChallengeID     *uint  `json:"challenge_id"`
+	AssignmentID    *uint  `json:"assignment_id"`
+	PowerupsAllowed bool   `json:"powerups_allowed"`
+	TimeLimit       int    `json:"time_limit" gorm:"default:0"`    // Detik, diambil dari challenge saat attempt dibuka; 0 = tanpa batas
+	BonusSeconds    int    `json:"bonus_seconds" gorm:"default:0"` // Tambahan waktu dari power-up +15 detik
+	Status          string `json:"status" gorm:"default:'active'"` // active, submitted, expired
+	HistoryID       *uint  `json:"history_id"`
+
+	// Remedial (QuizID 0): soal dipilih server, client tidak bisa menentukan sendiri
+	QuestionIDs    pq.Int64Array `json:"question_ids,omitempty" gorm:"type:bigint[]"`
+	QuestionSetKey string        `json:"-"`
+}
+
+// PowerUpUse mencatat pemakaian power-up. Satu tipe hanya bisa dipakai sekali per soal (atau sekali per run survival).
+type PowerUpUse struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	UserID     uint           `json:"user_id" gorm:"index"`
+	AttemptID  uint           `json:"attempt_id" gorm:"uniqueIndex:idx_powerup_use;default:0"`
+	RunID      uint           `json:"run_id" gorm:"uniqueIndex:idx_powerup_use;default:0"`
+	QuestionID uint           `json:"question_id" gorm:"uniqueIndex:idx_powerup_use;default:0"`
+	Type       string         `json:"type" gorm:"uniqueIndex:idx_powerup_use"`
+	Removed    pq.StringArray `json:"removed,omitempty" gorm:"type:text[]"` // Opsi yang dihapus oleh 50/50
+	CreatedAt  time.Time      `json:"created_at"`
+}
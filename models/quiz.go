@@ -13,5 +13,6 @@ type Quiz struct {
 	Creator     User       `json:"-" gorm:"foreignKey:CreatorID"`
 	IsPublic    bool       `json:"is_public" gorm:"default:false"` // Muncul di pencarian?
 	Status      string     `json:"status" gorm:"default:'draft'"` // draft, published, archived
+	ExamMode    bool       `json:"exam_mode" gorm:"default:false"` // Mode ujian: power-up tidak boleh dipakai
 	Questions   []Question `json:"-" gorm:"foreignKey:QuizID"`
 }
@@ -6,8 +6,8 @@ type Report struct {
 	gorm.Model
 	ReporterID uint   `json:"reporter_id"`
 	Reporter   User   `json:"reporter" gorm:"foreignKey:ReporterID"`
-	TargetID   uint   `json:"target_id"`   // ID of the question, user, or quiz being reported
-	TargetType string `json:"target_type"` // "question", "user", "quiz"
+	TargetID   uint   `json:"target_id"`   // ID of the question, user, quiz, or lobby chat message being reported
+	TargetType string `json:"target_type"` // "question", "user", "quiz", "chat_message"
 	Reason     string `json:"reason"`
 	Status     string `json:"status" gorm:"default:'pending'"` // "pending", "resolved", "dismissed"
 }
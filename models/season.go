@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Season adalah periode battle pass. Poin pass didapat dari misi & kuis selama season aktif.
+type Season struct {
+	gorm.Model
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	StartAt      time.Time `json:"start_at"`
+	EndAt        time.Time `json:"end_at"`
+	Status       string    `json:"status" gorm:"default:'upcoming';index"` // upcoming, active, archived
+	PremiumPrice int       `json:"premium_price" gorm:"default:0"`         // Harga koin untuk membuka track premium
+
+	Tiers []SeasonTier `json:"tiers,omitempty" gorm:"foreignKey:SeasonID"`
+}
+
+// SeasonTier adalah satu hadiah di track free atau premium
+type SeasonTier struct {
+	gorm.Model
+	SeasonID       uint   `json:"season_id" gorm:"uniqueIndex:idx_season_tier_track"`
+	Tier           int    `json:"tier" gorm:"uniqueIndex:idx_season_tier_track"`
+	Track          string `json:"track" gorm:"uniqueIndex:idx_season_tier_track;default:'free'"` // free, premium
+	PointsRequired int    `json:"points_required"`
+	RewardType     string `json:"reward_type"` // coins, item, title
+	RewardCoins    int    `json:"reward_coins" gorm:"default:0"`
+	ItemID         *uint  `json:"item_id"`
+	Item           *Item  `json:"item,omitempty" gorm:"foreignKey:ItemID"`
+	Title          string `json:"title"`
+}
+
+// UserSeasonPass adalah progress user di satu season. Diarsipkan saat season berakhir.
+type UserSeasonPass struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_user_season"`
+	SeasonID  uint      `json:"season_id" gorm:"uniqueIndex:idx_user_season"`
+	Season    Season    `json:"season,omitempty" gorm:"foreignKey:SeasonID"`
+	Points    int       `json:"points" gorm:"default:0"`
+	Premium   bool      `json:"premium" gorm:"default:false"`
+	FinalTier int       `json:"final_tier" gorm:"default:0"` // Diisi saat diarsipkan
+	Archived  bool      `json:"archived" gorm:"default:false"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserSeasonClaim mencatat tier yang sudah diklaim (satu kali per tier)
+type UserSeasonClaim struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"uniqueIndex:idx_user_season_claim"`
+	SeasonTierID uint      `json:"season_tier_id" gorm:"uniqueIndex:idx_user_season_claim"`
+	SeasonID     uint      `json:"season_id" gorm:"index"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UserTitle adalah gelar yang dimiliki user (hadiah season, dll)
+type UserTitle struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_user_title"`
+	Title     string    `json:"title" gorm:"uniqueIndex:idx_user_title"`
+	SeasonID  *uint     `json:"season_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// ShopOffer adalah diskon berbatas waktu untuk satu item
+type ShopOffer struct {
+	gorm.Model
+	ItemID          uint      `json:"item_id" gorm:"index"`
+	Item            Item      `json:"item" gorm:"foreignKey:ItemID"`
+	DiscountPercent int       `json:"discount_percent"` // 1-90
+	StartAt         time.Time `json:"start_at"`
+	EndAt           time.Time `json:"end_at" gorm:"index"`
+}
+
+// ShopBundle adalah paket beberapa item dengan harga gabungan
+type ShopBundle struct {
+	gorm.Model
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Price       int              `json:"price"`
+	AssetURL    string           `json:"asset_url"`
+	Rarity      string           `json:"rarity" gorm:"default:'common'"`
+	IsActive    bool             `json:"is_active"` // Tanpa default: nilai false dari admin harus tersimpan apa adanya
+	StartAt     *time.Time       `json:"start_at"`  // Null = tanpa jadwal
+	EndAt       *time.Time       `json:"end_at"`
+	Items       []ShopBundleItem `json:"items" gorm:"foreignKey:BundleID"`
+}
+
+// ShopBundleItem adalah isi bundle
+type ShopBundleItem struct {
+	ID       uint `json:"id" gorm:"primaryKey"`
+	BundleID uint `json:"bundle_id" gorm:"uniqueIndex:idx_bundle_item"`
+	ItemID   uint `json:"item_id" gorm:"uniqueIndex:idx_bundle_item"`
+	Item     Item `json:"item" gorm:"foreignKey:ItemID"`
+	Quantity int  `json:"quantity" gorm:"default:1"` // Hanya berlaku untuk consumable
+}
+
+// ShopRotation adalah daftar item unggulan (featured) toko untuk satu hari, sama untuk semua user
+type ShopRotation struct {
+	ID        uint          `json:"id" gorm:"primaryKey"`
+	Date      time.Time     `json:"date" gorm:"type:date;uniqueIndex"`
+	ItemIDs   pq.Int64Array `json:"item_ids" gorm:"type:bigint[]"`
+	CreatedAt time.Time     `json:"created_at"`
+}
@@ -10,6 +10,7 @@ type StreakLog struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	UserID    uint      `json:"user_id" gorm:"index"`
 	Date      time.Time `json:"date" gorm:"type:date;index"` 
+	Type      string    `json:"type" gorm:"default:'active'"` // active, frozen (Streak Freeze), repaired (Streak Repair)
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+type SurvivalRun struct {
+	gorm.Model
+	UserID            uint       `json:"user_id" gorm:"index"`
+	User              User       `json:"user" gorm:"foreignKey:UserID"`
+	Mode              string     `json:"mode" gorm:"default:'classic'"` // classic, daily
+	Seed              string     `json:"-"`                             // Dibuat server; kosong = acak murni
+	TopicID           *uint      `json:"topic_id" gorm:"index"`         // Diisi jika soal hanya dari satu topik
+	DailyDate         *time.Time `json:"daily_date" gorm:"type:date;index"`
+	Unranked          bool       `json:"unranked" gorm:"default:false"`  // Percobaan daily kedua dst tidak masuk leaderboard
+	ChallengeID       *uint      `json:"challenge_id" gorm:"index"`      // Diisi jika run bagian dari challenge survival
+	Status            string     `json:"status" gorm:"default:'active'"` // active, finished, abandoned
+	Score             int        `json:"score" gorm:"default:0"`         // Total jawaban benar
+	Streak            int        `json:"streak" gorm:"default:0"`
+	BestStreak        int        `json:"best_streak" gorm:"default:0"`
+	Answered          int        `json:"answered" gorm:"default:0"`
+	Lives             int        `json:"lives" gorm:"default:1"`
+	MaxLives          int        `json:"max_lives" gorm:"default:1"`
+	Shields           int        `json:"shields" gorm:"default:0"`
+	Difficulty        int        `json:"difficulty" gorm:"default:1"` // 1 mudah, 2 sedang, 3 sulit
+	CurrentQuestionID *uint      `json:"-"`
+	HistoryID         *uint      `json:"history_id"`
+	FinishedAt        *time.Time `json:"finished_at"`
+
+	// Run ber-seed: urutan soal dihitung sekali saat run dibuat, tiap jawaban hanya memajukan cursor
+	QuestionOrder pq.Int64Array `json:"-" gorm:"type:bigint[]"`
+	OrderCursor   int           `json:"-" gorm:"default:0"`
+}
+
+// SurvivalSeenQuestion mencatat soal yang sudah muncul di satu run supaya tidak berulang
+type SurvivalSeenQuestion struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	RunID      uint      `json:"run_id" gorm:"uniqueIndex:idx_survival_seen"`
+	QuestionID uint      `json:"question_id" gorm:"uniqueIndex:idx_survival_seen"`
+	Answer     string    `json:"answer"`
+	Correct    *bool     `json:"correct"` // Null = belum dijawab
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SurvivalDailySeed adalah seed global Daily Survival, sama untuk semua user di hari yang sama
+type SurvivalDailySeed struct {
+	gorm.Model
+	Date time.Time `json:"date" gorm:"type:date;uniqueIndex"`
+	Seed string    `json:"-"`
+
+	QuestionOrder pq.Int64Array `json:"-" gorm:"type:bigint[]"` // Urutan soal hari ini, dipakai semua run daily
+}
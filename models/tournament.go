@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Tournament struct {
+	gorm.Model
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	Format       string     `json:"format" gorm:"default:'single_elimination'"` // single_elimination, double_elimination, swiss
+	SeedBy       string     `json:"seed_by" gorm:"default:'level'"`             // level, rating
+	QuizID       *uint      `json:"quiz_id" gorm:"default:null"`
+	Quiz         Quiz       `json:"quiz" gorm:"foreignKey:QuizID"`
+	TimeLimit    int        `json:"time_limit"`
+	MaxPlayers   int        `json:"max_players" gorm:"default:32"`
+	SwissRounds  int        `json:"swiss_rounds" gorm:"default:0"` // 0 = otomatis (log2 jumlah pemain)
+	RoundHours   int        `json:"round_hours" gorm:"default:24"` // Deadline tiap ronde
+	EntryFee     int        `json:"entry_fee" gorm:"default:0"`
+	PrizePool    int        `json:"prize_pool" gorm:"default:0"`           // Hadiah dasar + total entry fee
+	PrizeSplit   string     `json:"prize_split" gorm:"default:'50,30,20'"` // Persentase hadiah juara 1,2,3,...
+	Status       string     `json:"status" gorm:"default:'registration'"`  // registration, running, finished, cancelled
+	CurrentRound int        `json:"current_round" gorm:"default:0"`
+	StartAt      *time.Time `json:"start_at"`
+	AdminID      uint       `json:"admin_id"`
+
+	Entries []TournamentEntry `json:"entries,omitempty" gorm:"foreignKey:TournamentID"`
+	Matches []TournamentMatch `json:"matches,omitempty" gorm:"foreignKey:TournamentID"`
+}
+
+type TournamentEntry struct {
+	gorm.Model
+	TournamentID    uint `json:"tournament_id" gorm:"uniqueIndex:idx_tournament_entry_user"`
+	UserID          uint `json:"user_id" gorm:"uniqueIndex:idx_tournament_entry_user"`
+	User            User `json:"user" gorm:"foreignKey:UserID"`
+	Seed            int  `json:"seed" gorm:"default:0"`
+	Wins            int  `json:"wins" gorm:"default:0"`
+	Losses          int  `json:"losses" gorm:"default:0"`
+	Draws           int  `json:"draws" gorm:"default:0"`
+	Points          int  `json:"points" gorm:"default:0"` // Swiss: menang 2, seri 1
+	Byes            int  `json:"byes" gorm:"default:0"`
+	Eliminated      bool `json:"eliminated" gorm:"default:false"`
+	EliminatedRound int  `json:"eliminated_round" gorm:"default:0"`
+	FinalRank       int  `json:"final_rank" gorm:"default:0"`
+	Prize           int  `json:"prize" gorm:"default:0"`
+}
+
+type TournamentMatch struct {
+	gorm.Model
+	TournamentID uint       `json:"tournament_id" gorm:"index"`
+	Round        int        `json:"round"`
+	Bracket      string     `json:"bracket"` // winners, losers, final, swiss
+	Position     int        `json:"position"`
+	PlayerAID    *uint      `json:"player_a_id"`
+	PlayerA      *User      `json:"player_a,omitempty" gorm:"foreignKey:PlayerAID"`
+	PlayerBID    *uint      `json:"player_b_id"` // Null = bye
+	PlayerB      *User      `json:"player_b,omitempty" gorm:"foreignKey:PlayerBID"`
+	ChallengeID  *uint      `json:"challenge_id" gorm:"index"`
+	WinnerID     *uint      `json:"winner_id"`
+	Status       string     `json:"status" gorm:"default:'active'"` // active, finished, bye
+	Deadline     *time.Time `json:"deadline"`
+}
@@ -22,7 +22,10 @@ type User struct {
 	LoginStreak            int        `json:"login_streak" gorm:"default:0"`
 	LastClaimDate          *time.Time `json:"last_claim_date"`
 	LastActivityDate       *time.Time `json:"last_activity_date"`
+	LostStreak             int        `json:"lost_streak" gorm:"default:0"` // Streak sebelum putus, bisa dipulihkan dengan Streak Repair
+	StreakLostOn           *time.Time `json:"streak_lost_on"`               // Hari pertama yang terlewat
 	IsBanned               bool       `json:"is_banned" gorm:"default:false"`
+	Title                  string     `json:"title"` // Gelar yang sedang dipakai (dari UserTitle)
 	UserItems              []UserItem `json:"equipped_items" gorm:"foreignKey:UserID"`
 }
 
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// XPEvent adalah event XP berjadwal, misal "Double XP Weekend" atau "2x XP Matematika"
+type XPEvent struct {
+	gorm.Model
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Multiplier  float64   `json:"multiplier" gorm:"default:2"`
+	TopicID     *uint     `json:"topic_id"` // Null = berlaku untuk semua topik
+	Topic       *Topic    `json:"topic,omitempty" gorm:"foreignKey:TopicID"`
+	StartAt     time.Time `json:"start_at" gorm:"index"`
+	EndAt       time.Time `json:"end_at" gorm:"index"`
+}
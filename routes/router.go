@@ -5,6 +5,7 @@ import (
 	"github.com/ROFL1ST/quizzes-backend/middleware"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
 )
 
 func SetupRoutes(app *fiber.App) {
@@ -38,6 +39,20 @@ func SetupRoutes(app *fiber.App) {
 	configGroup := adminGroup.Group("/config", middleware.AllowRoles("supervisor"))
 	configGroup.Get("/leveling", controllers.GetLevelingConfig)
 	configGroup.Put("/leveling", controllers.UpdateLevelingConfig)
+	configGroup.Get("/wager-fee", controllers.GetWagerFeeConfig)
+	configGroup.Put("/wager-fee", controllers.UpdateWagerFeeConfig)
+	configGroup.Get("/xp-formula", controllers.GetXPFormulaConfig)
+	configGroup.Put("/xp-formula", controllers.UpdateXPFormulaConfig)
+	configGroup.Get("/anti-farming", controllers.GetAntiFarmingConfig)
+	configGroup.Put("/anti-farming", controllers.UpdateAntiFarmingConfig)
+
+	// xp events
+	xpEventAdmin := adminGroup.Group("/xp-events", middleware.AllowRoles("supervisor", "admin"))
+	xpEventAdmin.Get("/", controllers.GetXPEventsAdmin)
+	xpEventAdmin.Post("/", controllers.CreateXPEvent)
+	xpEventAdmin.Put("/:id", controllers.UpdateXPEvent)
+	xpEventAdmin.Delete("/:id", controllers.DeleteXPEvent)
+
 	// topic admin routes
 	topicAdmin := adminGroup.Group("/topics", middleware.AllowRoles("supervisor", "admin"))
 	topicAdmin.Get("/", controllers.GetAllTopicsAdmin)
@@ -82,12 +97,25 @@ func SetupRoutes(app *fiber.App) {
 	shopAdmin.Post("/items", controllers.CreateShopItem)
 	shopAdmin.Put("/items/:id", controllers.UpdateShopItem)
 	shopAdmin.Delete("/items/:id", controllers.DeleteShopItem)
+	shopAdmin.Get("/offers", controllers.GetShopOffersAdmin)
+	shopAdmin.Post("/offers", controllers.CreateShopOffer)
+	shopAdmin.Delete("/offers/:id", controllers.DeleteShopOffer)
+	shopAdmin.Get("/bundles", controllers.GetShopBundlesAdmin)
+	shopAdmin.Post("/bundles", controllers.CreateShopBundle)
+	shopAdmin.Put("/bundles/:id", controllers.UpdateShopBundle)
+	shopAdmin.Delete("/bundles/:id", controllers.DeleteShopBundle)
+	shopAdmin.Post("/rotation", controllers.RegenerateShopRotation)
 
 	// Report Admin Routes
 	reportAdmin := adminGroup.Group("/reports", middleware.AllowRoles("supervisor", "admin"))
 	reportAdmin.Get("/", controllers.GetAllReports)
 	reportAdmin.Put("/:id", controllers.ResolveReport)
 
+	// anti-farming review queue
+	farmingAdmin := adminGroup.Group("/farming-flags", middleware.AllowRoles("supervisor", "admin"))
+	farmingAdmin.Get("/", controllers.GetFarmingFlags)
+	farmingAdmin.Put("/:id", controllers.ReviewFarmingFlag)
+
 	// Review Admin Routes
 	reviewAdmin := adminGroup.Group("/reviews", middleware.AllowRoles("supervisor", "admin"))
 	reviewAdmin.Get("/", controllers.GetAllReviews)
@@ -100,6 +128,42 @@ func SetupRoutes(app *fiber.App) {
 	// Broadcast Route
 	adminGroup.Post("/broadcast", controllers.Broadcast)
 
+	// Tournament Admin Routes
+	tournamentAdmin := adminGroup.Group("/tournaments", middleware.AllowRoles("supervisor", "admin"))
+	tournamentAdmin.Post("/", controllers.CreateTournament)
+	tournamentAdmin.Post("/:id/start", controllers.StartTournamentAdmin)
+	tournamentAdmin.Post("/:id/cancel", controllers.CancelTournamentAdmin)
+
+	// Achievement Admin Routes
+	achievementAdmin := adminGroup.Group("/achievements", middleware.AllowRoles("supervisor", "admin"))
+	achievementAdmin.Get("/", controllers.GetAllAchievementsAdmin)
+	achievementAdmin.Post("/", controllers.CreateAchievement)
+	achievementAdmin.Put("/:id", controllers.UpdateAchievement)
+	achievementAdmin.Delete("/:id", controllers.RetireAchievement)
+	achievementAdmin.Post("/:id/restore", controllers.RestoreAchievement)
+	achievementAdmin.Post("/:id/backfill", controllers.BackfillAchievement)
+
+	// Season Admin Routes
+	seasonAdmin := adminGroup.Group("/seasons", middleware.AllowRoles("supervisor", "admin"))
+	seasonAdmin.Get("/", controllers.GetAllSeasonsAdmin)
+	seasonAdmin.Post("/", controllers.CreateSeason)
+	seasonAdmin.Put("/tiers/:tierId", controllers.UpdateSeasonTier)
+	seasonAdmin.Delete("/tiers/:tierId", controllers.DeleteSeasonTier)
+	seasonAdmin.Put("/:id", controllers.UpdateSeason)
+	seasonAdmin.Post("/:id/archive", controllers.ArchiveSeasonAdmin)
+	seasonAdmin.Post("/:id/tiers", controllers.CreateSeasonTier)
+
+	// Mission Admin Routes
+	missionAdmin := adminGroup.Group("/missions", middleware.AllowRoles("supervisor", "admin"))
+	missionAdmin.Get("/", controllers.GetAllMissionsAdmin)
+	missionAdmin.Post("/", controllers.CreateMission)
+	missionAdmin.Put("/:id", controllers.UpdateMission)
+	missionAdmin.Delete("/:id", controllers.DeleteMission)
+
+	// Wallet Admin Routes
+	walletAdmin := adminGroup.Group("/wallet", middleware.AllowRoles("supervisor", "admin"))
+	walletAdmin.Get("/reconciliation", controllers.GetLedgerReconciliation)
+
 	// Classroom Admin Routes
 	classroomAdmin := adminGroup.Group("/classrooms", middleware.AllowRoles("supervisor", "admin", "pengajar"))
 	classroomAdmin.Get("/", controllers.GetAllClassrooms)
@@ -115,6 +179,8 @@ func SetupRoutes(app *fiber.App) {
 	// User Routes
 	api.Get("/topics/:slug/quizzes", middleware.Protected(), controllers.GetQuizzesByTopicSlug)
 	api.Get("/quizzes/:id/questions", middleware.Protected(), controllers.GetQuestionsByQuizID)
+	api.Post("/quizzes/:id/attempts", middleware.Protected(), controllers.StartQuizAttempt)
+	api.Post("/attempts/:id/powerups", middleware.Protected(), controllers.UseQuizPowerUp)
 
 	history := api.Group("/history", middleware.Protected())
 	history.Post("/", controllers.SaveHistory)
@@ -142,10 +208,41 @@ func SetupRoutes(app *fiber.App) {
 	challenges.Get("/", controllers.GetMyChallenges)
 	challenges.Post("/:id/accept", controllers.AcceptChallenge)
 	challenges.Post("/:id/refuse", controllers.RejectChallenge)
-	challenges.Get("/:id/lobby-stream", controllers.StreamChallengeLobby)
+	challenges.Post("/:id/cancel", controllers.CancelChallenge)
+	challenges.Put("/:id/spectators", controllers.UpdateSpectatorSetting)
+	challenges.Get("/:id/lobby-stream", controllers.StreamChallengeLobby) // SSE (client lama)
+	challenges.Get("/:id/lobby", controllers.UpgradeLobbySocket, websocket.New(controllers.ChallengeLobbySocket))
 	challenges.Post("/:id/start", controllers.StartGameRealtime)
 	challenges.Post("/:id/progress", controllers.UpdateChallengeProgress)
 	challenges.Post("/:id/leave", controllers.LeaveLobby)
+	challenges.Post("/:id/ready", controllers.SetLobbyReady)
+	challenges.Post("/:id/kick", controllers.KickLobbyPlayer)
+	challenges.Put("/:id/settings", controllers.UpdateLobbySettings)
+	challenges.Get("/:id/chat", controllers.GetLobbyChat)
+	challenges.Post("/:id/chat", controllers.SendLobbyChat)
+	challenges.Post("/:id/emote", controllers.SendLobbyEmote)
+	challenges.Post("/:id/ghost/start", controllers.StartGhostRace)
+	challenges.Post("/:id/ghost/answer", controllers.AnswerGhostRace)
+	challenges.Get("/:id/ghost/replay", controllers.StreamGhostReplay) // SSE
+	challenges.Get("/:id/ghost", controllers.GetGhostTimelines)
+	challenges.Get("/:id/summary", controllers.GetChallengeSummary)
+	challenges.Get("/:id/replay", controllers.GetChallengeReplay)
+	challenges.Post("/:id/rematch", controllers.RematchChallenge)
+
+	// Lobby terbuka (join lewat kode / link undangan)
+	lobbies := api.Group("/lobbies", middleware.Protected())
+	lobbies.Get("/", controllers.GetOpenLobbies)
+	lobbies.Get("/:code", controllers.GetLobbyByCode)
+	lobbies.Post("/:code/join", controllers.JoinLobby)
+
+	// Tournament Routes
+	tournaments := api.Group("/tournaments", middleware.Protected())
+	tournaments.Get("/", controllers.GetTournaments)
+	tournaments.Get("/:id", controllers.GetTournamentDetail)
+	tournaments.Post("/:id/join", controllers.JoinTournament)
+	tournaments.Delete("/:id/join", controllers.LeaveTournament)
+	tournaments.Get("/:id/bracket", controllers.GetTournamentBracket)
+	tournaments.Get("/:id/standings", controllers.GetTournamentStandings)
 
 	// Activity Feed
 	api.Get("/feed", middleware.Protected(), controllers.GetFriendActivity)
@@ -157,17 +254,46 @@ func SetupRoutes(app *fiber.App) {
 	userGroup.Get("/achievements", controllers.GetMyAchievements)
 	userGroup.Put("/me", controllers.UpdateProfile) // Ganti nama/password
 	userGroup.Get("/:username", controllers.GetUserProfile)
+	userGroup.Get("/:username/head-to-head", controllers.GetHeadToHead)
 	userGroup.Post("/share", controllers.ShareProfileTrigger)
 	userGroup.Get("/analytics/smart", controllers.GetUserSmartAnalytics)
 	userGroup.Get("/activity/calendar", controllers.GetActivityCalendar)
+	userGroup.Get("/activity/calendar/details", controllers.GetActivityCalendarDetails)
 
 	// Shop Routes
 	shopGroup := api.Group("/shop", middleware.Protected())
 	shopGroup.Get("/items", controllers.GetShopItems)
+	shopGroup.Get("/featured", controllers.GetFeaturedShop)
+	shopGroup.Get("/bundles", controllers.GetShopBundles)
 	shopGroup.Post("/buy", controllers.BuyItem)
 	shopGroup.Get("/inventory", controllers.GetMyInventory)
 	shopGroup.Post("/equip", controllers.EquipItem)
 
+	// Gift Routes
+	giftGroup := api.Group("/gifts", middleware.Protected())
+	giftGroup.Get("/", controllers.GetMyGifts)
+	giftGroup.Post("/", controllers.SendGift)
+	giftGroup.Get("/limits", controllers.GetGiftLimits)
+
+	// Streak Routes
+	streakGroup := api.Group("/streak", middleware.Protected())
+	streakGroup.Get("/", controllers.GetStreakStatus)
+	streakGroup.Post("/repair", controllers.RepairStreak)
+
+	// Season Pass Routes
+	seasons := api.Group("/seasons", middleware.Protected())
+	seasons.Get("/current", controllers.GetCurrentSeason)
+	seasons.Post("/current/premium", controllers.BuySeasonPremium)
+	seasons.Post("/tiers/:tierId/claim", controllers.ClaimSeasonTier)
+	seasons.Get("/history", controllers.GetSeasonHistory)
+	seasons.Get("/titles", controllers.GetMyTitles)
+	seasons.Put("/titles/equip", controllers.EquipTitle)
+
+	// Wallet Routes
+	wallet := api.Group("/wallet", middleware.Protected())
+	wallet.Get("/", controllers.GetWallet)
+	wallet.Get("/transactions", controllers.GetWalletTransactions)
+
 	// daily routes
 	daily := api.Group("/daily", middleware.Protected())
 	daily.Get("/info", controllers.GetDailyInfo)
@@ -181,10 +307,19 @@ func SetupRoutes(app *fiber.App) {
 	comunityGroup.Get("/quizzes/me", controllers.GetMyCommunityQuizzes)
 
 	api.Get("/quizzes/remedial/start", middleware.Protected(), controllers.GetRemedialQuestions)
+	api.Post("/quizzes/remedial/start", middleware.Protected(), controllers.StartRemedialAttempt)
 
 	// Global Leaderboard
 	api.Get("/global/leaderboard", middleware.Protected(), controllers.GetGlobalLeaderboard)
 
+	// Weekly League Routes
+	leagues := api.Group("/leagues", middleware.Protected())
+	leagues.Get("/current", controllers.GetCurrentLeague)
+	leagues.Get("/history", controllers.GetLeagueHistory)
+
+	// XP Events (aktif & akan datang)
+	api.Get("/xp-events", middleware.Protected(), controllers.GetXPEvents)
+
 	// Report Routes (User)
 	api.Post("/reports", middleware.Protected(), controllers.CreateReport)
 
@@ -203,5 +338,9 @@ func SetupRoutes(app *fiber.App) {
 	// Survival Mode
 	api.Post("/survival/start", middleware.Protected(), controllers.StartSurvival)
 	api.Post("/survival/answer", middleware.Protected(), controllers.AnswerSurvival)
+	api.Post("/survival/powerup", middleware.Protected(), controllers.UseSurvivalPowerUp)
+	api.Get("/survival/active", middleware.Protected(), controllers.GetActiveSurvival)
+	api.Get("/survival/leaderboard", middleware.Protected(), controllers.GetSurvivalLeaderboard)
+	api.Get("/survival/daily", middleware.Protected(), controllers.GetDailySurvival)
 
 }
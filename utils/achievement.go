@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+)
+
+// Event domain yang memicu evaluasi achievement
+const (
+	AchievementQuizFinished      = "quiz_finished"
+	AchievementChallengeWon      = "challenge_won"
+	AchievementChallengePlayed   = "challenge_played"
+	AchievementFriendRequestSent = "friend_request_sent"
+	AchievementFriendAdded       = "friend_added"
+	AchievementLevelUp           = "level_up"
+	AchievementXPGained          = "xp_gained"
+	AchievementLogin             = "login"
+)
+
+var (
+	achievementMetrics    = map[string]bool{"count": true, "sum": true, "distinct": true, "streak": true, "max": true}
+	achievementDistinctBy = map[string]string{
+		"quiz":  "ev.quiz_id",
+		"topic": "ev.topic_id",
+		"mode":  "ev.mode",
+		"day":   "DATE(ev.occurred_at AT TIME ZONE 'Asia/Jakarta')",
+	}
+)
+
+// achievementSources mengubah tiap trigger menjadi daftar event user dengan kolom seragam:
+// value, occurred_at, quiz_id, topic_id, mode. Dihitung dari tabel domain sehingga
+// achievement baru bisa di-backfill untuk user lama.
+var achievementSources = map[string]func(userID uint) *gorm.DB{
+	AchievementQuizFinished: func(userID uint) *gorm.DB {
+		return config.DB.Table("histories h").
+			Select("h.score AS value, h.created_at AS occurred_at, h.quiz_id, q.topic_id, '' AS mode").
+			Joins("LEFT JOIN quizzes q ON q.id = h.quiz_id").
+			Where("h.user_id = ? AND h.deleted_at IS NULL", userID)
+	},
+	AchievementChallengePlayed: func(userID uint) *gorm.DB {
+		return challengeAchievementSource(userID)
+	},
+	AchievementChallengeWon: func(userID uint) *gorm.DB {
+		return challengeAchievementSource(userID).
+			Where("c.winner_id = p.user_id OR (c.mode = ? AND c.winning_team <> '' AND c.winning_team = p.team)", "2v2")
+	},
+	AchievementFriendRequestSent: func(userID uint) *gorm.DB {
+		return config.DB.Table("friendships f").
+			Select("1 AS value, f.created_at AS occurred_at, NULL::bigint AS quiz_id, NULL::bigint AS topic_id, '' AS mode").
+			Where("f.user_id = ? AND f.deleted_at IS NULL", userID)
+	},
+	AchievementFriendAdded: func(userID uint) *gorm.DB {
+		return config.DB.Table("friendships f").
+			Select("1 AS value, f.updated_at AS occurred_at, NULL::bigint AS quiz_id, NULL::bigint AS topic_id, '' AS mode").
+			Where("(f.user_id = ? OR f.friend_id = ?) AND f.status = ? AND f.deleted_at IS NULL", userID, userID, "accepted")
+	},
+	AchievementLevelUp: func(userID uint) *gorm.DB {
+		return userAchievementSource(userID, "u.level")
+	},
+	AchievementXPGained: func(userID uint) *gorm.DB {
+		return userAchievementSource(userID, "u.xp")
+	},
+	AchievementLogin: func(userID uint) *gorm.DB {
+		// value = umur akun dalam hari
+		return userAchievementSource(userID, "EXTRACT(DAY FROM NOW() - u.created_at)::int")
+	},
+}
+
+func challengeAchievementSource(userID uint) *gorm.DB {
+	return config.DB.Table("challenge_participants p").
+		Select("p.score AS value, c.updated_at AS occurred_at, c.quiz_id, q.topic_id, c.mode").
+		Joins("JOIN challenges c ON c.id = p.challenge_id").
+		Joins("LEFT JOIN quizzes q ON q.id = c.quiz_id").
+		Where("p.user_id = ? AND p.status = ? AND c.status = ? AND c.deleted_at IS NULL", userID, "accepted", "finished")
+}
+
+func userAchievementSource(userID uint, valueExpr string) *gorm.DB {
+	return config.DB.Table("users u").
+		Select(valueExpr+" AS value, NOW() AS occurred_at, NULL::bigint AS quiz_id, NULL::bigint AS topic_id, '' AS mode").
+		Where("u.id = ?", userID)
+}
+
+// ValidateAchievementRule mengecek aturan achievement sebelum disimpan
+func ValidateAchievementRule(ach models.Achievement) error {
+	if ach.Key == "" || ach.Name == "" {
+		return errors.New("key and name are required")
+	}
+	if _, ok := achievementSources[ach.Trigger]; !ok {
+		return fmt.Errorf("unknown trigger %q", ach.Trigger)
+	}
+	if !achievementMetrics[ach.Metric] {
+		return fmt.Errorf("unknown metric %q", ach.Metric)
+	}
+	if ach.Metric == "distinct" {
+		if _, ok := achievementDistinctBy[ach.DistinctBy]; !ok {
+			return errors.New("distinct_by must be quiz, topic, mode or day")
+		}
+	}
+	if ach.Threshold < 1 {
+		return errors.New("threshold must be at least 1")
+	}
+	for _, h := range []*int{ach.HourFrom, ach.HourTo} {
+		if h != nil && (*h < 0 || *h > 24) {
+			return errors.New("hour_from / hour_to must be between 0 and 24")
+		}
+	}
+	if (ach.HourFrom == nil) != (ach.HourTo == nil) {
+		return errors.New("hour_from and hour_to must be set together")
+	}
+	return nil
+}
+
+// AchievementProgress menghitung nilai metric achievement untuk satu user
+func AchievementProgress(ach models.Achievement, userID uint) int {
+	source, ok := achievementSources[ach.Trigger]
+	if !ok {
+		return 0
+	}
+
+	query := config.DB.Table("(?) AS ev", source(userID))
+	if ach.TopicID != nil {
+		query = query.Where("ev.topic_id = ?", *ach.TopicID)
+	}
+	if ach.Mode != "" {
+		query = query.Where("ev.mode = ?", ach.Mode)
+	}
+	if ach.MinValue != nil {
+		query = query.Where("ev.value >= ?", *ach.MinValue)
+	}
+	if ach.MaxValue != nil {
+		query = query.Where("ev.value <= ?", *ach.MaxValue)
+	}
+	if ach.HourFrom != nil && ach.HourTo != nil {
+		hour := "EXTRACT(HOUR FROM ev.occurred_at AT TIME ZONE 'Asia/Jakarta')"
+		if *ach.HourFrom <= *ach.HourTo {
+			query = query.Where(hour+" >= ? AND "+hour+" < ?", *ach.HourFrom, *ach.HourTo)
+		} else {
+			// Rentang melewati tengah malam, misal 22 - 3
+			query = query.Where(hour+" >= ? OR "+hour+" < ?", *ach.HourFrom, *ach.HourTo)
+		}
+	}
+
+	var progress int
+	switch ach.Metric {
+	case "count":
+		query.Select("COUNT(*)").Scan(&progress)
+	case "sum":
+		query.Select("COALESCE(SUM(ev.value), 0)").Scan(&progress)
+	case "max":
+		query.Select("COALESCE(MAX(ev.value), 0)").Scan(&progress)
+	case "distinct":
+		query.Select("COUNT(DISTINCT " + achievementDistinctBy[ach.DistinctBy] + ")").Scan(&progress)
+	case "streak":
+		var days []time.Time
+		query.Select("DISTINCT DATE(ev.occurred_at AT TIME ZONE 'Asia/Jakarta') AS day").Order("day").Scan(&days)
+		progress = longestDayStreak(days)
+	}
+	return progress
+}
+
+// longestDayStreak menghitung rangkaian hari berturut-turut terpanjang (days sudah urut & unik)
+func longestDayStreak(days []time.Time) int {
+	best, current := 0, 0
+	for i, d := range days {
+		if i > 0 && DaysBetween(days[i-1], d) == 1 {
+			current++
+		} else {
+			current = 1
+		}
+		if current > best {
+			best = current
+		}
+	}
+	return best
+}
+
+// FireAchievementEvent mengevaluasi semua achievement aktif untuk trigger ini
+// dan membuka yang sudah mencapai threshold.
+func FireAchievementEvent(userID uint, trigger string) {
+	if userID == 0 {
+		return
+	}
+
+	var achievements []models.Achievement
+	config.DB.Where("trigger = ? AND is_active = ?", trigger, true).
+		Where("id NOT IN (?)", config.DB.Model(&models.UserAchievement{}).Select("achievement_id").Where("user_id = ?", userID)).
+		Find(&achievements)
+
+	for _, ach := range achievements {
+		if AchievementProgress(ach, userID) >= ach.Threshold {
+			unlockAchievement(userID, ach, true)
+		}
+	}
+}
+
+// BackfillAchievement mengevaluasi achievement untuk semua user yang belum membukanya.
+// Return jumlah user yang baru membuka.
+func BackfillAchievement(ach models.Achievement, notify bool) int {
+	var userIDs []uint
+	config.DB.Model(&models.User{}).
+		Where("id NOT IN (?)", config.DB.Model(&models.UserAchievement{}).Select("user_id").Where("achievement_id = ?", ach.ID)).
+		Pluck("id", &userIDs)
+
+	unlocked := 0
+	for _, userID := range userIDs {
+		if AchievementProgress(ach, userID) >= ach.Threshold && unlockAchievement(userID, ach, notify) {
+			unlocked++
+		}
+	}
+	return unlocked
+}
@@ -0,0 +1,337 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	BattleRoyaleMode = "battle_royale"
+
+	DefaultEliminationRule = "slowest_wrong"
+	MinBattleRoyalePlayers = 3
+
+	battleRoyaleParticipationXP = 20
+)
+
+// EliminationRules: slowest_wrong = pemain salah paling lambat tereliminasi;
+// lowest_score = skor kumulatif terendah tereliminasi (seri: yang salah & paling lambat di ronde ini)
+var EliminationRules = map[string]bool{
+	"slowest_wrong": true,
+	"lowest_score":  true,
+}
+
+// Hadiah per peringkat (index 0 = juara 1), sisanya dapat XP partisipasi
+var battleRoyaleRewards = []struct {
+	XP    int
+	Coins int
+}{
+	{XP: 150, Coins: 50},
+	{XP: 100, Coins: 25},
+	{XP: 60, Coins: 10},
+}
+
+type brElimination struct {
+	UserID    uint
+	Placement int
+	Score     int
+}
+
+func aliveBattleRoyalePlayers(tx *gorm.DB, challengeID uint) []models.ChallengeParticipant {
+	var alive []models.ChallengeParticipant
+	tx.Where("challenge_id = ? AND status = ? AND forfeited = ? AND eliminated = ? AND is_finished = ?",
+		challengeID, "accepted", false, false, false).Find(&alive)
+	return alive
+}
+
+// ResolveBattleRoyaleRound dipanggil sekali saat ronde (satu soal) ditutup: menambah skor
+// pemain yang benar, mengeliminasi pemain sesuai aturan challenge, dan menutup match
+// jika tinggal satu pemain.
+func ResolveBattleRoyaleRound(challengeID uint, questionID uint, results map[uint]bool) {
+	var eliminated []brElimination
+	var survivor *brElimination
+	round := 0
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var challenge models.Challenge
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&challenge, challengeID).Error; err != nil {
+			return err
+		}
+		if challenge.Mode != BattleRoyaleMode || challenge.Status != "active" {
+			return nil
+		}
+
+		challenge.CurrentRound++
+		round = challenge.CurrentRound
+		tx.Model(&challenge).Update("current_round", challenge.CurrentRound)
+
+		alive := aliveBattleRoyalePlayers(tx, challengeID)
+		for i := range alive {
+			if results[alive[i].UserID] {
+				alive[i].RoundScore++
+				tx.Model(&models.ChallengeParticipant{}).Where("id = ?", alive[i].ID).
+					UpdateColumn("round_score", gorm.Expr("round_score + 1"))
+			}
+		}
+
+		if len(alive) > 1 && challenge.CurrentRound > challenge.SafeRounds {
+			victims := pickBattleRoyaleVictims(challenge, alive, results, roundAnswerTimes(challengeID, questionID))
+
+			placement := len(alive)
+			for _, v := range victims {
+				tx.Model(&models.ChallengeParticipant{}).Where("id = ?", v.ID).Updates(map[string]interface{}{
+					"eliminated":       true,
+					"eliminated_round": challenge.CurrentRound,
+					"is_finished":      true,
+					"is_ready":         false,
+					"score":            v.RoundScore,
+					"placement":        placement,
+				})
+				eliminated = append(eliminated, brElimination{UserID: v.UserID, Placement: placement, Score: v.RoundScore})
+				placement--
+			}
+		}
+
+		survivor = crownLastSurvivor(tx, challengeID)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	announceBattleRoyaleEliminations(challengeID, round, eliminated)
+	if survivor != nil {
+		FinishChallengeIfComplete(challengeID)
+	}
+}
+
+// crownLastSurvivor menutup run pemain terakhir yang bertahan sebagai juara
+func crownLastSurvivor(tx *gorm.DB, challengeID uint) *brElimination {
+	alive := aliveBattleRoyalePlayers(tx, challengeID)
+	if len(alive) != 1 {
+		return nil
+	}
+
+	winner := alive[0]
+	tx.Model(&models.ChallengeParticipant{}).Where("id = ?", winner.ID).Updates(map[string]interface{}{
+		"is_finished": true,
+		"score":       winner.RoundScore,
+		"placement":   1,
+	})
+	return &brElimination{UserID: winner.UserID, Placement: 1, Score: winner.RoundScore}
+}
+
+// roundAnswerTimes: waktu jawab (ms sejak game_start) tiap pemain untuk satu soal, dari log event
+func roundAnswerTimes(challengeID uint, questionID uint) map[uint]int {
+	var events []models.ChallengeMatchEvent
+	config.DB.Where("challenge_id = ? AND type = ? AND question_id = ?", challengeID, "answer", questionID).Find(&events)
+
+	times := map[uint]int{}
+	for _, e := range events {
+		times[e.UserID] = e.ElapsedMs
+	}
+	return times
+}
+
+// pickBattleRoyaleVictims memilih pemain yang tereliminasi, urut dari yang terburuk.
+// Selalu menyisakan minimal satu pemain.
+func pickBattleRoyaleVictims(challenge models.Challenge, alive []models.ChallengeParticipant, results map[uint]bool, times map[uint]int) []models.ChallengeParticipant {
+	limit := challenge.EliminatePerRound
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > len(alive)-1 {
+		limit = len(alive) - 1
+	}
+
+	slowerFirst := func(a, b models.ChallengeParticipant) bool {
+		return times[a.UserID] > times[b.UserID]
+	}
+
+	var candidates []models.ChallengeParticipant
+	switch challenge.EliminationRule {
+	case "lowest_score":
+		candidates = append(candidates, alive...)
+		sort.SliceStable(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			if a.RoundScore != b.RoundScore {
+				return a.RoundScore < b.RoundScore
+			}
+			if results[a.UserID] != results[b.UserID] {
+				return !results[a.UserID]
+			}
+			return slowerFirst(a, b)
+		})
+	default:
+		// slowest_wrong: hanya yang salah; semua benar = tidak ada eliminasi
+		for _, p := range alive {
+			if !results[p.UserID] {
+				candidates = append(candidates, p)
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool { return slowerFirst(candidates[i], candidates[j]) })
+	}
+
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+func announceBattleRoyaleEliminations(challengeID uint, round int, eliminated []brElimination) {
+	for _, e := range eliminated {
+		BroadcastLobby(challengeID, "player_eliminated", map[string]interface{}{
+			"user_id":   e.UserID,
+			"round":     round,
+			"placement": e.Placement,
+			"score":     e.Score,
+		})
+		// Pemain tereliminasi lanjut menonton dari lobby yang sama
+		SendToLobbyClient(challengeID, e.UserID, "eliminated", map[string]interface{}{
+			"round":     round,
+			"placement": e.Placement,
+			"role":      "spectator",
+		})
+	}
+
+	var remaining int64
+	config.DB.Model(&models.ChallengeParticipant{}).
+		Where("challenge_id = ? AND status = ? AND forfeited = ? AND eliminated = ?", challengeID, "accepted", false, false).
+		Count(&remaining)
+	BroadcastLobby(challengeID, "round_survivors", map[string]interface{}{
+		"round":     round,
+		"remaining": remaining,
+	})
+}
+
+// PlaceBattleRoyaleForfeit memberi peringkat ke pemain yang kalah WO di tengah battle royale
+func PlaceBattleRoyaleForfeit(challengeID uint, userID uint) {
+	config.DB.Transaction(func(tx *gorm.DB) error {
+		var challenge models.Challenge
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&challenge, challengeID).Error; err != nil {
+			return err
+		}
+		if challenge.Mode != BattleRoyaleMode || challenge.Status != "active" {
+			return nil
+		}
+
+		alive := aliveBattleRoyalePlayers(tx, challengeID)
+		tx.Model(&models.ChallengeParticipant{}).
+			Where("challenge_id = ? AND user_id = ? AND placement = ?", challengeID, userID, 0).
+			Updates(map[string]interface{}{
+				"eliminated":       true,
+				"eliminated_round": challenge.CurrentRound,
+				"placement":        len(alive) + 1,
+			})
+
+		crownLastSurvivor(tx, challengeID)
+		return nil
+	})
+}
+
+// FinalizeBattleRoyale dipanggil DetermineWinner: memberi peringkat ke pemain yang bertahan
+// sampai soal habis (skor bertahan tertinggi, lalu waktu tercepat) dan return juara.
+func FinalizeBattleRoyale(challenge *models.Challenge) *uint {
+	parts := challenge.Participants
+
+	taken := map[int]bool{}
+	var unplaced []int
+	for i, p := range parts {
+		if p.Status != "accepted" {
+			continue
+		}
+		if p.Placement > 0 {
+			taken[p.Placement] = true
+		} else {
+			unplaced = append(unplaced, i)
+		}
+	}
+
+	sort.SliceStable(unplaced, func(i, j int) bool {
+		a, b := parts[unplaced[i]], parts[unplaced[j]]
+		if a.Forfeited != b.Forfeited {
+			return !a.Forfeited
+		}
+		if a.RoundScore != b.RoundScore {
+			return a.RoundScore > b.RoundScore
+		}
+		return a.TimeTaken < b.TimeTaken
+	})
+
+	next := 1
+	for _, idx := range unplaced {
+		for taken[next] {
+			next++
+		}
+		parts[idx].Placement = next
+		taken[next] = true
+		config.DB.Model(&models.ChallengeParticipant{}).Where("id = ?", parts[idx].ID).Update("placement", next)
+	}
+
+	for _, p := range parts {
+		if p.Status == "accepted" && p.Placement == 1 && !p.Forfeited {
+			winnerID := p.UserID
+			return &winnerID
+		}
+	}
+	return nil
+}
+
+// AwardBattleRoyaleRewards memberi XP & koin sesuai peringkat akhir
+func AwardBattleRoyaleRewards(challenge models.Challenge) {
+	for _, p := range challenge.Participants {
+		if p.Status != "accepted" || p.Placement == 0 || p.Forfeited {
+			continue
+		}
+
+		xp, coins := battleRoyaleParticipationXP, 0
+		if p.Placement <= len(battleRoyaleRewards) {
+			xp = battleRoyaleRewards[p.Placement-1].XP
+			coins = battleRoyaleRewards[p.Placement-1].Coins
+		}
+
+		xp = AwardXP(p.UserID, XPSourceChallenge, xp)
+		coins = awardBattleRoyaleCoins(p.UserID, challenge.ID, coins)
+
+		msg := fmt.Sprintf("🏆 Peringkat #%d battle royale! +%d XP", p.Placement, xp)
+		if coins > 0 {
+			msg += fmt.Sprintf(", +%d koin", coins)
+		}
+		SendNotification(p.UserID, "success", "Battle Royale Selesai", msg, "/challenges")
+	}
+}
+
+// awardBattleRoyaleCoins membayar koin peringkat setelah dipotong batas harian, supaya lobby privat
+// tidak bisa dipakai mencetak koin. Return koin yang benar-benar dibayar.
+func awardBattleRoyaleCoins(userID uint, challengeID uint, coins int) int {
+	if coins <= 0 {
+		return 0
+	}
+	limit := GetAntiFarmingRules().BattleRoyaleDailyCoins
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if limit > 0 {
+			// Row user dikunci supaya dua battle royale yang selesai bersamaan tidak menembus batas
+			var user models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+				return err
+			}
+			var paid int
+			tx.Model(&models.CoinTransaction{}).Select("COALESCE(SUM(amount), 0)").
+				Where("user_id = ? AND reason = ? AND created_at >= ?", userID, "battle_royale_reward", StripTime(GetJakartaTime())).
+				Scan(&paid)
+			coins = max(0, min(coins, limit-paid))
+		}
+		return MoveCoins(tx, AccountRewards, UserAccount(userID), coins,
+			"battle_royale_reward", LedgerRef{Type: "challenge", ID: challengeID})
+	})
+	if err != nil {
+		return 0
+	}
+	return coins
+}
@@ -0,0 +1,323 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultAcceptWindow   = 24 * time.Hour // Batas waktu menerima undangan
+	DefaultCompleteWindow = 72 * time.Hour // Batas waktu menyelesaikan challenge
+	DisconnectGracePeriod = 30 * time.Second
+
+	challengeExpiryInterval = time.Minute
+)
+
+// StartChallengeExpiryJob menjalankan pengecekan deadline challenge di background
+func StartChallengeExpiryJob() {
+	go func() {
+		ticker := time.NewTicker(challengeExpiryInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ExpireChallenges()
+			PurgeEndedLobbyChats()
+		}
+	}()
+}
+
+// ExpireChallenges memproses semua challenge yang sudah lewat deadline
+func ExpireChallenges() {
+	now := time.Now()
+
+	// 1. Lewat deadline accept: undangan pending kedaluwarsa
+	var overdueInvites []models.Challenge
+	config.DB.Preload("Participants").
+		Where("status IN ? AND accept_deadline IS NOT NULL AND accept_deadline < ?", []string{"pending", "active"}, now).
+		Where("status = ? OR id IN (?)", "pending",
+			config.DB.Model(&models.ChallengeParticipant{}).Select("challenge_id").Where("status = ?", "pending")).
+		Find(&overdueInvites)
+
+	for _, ch := range overdueInvites {
+		expireChallengeInvites(ch)
+	}
+
+	// 2. Lewat deadline selesai: peserta yang belum main kalah WO
+	var overdueMatches []models.Challenge
+	config.DB.Preload("Participants").
+		Where("status = ? AND complete_deadline IS NOT NULL AND complete_deadline < ?", "active", now).
+		Find(&overdueMatches)
+
+	for _, ch := range overdueMatches {
+		for _, p := range ch.Participants {
+			if p.Status == "accepted" && !p.IsFinished {
+				ForfeitParticipant(ch.ID, p.UserID, "Waktu challenge habis")
+			}
+		}
+		FinishChallengeIfComplete(ch.ID)
+	}
+}
+
+func expireChallengeInvites(ch models.Challenge) {
+	for _, p := range ch.Participants {
+		if p.Status != "pending" {
+			continue
+		}
+		config.DB.Model(&models.ChallengeParticipant{}).Where("id = ?", p.ID).Update("status", "expired")
+		RefundWagerStake(ch.ID, p.UserID)
+		SendNotification(p.UserID, "info", "Tantangan Kedaluwarsa", "⌛ Undangan challenge sudah lewat batas waktu.", "/challenges")
+	}
+
+	// Challenge yang belum pernah dimulai dibatalkan seluruhnya
+	if ch.Status == "pending" {
+		res := config.DB.Model(&models.Challenge{}).
+			Where("id = ? AND status = ?", ch.ID, "pending").
+			Update("status", "expired")
+		if res.RowsAffected == 0 {
+			return
+		}
+
+		RefundWagerEscrow(ch.ID)
+		SendNotification(ch.CreatorID, "info", "Challenge Kedaluwarsa", "⌛ Challenge kamu dibatalkan karena tidak dimulai tepat waktu.", "/challenges")
+		BroadcastLobby(ch.ID, "challenge_expired", map[string]interface{}{"challenge_id": ch.ID})
+		return
+	}
+
+	FinishChallengeIfComplete(ch.ID)
+}
+
+// ForfeitParticipant menandai peserta kalah WO. Return false jika peserta sudah selesai.
+func ForfeitParticipant(challengeID uint, userID uint, reason string) bool {
+	res := config.DB.Model(&models.ChallengeParticipant{}).
+		Where("challenge_id = ? AND user_id = ? AND status = ? AND is_finished = ?", challengeID, userID, "accepted", false).
+		Updates(map[string]interface{}{"forfeited": true, "is_finished": true, "score": 0})
+	if res.RowsAffected == 0 {
+		return false
+	}
+
+	LogMatchEvent(challengeID, userID, "forfeit", models.ChallengeMatchEvent{})
+	PlaceBattleRoyaleForfeit(challengeID, userID)
+	BroadcastLobby(challengeID, "player_forfeit", map[string]interface{}{
+		"user_id": userID,
+		"reason":  reason,
+	})
+	// Ronde yang hanya menunggu pemain ini langsung ditutup
+	RecheckRoundAnswers(challengeID)
+	SendNotification(userID, "warning", "Kalah WO", "🏳️ Kamu dianggap kalah: "+reason, "/challenges")
+	return true
+}
+
+// SubmitChallengeScore menyimpan skor peserta lalu menutup challenge jika semua sudah selesai.
+// Peserta yang sudah kalah WO / sudah selesai tidak bisa diubah lagi.
+func SubmitChallengeScore(challengeID uint, userID uint, score int, timeTaken int) bool {
+	updates := map[string]interface{}{"score": score, "time_taken": timeTaken, "is_finished": true}
+
+	// Battle royale: skor = jawaban benar selama bertahan (dihitung server per ronde)
+	var challenge models.Challenge
+	if err := config.DB.Select("id", "mode").First(&challenge, challengeID).Error; err == nil && challenge.Mode == BattleRoyaleMode {
+		updates["score"] = gorm.Expr("round_score")
+	}
+
+	res := config.DB.Model(&models.ChallengeParticipant{}).
+		Where("challenge_id = ? AND user_id = ? AND forfeited = ? AND is_finished = ?", challengeID, userID, false, false).
+		Updates(updates)
+	if res.RowsAffected == 0 {
+		return false
+	}
+	LogMatchEvent(challengeID, userID, "finish", models.ChallengeMatchEvent{Score: &score})
+
+	FinishChallengeIfComplete(challengeID)
+	return true
+}
+
+// FinishChallengeIfComplete menutup challenge & menentukan pemenang jika semua peserta sudah selesai.
+// Peserta yang masih pending (belum jawab undangan) ikut ditunggu sampai deadline accept.
+func FinishChallengeIfComplete(challengeID uint) bool {
+	var challenge models.Challenge
+	if err := config.DB.Preload("Participants").First(&challenge, challengeID).Error; err != nil {
+		return false
+	}
+
+	for _, p := range challenge.Participants {
+		if p.Status == "pending" {
+			return false
+		}
+		if p.Status == "accepted" && !p.IsFinished {
+			return false
+		}
+	}
+
+	// Update atomik supaya DetermineWinner (dan payout) hanya jalan sekali
+	res := config.DB.Model(&models.Challenge{}).
+		Where("id = ? AND status IN ?", challengeID, []string{"pending", "active"}).
+		Update("status", "finished")
+	if res.RowsAffected == 0 {
+		return false
+	}
+
+	DetermineWinner(challengeID)
+	return true
+}
+
+// --- Grace period disconnect saat match realtime ---
+
+var disconnectTimers = struct {
+	sync.Mutex
+	Timers map[string]*time.Timer
+}{Timers: make(map[string]*time.Timer)}
+
+func disconnectTimerKey(challengeID uint, userID uint) string {
+	return fmt.Sprintf("%d:%d", challengeID, userID)
+}
+
+// ScheduleDisconnectForfeit dipanggil saat koneksi lobby terputus. Jika pemain
+// tidak kembali dalam DisconnectGracePeriod selama match berjalan, dia kalah WO.
+func ScheduleDisconnectForfeit(challengeID uint, userID uint) {
+	var challenge models.Challenge
+	if err := config.DB.First(&challenge, challengeID).Error; err != nil {
+		return
+	}
+	if !challenge.IsRealtime || challenge.Status != "active" {
+		return
+	}
+
+	var participant models.ChallengeParticipant
+	if err := config.DB.Where("challenge_id = ? AND user_id = ?", challengeID, userID).First(&participant).Error; err != nil {
+		return
+	}
+	if participant.Status != "accepted" || participant.IsFinished {
+		return
+	}
+
+	key := disconnectTimerKey(challengeID, userID)
+
+	disconnectTimers.Lock()
+	if old, ok := disconnectTimers.Timers[key]; ok {
+		old.Stop()
+	}
+	disconnectTimers.Timers[key] = time.AfterFunc(DisconnectGracePeriod, func() {
+		disconnectTimers.Lock()
+		delete(disconnectTimers.Timers, key)
+		disconnectTimers.Unlock()
+
+		if IsInLobby(challengeID, userID) {
+			return
+		}
+		if ForfeitParticipant(challengeID, userID, "Terputus dari match") {
+			FinishChallengeIfComplete(challengeID)
+		}
+	})
+	disconnectTimers.Unlock()
+
+	LogMatchEvent(challengeID, userID, "disconnect", models.ChallengeMatchEvent{})
+	BroadcastLobby(challengeID, "player_disconnected", map[string]interface{}{
+		"user_id":       userID,
+		"grace_seconds": int(DisconnectGracePeriod.Seconds()),
+	})
+}
+
+// CancelDisconnectForfeit dipanggil saat pemain tersambung kembali ke lobby
+func CancelDisconnectForfeit(challengeID uint, userID uint) {
+	key := disconnectTimerKey(challengeID, userID)
+	hostKey := "host:" + key
+
+	disconnectTimers.Lock()
+	timer, ok := disconnectTimers.Timers[key]
+	if ok {
+		timer.Stop()
+		delete(disconnectTimers.Timers, key)
+	}
+	if hostTimer, exists := disconnectTimers.Timers[hostKey]; exists {
+		hostTimer.Stop()
+		delete(disconnectTimers.Timers, hostKey)
+	}
+	disconnectTimers.Unlock()
+
+	if ok {
+		LogMatchEvent(challengeID, userID, "reconnect", models.ChallengeMatchEvent{})
+		BroadcastLobby(challengeID, "player_reconnected", map[string]interface{}{"user_id": userID})
+	}
+}
+
+// --- Migrasi host lobby ---
+
+// ScheduleHostMigration dipanggil saat koneksi host ke lobby terputus. Jika host tidak
+// kembali dalam DisconnectGracePeriod, host dipindah ke pemain lain supaya game tetap bisa dimulai.
+func ScheduleHostMigration(challengeID uint, userID uint) {
+	var challenge models.Challenge
+	if err := config.DB.First(&challenge, challengeID).Error; err != nil {
+		return
+	}
+	if challenge.CreatorID != userID || challenge.Status != "pending" || !challenge.IsRealtime {
+		return
+	}
+
+	key := "host:" + disconnectTimerKey(challengeID, userID)
+
+	disconnectTimers.Lock()
+	defer disconnectTimers.Unlock()
+
+	if old, ok := disconnectTimers.Timers[key]; ok {
+		old.Stop()
+	}
+	disconnectTimers.Timers[key] = time.AfterFunc(DisconnectGracePeriod, func() {
+		disconnectTimers.Lock()
+		delete(disconnectTimers.Timers, key)
+		disconnectTimers.Unlock()
+
+		if IsInLobby(challengeID, userID) {
+			return
+		}
+		MigrateLobbyHost(challengeID, userID)
+	})
+}
+
+// MigrateLobbyHost memindahkan host lobby yang belum mulai ke pemain accepted lain,
+// diutamakan yang sedang online. Return host baru dan true jika berhasil.
+func MigrateLobbyHost(challengeID uint, leavingUserID uint) (uint, bool) {
+	var challenge models.Challenge
+	if err := config.DB.Preload("Participants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).First(&challenge, challengeID).Error; err != nil {
+		return 0, false
+	}
+	if challenge.CreatorID != leavingUserID || challenge.Status != "pending" || challenge.TournamentID != nil {
+		return 0, false
+	}
+
+	var newHost uint
+	for pass := 0; pass < 2 && newHost == 0; pass++ {
+		for _, p := range challenge.Participants {
+			if p.UserID == leavingUserID || p.Status != "accepted" {
+				continue
+			}
+			if pass == 0 && !IsInLobby(challengeID, p.UserID) {
+				continue
+			}
+			newHost = p.UserID
+			break
+		}
+	}
+	if newHost == 0 {
+		return 0, false
+	}
+
+	res := config.DB.Model(&models.Challenge{}).
+		Where("id = ? AND creator_id = ? AND status = ?", challengeID, leavingUserID, "pending").
+		Update("creator_id", newHost)
+	if res.RowsAffected == 0 {
+		return 0, false
+	}
+
+	BroadcastLobby(challengeID, "host_changed", map[string]interface{}{
+		"old_host_id": leavingUserID,
+		"new_host_id": newHost,
+	})
+	SendNotification(newHost, "info", "Kamu Host Baru", "👑 Host keluar dari lobby, sekarang kamu yang jadi host.", "/challenges")
+	return newHost, true
+}
@@ -0,0 +1,287 @@
+package utils
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInsufficientCoins = errors.New("insufficient coins")
+
+// GetWagerHouseFeePercent membaca potongan bandar (persen dari pot) dari SystemConfig
+func GetWagerHouseFeePercent() int {
+	var conf models.SystemConfig
+	config.DB.Where("key = ?", "wager_house_fee_percent").Find(&conf)
+
+	val, err := strconv.Atoi(conf.Value)
+	if err != nil || val < 0 || val > 100 {
+		return 0
+	}
+	return val
+}
+
+// HoldWagerStake memotong koin user dan menyimpannya di escrow challenge.
+// Idempotent: jika user sudah punya stake yang ditahan, tidak dipotong lagi.
+func HoldWagerStake(tx *gorm.DB, challenge models.Challenge, userID uint) error {
+	if challenge.WagerAmount <= 0 {
+		return nil
+	}
+
+	escrow, err := lockEscrow(tx, challenge.ID)
+	if err != nil {
+		return err
+	}
+	if escrow.Status != "holding" {
+		return errors.New("escrow already closed")
+	}
+
+	var stake models.WagerStake
+	err = tx.Where("challenge_id = ? AND user_id = ?", challenge.ID, userID).First(&stake).Error
+	if err == nil && stake.Status == "held" {
+		return nil
+	}
+
+	// Potong koin secara atomik ke akun escrow, gagal jika saldo kurang
+	if err := MoveCoins(tx, UserAccount(userID), EscrowAccount(challenge.ID), challenge.WagerAmount,
+		"wager_stake", LedgerRef{Type: "challenge", ID: challenge.ID}); err != nil {
+		return err
+	}
+
+	if stake.ID != 0 {
+		// Stake lama (sudah di-refund) dipakai ulang
+		stake.Status = "held"
+		stake.Amount = challenge.WagerAmount
+		stake.Payout = 0
+		if err := tx.Save(&stake).Error; err != nil {
+			return err
+		}
+	} else {
+		stake = models.WagerStake{
+			EscrowID:    escrow.ID,
+			ChallengeID: challenge.ID,
+			UserID:      userID,
+			Amount:      challenge.WagerAmount,
+			Status:      "held",
+		}
+		if err := tx.Create(&stake).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&escrow).UpdateColumn("total_amount", gorm.Expr("total_amount + ?", challenge.WagerAmount)).Error
+}
+
+// RefundWagerStake mengembalikan stake satu user (misal: reject setelah accept, tidak hadir saat start)
+func RefundWagerStake(challengeID uint, userID uint) int {
+	refunded := 0
+	config.DB.Transaction(func(tx *gorm.DB) error {
+		escrow, err := lockEscrow(tx, challengeID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && escrow.Status != "holding") {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var stake models.WagerStake
+		if err := tx.Where("challenge_id = ? AND user_id = ? AND status = ?", challengeID, userID, "held").First(&stake).Error; err != nil {
+			return nil
+		}
+
+		if err := refundStake(tx, &stake); err != nil {
+			return err
+		}
+		refunded = stake.Amount
+		return tx.Model(&escrow).UpdateColumn("total_amount", gorm.Expr("total_amount - ?", stake.Amount)).Error
+	})
+
+	if refunded > 0 {
+		SendNotification(userID, "info", "Taruhan Dikembalikan", strconv.Itoa(refunded)+" koin taruhan dikembalikan.", "/shop")
+	}
+	return refunded
+}
+
+// RefundWagerEscrow mengembalikan semua stake (reject, cancel, expired, draw).
+// Idempotent: escrow yang sudah ditutup tidak diproses lagi.
+func RefundWagerEscrow(challengeID uint) map[uint]int {
+	refunds := make(map[uint]int)
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		escrow, err := lockEscrow(tx, challengeID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && escrow.Status != "holding") {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var stakes []models.WagerStake
+		tx.Where("escrow_id = ? AND status = ?", escrow.ID, "held").Find(&stakes)
+
+		for i := range stakes {
+			if err := refundStake(tx, &stakes[i]); err != nil {
+				return err
+			}
+			refunds[stakes[i].UserID] += stakes[i].Amount
+		}
+
+		escrow.Status = "refunded"
+		return tx.Save(&escrow).Error
+	})
+	if err != nil {
+		return map[uint]int{}
+	}
+
+	for userID, amount := range refunds {
+		SendNotification(userID, "info", "Taruhan Dikembalikan", strconv.Itoa(amount)+" koin taruhan dikembalikan.", "/shop")
+	}
+	return refunds
+}
+
+// SettleWagerEscrow membagi pot ke para pemenang (dikurangi potongan bandar).
+// Tanpa pemenang (draw) semua stake dikembalikan. Return payout per pemenang.
+func SettleWagerEscrow(challengeID uint, winnerIDs []uint) map[uint]int {
+	if len(winnerIDs) == 0 {
+		RefundWagerEscrow(challengeID)
+		return map[uint]int{}
+	}
+
+	payouts := make(map[uint]int)
+	feePercent := GetWagerHouseFeePercent()
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		escrow, err := lockEscrow(tx, challengeID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && escrow.Status != "holding") {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var stakes []models.WagerStake
+		tx.Where("escrow_id = ? AND status = ?", escrow.ID, "held").Find(&stakes)
+
+		pot := 0
+		for _, s := range stakes {
+			pot += s.Amount
+		}
+		fee := pot * feePercent / 100
+		distributable := pot - fee
+
+		// Urutkan supaya sisa pembagian selalu jatuh ke pemenang yang sama
+		winners := append([]uint(nil), winnerIDs...)
+		sort.Slice(winners, func(i, j int) bool { return winners[i] < winners[j] })
+
+		share := distributable / len(winners)
+		remainder := distributable % len(winners)
+		for i, uid := range winners {
+			amount := share
+			if i == 0 {
+				amount += remainder
+			}
+			if amount <= 0 {
+				continue
+			}
+			if err := MoveCoins(tx, EscrowAccount(challengeID), UserAccount(uid), amount,
+				"wager_payout", LedgerRef{Type: "challenge", ID: challengeID}); err != nil {
+				return err
+			}
+			payouts[uid] = amount
+		}
+		if err := MoveCoins(tx, EscrowAccount(challengeID), AccountHouse, fee,
+			"wager_house_fee", LedgerRef{Type: "challenge", ID: challengeID}); err != nil {
+			return err
+		}
+
+		isWinner := make(map[uint]bool)
+		for _, uid := range winners {
+			isWinner[uid] = true
+		}
+		for _, s := range stakes {
+			status := "lost"
+			if isWinner[s.UserID] {
+				status = "paid_out"
+			}
+			if err := tx.Model(&models.WagerStake{}).Where("id = ?", s.ID).
+				Updates(map[string]interface{}{"status": status, "payout": payouts[s.UserID]}).Error; err != nil {
+				return err
+			}
+		}
+
+		escrow.Status = "released"
+		escrow.HouseFee = fee
+		return tx.Save(&escrow).Error
+	})
+	if err != nil {
+		return map[uint]int{}
+	}
+	return payouts
+}
+
+func refundStake(tx *gorm.DB, stake *models.WagerStake) error {
+	res := tx.Model(&models.WagerStake{}).
+		Where("id = ? AND status = ?", stake.ID, "held").
+		Update("status", "refunded")
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil
+	}
+	return MoveCoins(tx, EscrowAccount(stake.ChallengeID), UserAccount(stake.UserID), stake.Amount,
+		"wager_refund", LedgerRef{Type: "challenge", ID: stake.ChallengeID})
+}
+
+// OpenWagerEscrow membuat escrow kosong untuk challenge baru (dipanggil di transaksi yang sama)
+func OpenWagerEscrow(tx *gorm.DB, challengeID uint) error {
+	return tx.Create(&models.WagerEscrow{ChallengeID: challengeID, Status: "holding"}).Error
+}
+
+// lockEscrow mengambil escrow challenge. Row challenge dikunci (SELECT ... FOR UPDATE)
+// supaya semua operasi escrow untuk challenge yang sama berjalan berurutan.
+// Challenge lama yang dibuat sebelum ada escrow dibuatkan escrow dari peserta yang sudah accept.
+func lockEscrow(tx *gorm.DB, challengeID uint) (models.WagerEscrow, error) {
+	var escrow models.WagerEscrow
+
+	var challenge models.Challenge
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&challenge, challengeID).Error; err != nil {
+		return escrow, err
+	}
+
+	err := tx.Where("challenge_id = ?", challengeID).First(&escrow).Error
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return escrow, err
+	}
+	if challenge.WagerAmount <= 0 {
+		return escrow, gorm.ErrRecordNotFound
+	}
+
+	// Challenge lama: koin peserta yang accept sudah terpotong langsung
+	var participants []models.ChallengeParticipant
+	tx.Where("challenge_id = ? AND status = ?", challengeID, "accepted").Find(&participants)
+
+	escrow = models.WagerEscrow{ChallengeID: challengeID, Status: "holding"}
+	for _, p := range participants {
+		escrow.Stakes = append(escrow.Stakes, models.WagerStake{
+			ChallengeID: challengeID,
+			UserID:      p.UserID,
+			Amount:      challenge.WagerAmount,
+			Status:      "held",
+		})
+		escrow.TotalAmount += challenge.WagerAmount
+	}
+
+	if err := tx.Create(&escrow).Error; err != nil {
+		return escrow, err
+	}
+
+	// Stake lama belum tercatat di ledger: catat sebagai saldo awal escrow
+	err = recordLedgerTransfer(tx, AccountOpeningBalance, EscrowAccount(challengeID), escrow.TotalAmount,
+		"opening_balance", LedgerRef{Type: "challenge", ID: challengeID})
+	return escrow, err
+}
@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const antiFarmingKey = "anti_farming"
+
+// Sumber XP untuk batas harian
+const (
+	XPSourceQuiz      = "quiz"
+	XPSourceSurvival  = "survival"
+	XPSourceChallenge = "challenge"
+)
+
+// Alasan flag anti-farming
+const (
+	FarmingTooFast           = "too_fast"
+	FarmingImpossiblePerfect = "impossible_perfect"
+)
+
+var (
+	ErrFarmingFlagReviewed = errors.New("flag already reviewed")
+	ErrFarmingFlagStatus   = errors.New("status must be dismissed or confirmed")
+)
+
+// AntiFarmingRules disimpan sebagai JSON di SystemConfig "anti_farming"
+type AntiFarmingRules struct {
+	QuizCooldownMinutes          int            `json:"quiz_cooldown_minutes"`            // Kuis yang sama dalam jeda ini tidak dapat XP & progress misi
+	PerfectRunDecay              float64        `json:"perfect_run_decay"`                // Pengali tambahan per skor 100 sebelumnya di kuis yang sama
+	DailyXPCaps                  map[string]int `json:"daily_xp_caps"`                    // Batas XP harian per sumber, 0 = tanpa batas
+	MinSecondsPerQuestion        float64        `json:"min_seconds_per_question"`         // Di bawah ini = too_fast
+	PerfectMinSecondsPerQuestion float64        `json:"perfect_min_seconds_per_question"` // Skor sempurna di bawah ini = impossible_perfect
+	BattleRoyaleDailyCoins       int            `json:"battle_royale_daily_coins"`        // Batas koin hadiah battle royale per hari, 0 = tanpa batas
+}
+
+func DefaultAntiFarmingRules() AntiFarmingRules {
+	return AntiFarmingRules{
+		QuizCooldownMinutes: 10,
+		PerfectRunDecay:     0.5,
+		DailyXPCaps: map[string]int{
+			XPSourceQuiz:      2000,
+			XPSourceSurvival:  1000,
+			XPSourceChallenge: 1500,
+		},
+		MinSecondsPerQuestion:        0.5,
+		PerfectMinSecondsPerQuestion: 1,
+		BattleRoyaleDailyCoins:       100,
+	}
+}
+
+// GetAntiFarmingRules membaca aturan dari SystemConfig; field yang kosong memakai default
+func GetAntiFarmingRules() AntiFarmingRules {
+	rules := DefaultAntiFarmingRules()
+
+	var conf models.SystemConfig
+	config.DB.Where("key = ?", antiFarmingKey).Find(&conf)
+	if conf.Value == "" {
+		return rules
+	}
+	if err := json.Unmarshal([]byte(conf.Value), &rules); err != nil {
+		return DefaultAntiFarmingRules()
+	}
+	return rules
+}
+
+func ValidateAntiFarmingRules(r AntiFarmingRules) error {
+	if r.QuizCooldownMinutes < 0 || r.QuizCooldownMinutes > 24*60 {
+		return errors.New("quiz_cooldown_minutes must be between 0 and 1440")
+	}
+	if r.PerfectRunDecay < 0 || r.PerfectRunDecay > 1 {
+		return errors.New("perfect_run_decay must be between 0 and 1")
+	}
+	for source, limit := range r.DailyXPCaps {
+		if source != XPSourceQuiz && source != XPSourceSurvival && source != XPSourceChallenge {
+			return fmt.Errorf("unknown xp source %q", source)
+		}
+		if limit < 0 {
+			return errors.New("daily xp cap cannot be negative")
+		}
+	}
+	if r.MinSecondsPerQuestion < 0 || r.PerfectMinSecondsPerQuestion < 0 {
+		return errors.New("seconds per question cannot be negative")
+	}
+	if r.BattleRoyaleDailyCoins < 0 {
+		return errors.New("battle_royale_daily_coins cannot be negative")
+	}
+	return nil
+}
+
+func SaveAntiFarmingRules(r AntiFarmingRules) error {
+	if err := ValidateAntiFarmingRules(r); err != nil {
+		return err
+	}
+	value, _ := json.Marshal(r)
+
+	var conf models.SystemConfig
+	return config.DB.Where("key = ?", antiFarmingKey).
+		Assign(models.SystemConfig{Value: string(value)}).
+		FirstOrCreate(&conf).Error
+}
+
+// sameQuizHistories memfilter history untuk kuis yang sama; remedial (quizID 0) dikenali dari set soalnya.
+// Return nil jika tidak ada acuan (misal survival).
+func sameQuizHistories(userID uint, quizID uint, questionSetKey string) *gorm.DB {
+	query := config.DB.Model(&models.History{}).Where("user_id = ?", userID)
+	if quizID != 0 {
+		return query.Where("quiz_id = ?", quizID)
+	}
+	if questionSetKey != "" {
+		return query.Where("quiz_id = ? AND question_set_key = ?", 0, questionSetKey)
+	}
+	return nil
+}
+
+// QuizOnCooldown: user sudah menyelesaikan kuis (atau set soal remedial) yang sama dalam jeda cooldown
+func QuizOnCooldown(userID uint, quizID uint, questionSetKey string, rules AntiFarmingRules) bool {
+	query := sameQuizHistories(userID, quizID, questionSetKey)
+	if query == nil || rules.QuizCooldownMinutes <= 0 {
+		return false
+	}
+	since := time.Now().Add(-time.Duration(rules.QuizCooldownMinutes) * time.Minute)
+
+	var count int64
+	query.Where("created_at > ?", since).Count(&count)
+	return count > 0
+}
+
+// AwardXP memberi XP setelah dipotong batas harian sumbernya. Return XP yang benar-benar diberikan.
+func AwardXP(userID uint, source string, amount int) int {
+	if amount <= 0 {
+		return 0
+	}
+
+	granted := amount
+	limit := GetAntiFarmingRules().DailyXPCaps[source]
+	if limit > 0 {
+		today := StripTime(GetJakartaTime())
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.UserDailyXP{UserID: userID, Date: today, Source: source})
+
+			var daily models.UserDailyXP
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("user_id = ? AND date = ? AND source = ?", userID, today, source).
+				First(&daily).Error; err != nil {
+				return err
+			}
+
+			granted = max(0, min(amount, limit-daily.Amount))
+			return tx.Model(&daily).Updates(map[string]interface{}{
+				"amount": gorm.Expr("amount + ?", granted),
+				"capped": gorm.Expr("capped + ?", amount-granted),
+			}).Error
+		})
+		if err != nil {
+			return 0
+		}
+	}
+
+	if granted > 0 {
+		AddUserXP(userID, granted)
+	}
+	return granted
+}
+
+// DetectFarming memeriksa hasil yang mustahil dicapai manusia.
+// perfect = semua soal benar; return reason kosong jika wajar.
+func DetectFarming(questions int, timeTaken int, perfect bool, rules AntiFarmingRules) (string, string) {
+	if questions <= 0 {
+		return "", ""
+	}
+	perQuestion := float64(timeTaken) / float64(questions)
+	details := fmt.Sprintf("%d soal dalam %d detik (%.2f detik/soal)", questions, timeTaken, perQuestion)
+
+	if perfect && perQuestion < rules.PerfectMinSecondsPerQuestion {
+		return FarmingImpossiblePerfect, details
+	}
+	if perQuestion < rules.MinSecondsPerQuestion {
+		return FarmingTooFast, details
+	}
+	return "", ""
+}
+
+// FlagFarming memasukkan hasil ke antrean review dan menandai history-nya
+func FlagFarming(flag models.FarmingFlag) {
+	flag.Status = "pending"
+	if err := config.DB.Create(&flag).Error; err != nil {
+		return
+	}
+	if flag.HistoryID != nil {
+		config.DB.Model(&models.History{}).Where("id = ?", *flag.HistoryID).Update("flagged", true)
+	}
+}
+
+// ReviewFarmingFlag menutup flag. Dismissed = hasil dianggap wajar, XP & progress misi yang ditahan diberikan.
+func ReviewFarmingFlag(flagID uint, reviewerID uint, status string, note string) (models.FarmingFlag, error) {
+	var flag models.FarmingFlag
+	if status != "dismissed" && status != "confirmed" {
+		return flag, ErrFarmingFlagStatus
+	}
+	if err := config.DB.First(&flag, flagID).Error; err != nil {
+		return flag, err
+	}
+
+	// Hanya satu review yang bisa mengubah status pending
+	now := time.Now()
+	res := config.DB.Model(&models.FarmingFlag{}).
+		Where("id = ? AND status = ?", flag.ID, "pending").
+		Updates(map[string]interface{}{"status": status, "reviewed_by": reviewerID, "reviewed_at": now, "note": note})
+	if res.Error != nil {
+		return flag, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return flag, ErrFarmingFlagReviewed
+	}
+
+	if status == "dismissed" && flag.HistoryID != nil {
+		var history models.History
+		if err := config.DB.First(&history, *flag.HistoryID).Error; err == nil {
+			granted := AwardXP(flag.UserID, flag.Source, flag.WithheldXP)
+			config.DB.Model(&history).Updates(map[string]interface{}{"flagged": false, "xp_gained": granted})
+			if flag.MissionWithheld {
+				TrackMissionEvent(flag.UserID, QuizFinishedMissionEvent(history))
+			}
+		}
+	}
+
+	config.DB.Preload("User").First(&flag, flag.ID)
+	return flag, nil
+}
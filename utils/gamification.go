@@ -4,10 +4,12 @@ import (
 	"github.com/ROFL1ST/quizzes-backend/config"
 	"github.com/ROFL1ST/quizzes-backend/models"
 	"math"
-	"math/rand"
 	"strconv"
 	"time"
 	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func GetJakartaTime() time.Time {
@@ -30,178 +32,17 @@ func StripTime(t time.Time) time.Time {
 	return time.Date(y, m, d, 0, 0, 0, 0, loc)
 }
 
-func CheckDailyMissions(userID uint, trigger string, val int, meta string) {
-	today := StripTime(GetJakartaTime()) // Waktu Jakarta
-
-	// 1. Ambil Misi Harian User yang Aktif Hari Ini & Belum Diklaim
-	var userMissions []models.UserMission
-	config.DB.Preload("Mission").
-		Where("user_id = ? AND reset_date = ? AND is_claimed = ?", userID, today, false).
-		Find(&userMissions)
-
-	for _, um := range userMissions {
-		key := um.Mission.Key
-		increment := 0
-
-		// 2. Logika Pencocokan Misi
-		switch trigger {
-		case "quiz":
-			// Misi: Mainkan Kuis
-			if key == "play_quiz_1" || key == "play_quiz_3" || key == "play_quiz_5" {
-				increment = 1
-			}
-			// Misi: Skor
-			if (key == "score_100" || key == "score_100_3x") && val == 100 {
-				increment = 1
-			}
-			if key == "total_score_500" {
-				increment = val // Tambah skor
-			}
-			if key == "perfect_streak" && val >= 10 {
-				increment = 10
-			}
-			// Misi Topik
-			if key == "quiz_math" && meta == "Matematika" {
-				increment = 1
-			}
-			if key == "quiz_history" && meta == "Sejarah" {
-				increment = 1
-			}
-
-		case "login":
-			// Cek Jam Login (Waktu Jakarta)
-			hour := GetJakartaTime().Hour()
-			if key == "login_morning" && hour >= 5 && hour < 10 {
-				increment = 1
-			}
-			if key == "login_night" && hour >= 20 && hour <= 23 {
-				increment = 1
-			}
-
-		case "shop":
-			if key == "buy_item" && meta == "buy" {
-				increment = 1
-			}
-			if key == "equip_avatar" && meta == "equip" {
-				increment = 1
-			}
-
-		case "social":
-			if key == "add_friend" && meta == "add" {
-				increment = 1
-			}
-			if key == "check_leaderboard" && meta == "view" {
-				increment = 1
-			}
-			if key == "share_app" && meta == "share" {
-				increment = 1
-			}
-
-		case "challenge":
-			if key == "win_challenge_1" && meta == "win" {
-				increment = 1
-			}
-			if key == "play_challenge_2v2" && meta == "2v2" {
-				increment = 1
-			}
-
-		case "level":
-			if key == "earn_xp_1000" {
-				increment = val
-			}
-
-			if key == "level_up_daily" && meta == "levelup" {
-				increment = 1
-			}
-		}
-
-		// 3. Update Progress
-		if increment > 0 {
-			if um.Progress < um.Mission.Target {
-				um.Progress += increment
-				if um.Progress > um.Mission.Target {
-					um.Progress = um.Mission.Target
-				}
-				config.DB.Save(&um)
-
-				if um.Progress == um.Mission.Target {
-					SendNotification(userID, "success", "Misi Selesai!", "Kamu menyelesaikan misi: "+um.Mission.Title, "/")
-				}
-			}
-		}
-	}
-}
-
-func AssignDailyMissions(userID uint) {
-	today := StripTime(time.Now())
-
-	// Cek apakah user sudah punya misi hari ini?
-	var count int64
-	config.DB.Model(&models.UserMission{}).
-		Where("user_id = ? AND reset_date = ?", userID, today).
-		Count(&count)
-
-	if count > 0 {
-		return
-	} // Sudah ada, skip.
-
-	// Ambil semua misi aktif
-	var allMissions []models.Mission
-	config.DB.Where("is_active = ?", true).Find(&allMissions)
-
-	if len(allMissions) == 0 {
-		return
-	}
-
-	// Acak (Shuffle)
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(allMissions), func(i, j int) {
-		allMissions[i], allMissions[j] = allMissions[j], allMissions[i]
-	})
-
-	// Ambil 5 Teratas
-	limit := 5
-	if len(allMissions) < 5 {
-		limit = len(allMissions)
-	}
-
-	for i := 0; i < limit; i++ {
-		um := models.UserMission{
-			UserID:    userID,
-			MissionID: allMissions[i].ID,
-			ResetDate: today,
-			Progress:  0,
-		}
-		config.DB.Create(&um)
-	}
-}
-
-func UnlockAchievement(userID uint, achievementID uint) {
-	var count int64
-	config.DB.Model(&models.UserAchievement{}).
-		Where("user_id = ? AND achievement_id = ?", userID, achievementID).
-		Count(&count)
-
-	if count > 0 {
-		return
-	}
-
+// unlockAchievement mencatat achievement untuk user. Return false jika sudah pernah dibuka.
+func unlockAchievement(userID uint, ach models.Achievement, notify bool) bool {
 	ua := models.UserAchievement{
 		UserID:        userID,
-		AchievementID: achievementID,
+		AchievementID: ach.ID,
 		UnlockedAt:    time.Now(),
 	}
-	if err := config.DB.Create(&ua).Error; err != nil {
-		return
-	}
-
-	var ach models.Achievement
-	config.DB.First(&ach, achievementID)
-
-	// 1. Ambil data User untuk mendapatkan Username
-	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
-		return // Handle jika user tidak ditemukan (opsional)
+	// Primary key (user_id, achievement_id) mencegah unlock ganda
+	res := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&ua)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false
 	}
 
 	activity := models.Activity{
@@ -211,90 +52,13 @@ func UnlockAchievement(userID uint, achievementID uint) {
 	}
 	config.DB.Create(&activity)
 
-	// 2. [UPDATED] Send Notification dengan Title
-	// Format: UserID, Type, Title, Message, Link
-	SendNotification(userID, "success", "Achievement Unlocked!", "🏆 Selamat! Kamu membuka: "+ach.Name, "/@"+user.Username)
-}
-
-func CheckQuizAchievements(userID uint, score int) {
-	UnlockAchievement(userID, 1)
-
-	if score == 100 {
-		UnlockAchievement(userID, 2)
-	}
-
-	if score < 50 {
-		UnlockAchievement(userID, 11)
-	}
-
-	var totalKuis int64
-	config.DB.Model(&models.History{}).Where("user_id = ?", userID).Count(&totalKuis)
-	if totalKuis >= 10 {
-		UnlockAchievement(userID, 3)
-	}
-
-	currentHour := time.Now().Hour()
-	if currentHour >= 0 && currentHour < 5 {
-		UnlockAchievement(userID, 12)
-	}
-
-	var user models.User
-	config.DB.First(&user, userID)
-
-	if user.Level >= 5 {
-		UnlockAchievement(userID, 5)
-	}
-
-	if user.Level >= 10 {
-		UnlockAchievement(userID, 13)
-	}
-
-	if user.XP >= 5000 {
-		UnlockAchievement(userID, 10)
-	}
-
-	if user.StreakCount >= 3 {
-		UnlockAchievement(userID, 6)
-	}
-
-	hoursSinceJoin := time.Since(user.CreatedAt).Hours()
-	if hoursSinceJoin >= (24 * 30) {
-		UnlockAchievement(userID, 14)
-	}
-
-	if score == 100 {
-		var perfectQuizzes int64
-		config.DB.Model(&models.History{}).
-			Where("user_id = ? AND score = 100", userID).
-			Distinct("quiz_id").
-			Count(&perfectQuizzes)
-
-		if perfectQuizzes >= 3 {
-			UnlockAchievement(userID, 15)
+	if notify {
+		var user models.User
+		if err := config.DB.Select("id", "username").First(&user, userID).Error; err == nil {
+			SendNotification(userID, "success", "Achievement Unlocked!", "🏆 Selamat! Kamu membuka: "+ach.Name, "/@"+user.Username)
 		}
 	}
-
-	var distinctTopics int64
-	config.DB.Table("histories").
-		Joins("JOIN quizzes ON quizzes.id = histories.quiz_id").
-		Where("histories.user_id = ?", userID).
-		Distinct("quizzes.topic_id").
-		Count(&distinctTopics)
-
-	if distinctTopics >= 3 {
-		UnlockAchievement(userID, 8)
-	}
-
-	var totalWins int64
-	config.DB.Model(&models.Challenge{}).Where("winner_id = ?", userID).Count(&totalWins)
-
-	if totalWins >= 1 {
-		UnlockAchievement(userID, 4)
-	}
-
-	if totalWins >= 5 {
-		UnlockAchievement(userID, 9)
-	}
+	return true
 }
 
 func GetLevelingFactor() float64 {
@@ -325,6 +89,44 @@ func CalculateMinXPForLevel(level int) int64 {
 	return int64(factor * math.Pow(float64(level-1), 2))
 }
 
+// AddUserXP menambah XP user secara atomik dan menaikkan level jika perlu.
+// Return level terbaru dan apakah user naik level.
+func AddUserXP(userID uint, amount int) (int, bool) {
+	if amount > 0 {
+		config.DB.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("xp", gorm.Expr("xp + ?", amount))
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return 0, false
+	}
+
+	if amount > 0 {
+		AddLeagueXP(userID, amount)
+		FireAchievementEvent(userID, AchievementXPGained)
+		TrackMissionEvent(userID, MissionEvent{Type: MissionXPGained, Value: amount})
+	}
+
+	newLevel := CalculateLevel(user.XP)
+	if newLevel <= user.Level {
+		return user.Level, false
+	}
+
+	config.DB.Model(&user).Update("level", newLevel)
+	activity := models.Activity{UserID: user.ID, Type: "level_up", Description: "Naik ke Level " + strconv.Itoa(newLevel)}
+	config.DB.Create(&activity)
+
+	SendNotification(user.ID, "success", "Naik Level!", "⭐ Level Up! Kamu naik ke Level "+strconv.Itoa(newLevel), "/@"+user.Username)
+	TrackMissionEvent(user.ID, MissionEvent{
+		Type:  MissionLevelUp,
+		Key:   "level_up:" + strconv.Itoa(newLevel),
+		Value: 1,
+		Attrs: map[string]interface{}{"level": newLevel},
+	})
+	FireAchievementEvent(user.ID, AchievementLevelUp)
+	return newLevel, true
+}
+
 func DetermineWinner(challengeID uint) {
 	var challenge models.Challenge
 	// Preload Participants untuk akses data user dan scoring
@@ -344,7 +146,7 @@ func DetermineWinner(challengeID uint) {
 		scoreB, timeB := 0, 0
 		
 		for _, p := range challenge.Participants {
-			if p.Status == "accepted" && p.IsFinished {
+			if p.Status == "accepted" && p.IsFinished && !p.Forfeited {
 				if p.Team == "A" {
 					scoreA += p.Score
 					timeA += p.TimeTaken
@@ -369,6 +171,10 @@ func DetermineWinner(challengeID uint) {
 			}
 		}
 
+	} else if challenge.Mode == BattleRoyaleMode {
+		// --- LOGIC BATTLE ROYALE ELIMINASI: juara = peringkat 1 ---
+		challenge.WinnerID = FinalizeBattleRoyale(&challenge)
+
 	} else {
 		// --- LOGIC BATTLE ROYALE / 1V1 ---
 		var winnerID uint = 0
@@ -376,8 +182,8 @@ func DetermineWinner(challengeID uint) {
 		lowestTime := 999999999
 
 		for _, p := range challenge.Participants {
-			// Hanya hitung yang Accepted dan Sudah Selesai
-			if p.Status == "accepted" && p.IsFinished {
+			// Hanya hitung yang Accepted, Sudah Selesai, dan tidak kalah WO
+			if p.Status == "accepted" && p.IsFinished && !p.Forfeited {
 				if p.Score > highestScore {
 					highestScore = p.Score
 					lowestTime = p.TimeTaken
@@ -397,58 +203,59 @@ func DetermineWinner(challengeID uint) {
 	}
 
 
-	if challenge.WagerAmount > 0 {
-		// CASE A: Mode 1v1 (Ada WinnerID)
-		if challenge.WinnerID != nil {
-			totalPot := challenge.WagerAmount * 2 // Taruhan Saya + Taruhan Lawan
-			
-			var winner models.User
-			if err := config.DB.First(&winner, *challenge.WinnerID).Error; err == nil {
-				winner.Coins += totalPot
-				config.DB.Save(&winner)
-
-				// Notifikasi Khusus Menang Uang
-				msg := fmt.Sprintf("Jackpot! Kamu menang %d koin dari taruhan!", totalPot)
-				SendNotification(winner.ID, "success", "Menang Taruhan!", msg, "/shop")
-			}
-		} 
-		
-		
-		if challenge.Mode == "2v2" && challenge.WinningTeam != "" && challenge.WinningTeam != "DRAW" {
-		
-			rewardPerPerson := challenge.WagerAmount * 2
-
-			for _, p := range challenge.Participants {
-				if p.Team == challenge.WinningTeam && p.Status == "accepted" {
-					var member models.User
-					if err := config.DB.First(&member, p.UserID).Error; err == nil {
-						member.Coins += rewardPerPerson
-						config.DB.Save(&member)
-
-						msg := fmt.Sprintf("Tim Menang! Kamu dapat %d koin!", rewardPerPerson)
-						SendNotification(member.ID, "success", "Menang Taruhan 2v2!", msg, "/shop")
-					}
-				}
+	// Pembayaran taruhan lewat escrow: pot (dikurangi potongan bandar) dibagi rata ke pemenang,
+	// DRAW / tanpa pemenang = semua taruhan dikembalikan
+	var winnerIDs []uint
+	if challenge.WinnerID != nil {
+		winnerIDs = append(winnerIDs, *challenge.WinnerID)
+	}
+	if challenge.Mode == "2v2" && challenge.WinningTeam != "" && challenge.WinningTeam != "DRAW" {
+		for _, p := range challenge.Participants {
+			if p.Team == challenge.WinningTeam && p.Status == "accepted" {
+				winnerIDs = append(winnerIDs, p.UserID)
 			}
 		}
-		
-		// CASE C: DRAW (Kembalikan Uang - Optional)
-		// Jika DRAW, biasanya uang hangus atau dikembalikan. 
-		// Kode di bawah ini opsional untuk refund jika Draw.
-		/*
-		if (challenge.Mode == "2v2" && challenge.WinningTeam == "DRAW") {
-			for _, p := range challenge.Participants {
-				if p.Status == "accepted" {
-					// Refund logic here
-				}
+	}
+
+	if challenge.WagerAmount > 0 {
+		payouts := SettleWagerEscrow(challenge.ID, winnerIDs)
+		for uid, amount := range payouts {
+			title := "Menang Taruhan!"
+			msg := fmt.Sprintf("Jackpot! Kamu menang %d koin dari taruhan!", amount)
+			if challenge.Mode == "2v2" {
+				title = "Menang Taruhan 2v2!"
+				msg = fmt.Sprintf("Tim Menang! Kamu dapat %d koin!", amount)
 			}
+			SendNotification(uid, "success", title, msg, "/shop")
 		}
-		*/
 	}
 
 	// Simpan Perubahan Challenge
 	config.DB.Save(&challenge)
 
+	if challenge.Mode == BattleRoyaleMode {
+		AwardBattleRoyaleRewards(challenge)
+	}
+
+	// Klasemen akhir untuk pemain & penonton lobby
+	BroadcastFinalStandings(challenge)
+
+	// Match turnamen: catat hasil & lanjutkan bracket
+	AdvanceTournamentMatch(challenge)
+
+	challengeKey := "challenge:" + strconv.Itoa(int(challenge.ID))
+	modeAttrs := map[string]interface{}{"mode": challenge.Mode}
+	for _, p := range challenge.Participants {
+		if p.Status == "accepted" {
+			FireAchievementEvent(p.UserID, AchievementChallengePlayed)
+			TrackMissionEvent(p.UserID, MissionEvent{Type: MissionChallengePlayed, Key: challengeKey + ":played", Value: 1, Attrs: modeAttrs})
+		}
+	}
+	for _, uid := range winnerIDs {
+		FireAchievementEvent(uid, AchievementChallengeWon)
+		TrackMissionEvent(uid, MissionEvent{Type: MissionChallengeWon, Key: challengeKey + ":won", Value: 1, Attrs: modeAttrs})
+	}
+
 	// Broadcast Notif Umum ke Semua Peserta
 	for _, p := range challenge.Participants {
 		// Hindari spam notif jika pemenang sudah dapat notif khusus di atas
@@ -462,36 +269,8 @@ func DetermineWinner(challengeID uint) {
 	}
 }
 
+// CheckAndApplyStreak dipertahankan untuk pemanggil lama. Streak kini dihitung dari StreakLog
+// lewat RecordActivity supaya Streak Freeze & Streak Repair ikut diperhitungkan.
 func CheckAndApplyStreak(userID uint) {
-	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
-		return
-	}
-
-	// Dapatkan Waktu Sekarang (Jakarta)
-	now := GetJakartaTime()
-	today := StripTime(now)
-
-	// Cek kapan terakhir update streak
-	var lastStreakDate time.Time
-	if user.LastStreakUpdate != nil {
-		// Konversi ke Jakarta Time untuk perbandingan
-		lastStreakDate = StripTime(*user.LastStreakUpdate)
-	}
-
-	// LOGIKA DUOLINGO
-	if user.LastStreakUpdate != nil && lastStreakDate.Equal(today) {
-		// KASUS 1: Sudah mengerjakan hari ini -> Jangan update streak, cukup update LastActivity
-		// (No Op)
-	} else if user.LastStreakUpdate != nil && lastStreakDate.AddDate(0, 0, 1).Equal(today) {
-		// KASUS 2: Terakhir mengerjakan kemarin -> Streak + 1
-		user.StreakCount++
-	} else {
-		// KASUS 3: Terlewat satu hari atau lebih, atau Baru Main -> Reset jadi 1
-		user.StreakCount = 1
-	}
-
-	// Simpan waktu update streak sekarang
-	user.LastStreakUpdate = &now
-	config.DB.Save(&user)
-}
\ No newline at end of file
+	RecordActivity(userID)
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+)
+
+var (
+	ErrGhostNotEnabled      = errors.New("challenge is not a ghost race")
+	ErrGhostNotInChallenge  = errors.New("you are not in this challenge")
+	ErrGhostNotStarted      = errors.New("run has not started")
+	ErrGhostFinished        = errors.New("already played")
+	ErrGhostAlreadyAnswered = errors.New("question already answered")
+	ErrGhostWrongQuiz       = errors.New("question is not part of this challenge")
+)
+
+// GhostTimeline adalah rekaman jawaban satu peserta yang sudah selesai main
+type GhostTimeline struct {
+	UserID    uint                            `json:"user_id"`
+	Username  string                          `json:"username"`
+	Name      string                          `json:"name"`
+	Score     int                             `json:"score"`
+	TimeTaken int                             `json:"time_taken"`
+	Entries   []models.ChallengeTimelineEntry `json:"entries"`
+}
+
+func ghostParticipant(challengeID uint, userID uint) (models.Challenge, models.ChallengeParticipant, error) {
+	var challenge models.Challenge
+	if err := config.DB.First(&challenge, challengeID).Error; err != nil {
+		return challenge, models.ChallengeParticipant{}, err
+	}
+	if !challenge.Ghost {
+		return challenge, models.ChallengeParticipant{}, ErrGhostNotEnabled
+	}
+
+	var participant models.ChallengeParticipant
+	if err := config.DB.Where("challenge_id = ? AND user_id = ? AND status = ?", challengeID, userID, "accepted").
+		First(&participant).Error; err != nil {
+		return challenge, participant, ErrGhostNotInChallenge
+	}
+	return challenge, participant, nil
+}
+
+// StartGhostRun menandai waktu mulai peserta. Idempotent: memanggil ulang tidak mereset waktu.
+func StartGhostRun(challengeID uint, userID uint) (models.ChallengeParticipant, error) {
+	challenge, participant, err := ghostParticipant(challengeID, userID)
+	if err != nil {
+		return participant, err
+	}
+	if participant.IsFinished {
+		return participant, ErrGhostFinished
+	}
+	if challenge.Status != "pending" && challenge.Status != "active" {
+		return participant, ErrGhostFinished
+	}
+
+	if participant.StartedAt == nil {
+		now := time.Now()
+		config.DB.Model(&models.ChallengeParticipant{}).
+			Where("id = ? AND started_at IS NULL", participant.ID).
+			Update("started_at", now)
+		config.DB.First(&participant, participant.ID)
+	}
+	return participant, nil
+}
+
+// RecordGhostAnswer menilai jawaban di server dan menambahkannya ke timeline peserta
+func RecordGhostAnswer(challengeID uint, userID uint, questionID uint, answer string) (models.ChallengeTimelineEntry, error) {
+	var entry models.ChallengeTimelineEntry
+
+	challenge, participant, err := ghostParticipant(challengeID, userID)
+	if err != nil {
+		return entry, err
+	}
+	if participant.IsFinished {
+		return entry, ErrGhostFinished
+	}
+	if participant.StartedAt == nil {
+		return entry, ErrGhostNotStarted
+	}
+
+	var question models.Question
+	if err := config.DB.First(&question, questionID).Error; err != nil {
+		return entry, err
+	}
+	if challenge.QuizID != nil && question.QuizID != *challenge.QuizID {
+		return entry, ErrGhostWrongQuiz
+	}
+
+	var answered int64
+	config.DB.Model(&models.ChallengeTimelineEntry{}).Where("participant_id = ?", participant.ID).Count(&answered)
+
+	entry = models.ChallengeTimelineEntry{
+		ChallengeID:   challengeID,
+		ParticipantID: participant.ID,
+		UserID:        userID,
+		QuestionID:    questionID,
+		Position:      int(answered) + 1,
+		ElapsedMs:     int(time.Since(*participant.StartedAt).Milliseconds()),
+		Correct:       IsAnswerCorrect(question, answer),
+		Answer:        answer,
+	}
+	if err := config.DB.Create(&entry).Error; err != nil {
+		// Unique index (peserta, soal) menolak jawaban ganda
+		return entry, ErrGhostAlreadyAnswered
+	}
+	return entry, nil
+}
+
+// GetGhostTimelines mengembalikan timeline peserta lain yang sudah selesai main
+func GetGhostTimelines(challengeID uint, excludeUserID uint) []GhostTimeline {
+	var participants []models.ChallengeParticipant
+	config.DB.Preload("User").
+		Where("challenge_id = ? AND user_id <> ? AND is_finished = ? AND forfeited = ?", challengeID, excludeUserID, true, false).
+		Find(&participants)
+
+	var timelines []GhostTimeline
+	for _, p := range participants {
+		var entries []models.ChallengeTimelineEntry
+		config.DB.Where("participant_id = ?", p.ID).Order("position ASC").Find(&entries)
+		if len(entries) == 0 {
+			continue
+		}
+		timelines = append(timelines, GhostTimeline{
+			UserID:    p.UserID,
+			Username:  p.User.Username,
+			Name:      p.User.Name,
+			Score:     p.Score,
+			TimeTaken: p.TimeTaken,
+			Entries:   entries,
+		})
+	}
+
+	sort.Slice(timelines, func(i, j int) bool { return timelines[i].Score > timelines[j].Score })
+	return timelines
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Batas gifting (anti-abuse multi akun)
+const (
+	GiftMaxCoins          = 200 // Maks koin per kiriman
+	GiftDailyCount        = 5   // Maks kiriman per hari per pengirim
+	GiftDailyCoins        = 500 // Maks total koin (termasuk harga item) dikirim per hari
+	GiftDailyReceiveCoins = 1000
+	GiftMinAccountAge     = 7 * 24 * time.Hour // Umur akun pengirim & penerima
+	GiftMinLevel          = 3                  // Level minimal pengirim
+	GiftMinFriendshipAge  = 24 * time.Hour     // Umur pertemanan (sejak diterima)
+	giftMessageMaxLength  = 200
+)
+
+var (
+	ErrGiftSelf              = errors.New("cannot send a gift to yourself")
+	ErrGiftNotFriends        = errors.New("gifts can only be sent to accepted friends")
+	ErrGiftFriendshipTooNew  = errors.New("friendship is too new for gifting")
+	ErrGiftAccountTooNew     = errors.New("account is too new for gifting")
+	ErrGiftLevelTooLow       = errors.New("sender level is too low for gifting")
+	ErrGiftInvalidAmount     = errors.New("invalid gift amount")
+	ErrGiftDailyLimit        = errors.New("daily gift limit reached")
+	ErrGiftReceiverLimit     = errors.New("receiver daily gift limit reached")
+	ErrGiftItemNotAvailable  = errors.New("item is not available in the shop")
+	ErrGiftMessageTooLong    = errors.New("gift message is too long")
+	ErrGiftReceiverNotActive = errors.New("receiver cannot receive gifts")
+)
+
+// GiftInput adalah isi kiriman hadiah
+type GiftInput struct {
+	Type     string // coins, item
+	Amount   int
+	ItemID   uint
+	Quantity int
+	Message  string
+}
+
+// GiftLimits adalah sisa kuota gifting pengirim hari ini
+type GiftLimits struct {
+	GiftsLeft int `json:"gifts_left"`
+	CoinsLeft int `json:"coins_left"`
+	MaxCoins  int `json:"max_coins_per_gift"`
+}
+
+// GetGiftLimits menghitung sisa kuota gifting user hari ini (WIB)
+func GetGiftLimits(tx *gorm.DB, userID uint) GiftLimits {
+	today := StripTime(GetJakartaTime())
+
+	var sent struct {
+		Count int
+		Total int
+	}
+	tx.Model(&models.Gift{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total").
+		Where("sender_id = ? AND created_at >= ?", userID, today).
+		Scan(&sent)
+
+	return GiftLimits{
+		GiftsLeft: max(0, GiftDailyCount-sent.Count),
+		CoinsLeft: max(0, GiftDailyCoins-sent.Total),
+		MaxCoins:  GiftMaxCoins,
+	}
+}
+
+// SendGift mengirim koin atau item toko ke teman. Semua cek batas, pemotongan koin,
+// pemberian item dan catatan gift terjadi dalam satu transaksi.
+func SendGift(senderID uint, receiverID uint, input GiftInput) (models.Gift, error) {
+	gift := models.Gift{SenderID: senderID, ReceiverID: receiverID, Type: input.Type}
+
+	if senderID == receiverID {
+		return gift, ErrGiftSelf
+	}
+	message := strings.TrimSpace(input.Message)
+	if utf8.RuneCountInString(message) > giftMessageMaxLength {
+		return gift, ErrGiftMessageTooLong
+	}
+	gift.Message, _ = FilterProfanity(message)
+
+	if !AreFriends(senderID, receiverID) {
+		return gift, ErrGiftNotFriends
+	}
+	var friendship models.Friendship
+	config.DB.Where("status = ? AND ((user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?))",
+		"accepted", senderID, receiverID, receiverID, senderID).First(&friendship)
+	if time.Since(friendship.UpdatedAt) < GiftMinFriendshipAge {
+		return gift, ErrGiftFriendshipTooNew
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Row pengirim & penerima dikunci (urut ID supaya tidak deadlock) agar kuota harian
+		// kirim maupun terima tidak bisa ditembus request paralel
+		var users []models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{senderID, receiverID}).
+			Order("id").Find(&users).Error; err != nil {
+			return err
+		}
+		var sender, receiver models.User
+		for _, u := range users {
+			if u.ID == senderID {
+				sender = u
+			} else {
+				receiver = u
+			}
+		}
+		if sender.ID == 0 || receiver.ID == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if receiver.IsBanned {
+			return ErrGiftReceiverNotActive
+		}
+		if time.Since(sender.CreatedAt) < GiftMinAccountAge || time.Since(receiver.CreatedAt) < GiftMinAccountAge {
+			return ErrGiftAccountTooNew
+		}
+		if sender.Level < GiftMinLevel {
+			return ErrGiftLevelTooLow
+		}
+
+		switch input.Type {
+		case "coins":
+			if input.Amount < 1 || input.Amount > GiftMaxCoins {
+				return ErrGiftInvalidAmount
+			}
+			gift.Amount = input.Amount
+		case "item":
+			// Harga mengikuti toko (diskon & rotasi), syarat level / achievement dicek untuk penerima
+			view, err := QuoteShopItem(tx, receiverID, input.ItemID)
+			if errors.Is(err, ErrShopItemUnavailable) {
+				return ErrGiftItemNotAvailable
+			}
+			if err != nil {
+				return err
+			}
+			item := view.Item
+			qty := 1
+			if item.Consumable && input.Quantity > 1 {
+				qty = input.Quantity
+			}
+			gift.ItemID = &item.ID
+			gift.Quantity = qty
+			gift.Amount = view.FinalPrice * qty
+			if err := GrantUserItem(tx, receiverID, item, qty); err != nil {
+				return err
+			}
+		default:
+			return ErrGiftInvalidAmount
+		}
+
+		limits := GetGiftLimits(tx, senderID)
+		if limits.GiftsLeft == 0 || gift.Amount > limits.CoinsLeft {
+			return ErrGiftDailyLimit
+		}
+		if input.Type == "coins" {
+			var received int
+			tx.Model(&models.Gift{}).Select("COALESCE(SUM(amount), 0)").
+				Where("receiver_id = ? AND type = ? AND created_at >= ?", receiverID, "coins", StripTime(GetJakartaTime())).
+				Scan(&received)
+			if received+gift.Amount > GiftDailyReceiveCoins {
+				return ErrGiftReceiverLimit
+			}
+		}
+
+		if err := tx.Create(&gift).Error; err != nil {
+			return err
+		}
+
+		// Koin langsung berpindah antar user; item dibayar pengirim ke toko
+		if gift.Type == "coins" {
+			return MoveCoins(tx, UserAccount(senderID), UserAccount(receiverID), gift.Amount,
+				"gift", LedgerRef{Type: "gift", ID: gift.ID})
+		}
+		return MoveCoins(tx, UserAccount(senderID), AccountShop, gift.Amount,
+			"gift_purchase", LedgerRef{Type: "gift", ID: gift.ID})
+	})
+	if err != nil {
+		return gift, err
+	}
+
+	config.DB.Preload("Item").Preload("Sender").First(&gift, gift.ID)
+
+	what := fmt.Sprintf("%d koin 🪙", gift.Amount)
+	if gift.Item != nil {
+		what = gift.Item.Name
+		if gift.Quantity > 1 {
+			what = fmt.Sprintf("%dx %s", gift.Quantity, gift.Item.Name)
+		}
+	}
+	msg := fmt.Sprintf("🎁 %s mengirim kamu %s", gift.Sender.Name, what)
+	if gift.Message != "" {
+		msg += ": \"" + gift.Message + "\""
+	}
+	SendNotification(receiverID, "success", "Kamu Dapat Hadiah!", msg, "/gifts")
+	return gift, nil
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/ROFL1ST/quizzes-backend/models"
+)
+
+// IsAnswerCorrect menilai satu jawaban sesuai tipe soal
+func IsAnswerCorrect(q models.Question, answer string) bool {
+	switch q.Type {
+	case "short_answer":
+		// Case Insensitive & Trim Space
+		return strings.EqualFold(strings.TrimSpace(answer), strings.TrimSpace(q.CorrectAnswer))
+
+	case "multi_select":
+		var userAns []string
+		var correctAns []string
+
+		err1 := json.Unmarshal([]byte(answer), &userAns)
+		err2 := json.Unmarshal([]byte(q.CorrectAnswer), &correctAns)
+		if err1 != nil || err2 != nil || len(userAns) != len(correctAns) {
+			return false
+		}
+
+		sort.Strings(userAns)
+		sort.Strings(correctAns)
+		for i := range userAns {
+			if userAns[i] != correctAns[i] {
+				return false
+			}
+		}
+		return true
+
+	default:
+		// boolean, mcq
+		return answer == q.CorrectAnswer
+	}
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"errors"
+
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tipe item consumable yang punya efek di server
+const (
+	ItemStreakFreeze = "streak_freeze"
+	ItemStreakRepair = "streak_repair"
+)
+
+var (
+	ErrItemOwned     = errors.New("item already owned")
+	ErrItemStackFull = errors.New("item stack is full")
+	ErrItemNotOwned  = errors.New("not enough items")
+)
+
+// GrantUserItem memberi item ke user. Item biasa hanya bisa dimiliki sekali (qty diabaikan),
+// item consumable ditambah qty sampai MaxStack.
+func GrantUserItem(tx *gorm.DB, userID uint, item models.Item, qty int) error {
+	userItem := models.UserItem{UserID: userID, ItemID: item.ID, Quantity: 1}
+
+	if !item.Consumable {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&userItem)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrItemOwned
+		}
+		return nil
+	}
+
+	if qty < 1 || (item.MaxStack > 0 && qty > item.MaxStack) {
+		return ErrItemStackFull
+	}
+	userItem.Quantity = qty
+
+	onConflict := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "item_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"quantity": gorm.Expr("user_items.quantity + ?", qty)}),
+	}
+	if item.MaxStack > 0 {
+		onConflict.Where = clause.Where{Exprs: []clause.Expression{gorm.Expr("user_items.quantity + ? <= ?", qty, item.MaxStack)}}
+	}
+
+	res := tx.Clauses(onConflict).Create(&userItem)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrItemStackFull
+	}
+	return nil
+}
+
+// ConsumeUserItem memakai qty item consumable bertipe itemType secara atomik
+func ConsumeUserItem(tx *gorm.DB, userID uint, itemType string, qty int) error {
+	var userItem models.UserItem
+	err := tx.Joins("JOIN items ON items.id = user_items.item_id").
+		Where("user_items.user_id = ? AND items.type = ? AND items.consumable = ? AND user_items.quantity >= ?", userID, itemType, true, qty).
+		Order("user_items.quantity DESC").
+		First(&userItem).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrItemNotOwned
+	}
+	if err != nil {
+		return err
+	}
+
+	res := tx.Model(&models.UserItem{}).
+		Where("user_id = ? AND item_id = ? AND quantity >= ?", userID, userItem.ItemID, qty).
+		UpdateColumn("quantity", gorm.Expr("quantity - ?", qty))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrItemNotOwned
+	}
+	return nil
+}
+
+// CountUserItems menghitung sisa stok item consumable bertipe itemType
+func CountUserItems(tx *gorm.DB, userID uint, itemType string) int {
+	var total int
+	tx.Model(&models.UserItem{}).
+		Select("COALESCE(SUM(user_items.quantity), 0)").
+		Joins("JOIN items ON items.id = user_items.item_id").
+		Where("user_items.user_id = ? AND items.type = ? AND items.consumable = ?", userID, itemType, true).
+		Scan(&total)
+	return total
+}
@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	leagueJobInterval  = time.Minute
+	LeagueCohortSize   = 30
+	LeaguePromoteCount = 5 // Top N naik tier
+	LeagueDemoteCount  = 5 // Bottom N turun tier
+)
+
+var errLeagueAlreadyJoined = errors.New("already joined this week's league")
+
+// Nama tier liga, index = models.LeagueMember.Tier
+var LeagueTierNames = []string{"Bronze", "Silver", "Gold", "Platinum", "Diamond"}
+
+// Hadiah koin peringkat 1-3, dikali (tier + 1)
+var leagueRankRewards = []int{50, 30, 20}
+
+// StartLeagueJob menutup liga minggu lalu (promosi, degradasi & hadiah) setelah Senin 00:00 WIB
+func StartLeagueJob() {
+	go func() {
+		ticker := time.NewTicker(leagueJobInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			RolloverLeagues()
+		}
+	}()
+}
+
+// RolloverLeagues memfinalisasi semua cohort dari minggu-minggu sebelumnya
+func RolloverLeagues() {
+	week := WeekStart(GetJakartaTime())
+
+	var cohortIDs []uint
+	config.DB.Model(&models.LeagueCohort{}).Where("finalized_at IS NULL AND week_start < ?", week).Pluck("id", &cohortIDs)
+	for _, id := range cohortIDs {
+		finalizeLeagueCohort(id)
+	}
+}
+
+// LeagueTierName mengembalikan nama tier liga
+func LeagueTierName(tier int) string {
+	if tier < 0 || tier >= len(LeagueTierNames) {
+		return LeagueTierNames[0]
+	}
+	return LeagueTierNames[tier]
+}
+
+// LeagueMovement menghitung jumlah user yang naik & turun di cohort berukuran size.
+// Cohort kecil dibagi per tiga supaya zona naik dan turun tidak bertabrakan.
+func LeagueMovement(tier int, size int) (int, int) {
+	promote := min(LeaguePromoteCount, (size+2)/3)
+	demote := min(LeagueDemoteCount, size/3)
+	if tier >= len(LeagueTierNames)-1 {
+		promote = 0
+	}
+	if tier <= 0 {
+		demote = 0
+	}
+	return promote, demote
+}
+
+// AddLeagueXP mencatat XP minggu ini. User otomatis masuk cohort saat pertama kali dapat XP di minggu itu.
+func AddLeagueXP(userID uint, amount int) {
+	if amount <= 0 {
+		return
+	}
+	week := WeekStart(GetJakartaTime())
+
+	for attempt := 0; attempt < 2; attempt++ {
+		res := config.DB.Model(&models.LeagueMember{}).
+			Where("user_id = ? AND week_start = ?", userID, week).
+			UpdateColumns(map[string]interface{}{"weekly_xp": gorm.Expr("weekly_xp + ?", amount), "updated_at": time.Now()})
+		if res.Error != nil || res.RowsAffected > 0 {
+			return
+		}
+		if err := joinLeague(userID, week); err != nil {
+			return
+		}
+	}
+}
+
+// joinLeague memasukkan user ke cohort yang masih punya slot di tier-nya, atau membuat cohort baru
+func joinLeague(userID uint, week time.Time) error {
+	tier := NextLeagueTier(userID, week)
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var cohort models.LeagueCohort
+		err := tx.Where("week_start = ? AND tier = ? AND member_count < ?", week, tier, LeagueCohortSize).
+			Order("id ASC").First(&cohort).Error
+
+		reserved := false
+		if err == nil {
+			// Reservasi slot atomik, cohort bisa saja penuh duluan oleh request lain
+			res := tx.Model(&models.LeagueCohort{}).
+				Where("id = ? AND member_count < ?", cohort.ID, LeagueCohortSize).
+				UpdateColumn("member_count", gorm.Expr("member_count + 1"))
+			if res.Error != nil {
+				return res.Error
+			}
+			reserved = res.RowsAffected > 0
+		}
+		if !reserved {
+			cohort = models.LeagueCohort{WeekStart: week, Tier: tier, MemberCount: 1}
+			if err := tx.Create(&cohort).Error; err != nil {
+				return err
+			}
+		}
+
+		member := models.LeagueMember{UserID: userID, WeekStart: week, CohortID: cohort.ID, Tier: tier}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&member)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// Sudah masuk lewat request lain, slot yang direservasi dibatalkan
+			return errLeagueAlreadyJoined
+		}
+		return nil
+	})
+	if errors.Is(err, errLeagueAlreadyJoined) {
+		return nil
+	}
+	return err
+}
+
+// NextLeagueTier menentukan tier user minggu ini dari hasil liga terakhirnya
+func NextLeagueTier(userID uint, week time.Time) int {
+	var last models.LeagueMember
+	if err := config.DB.Where("user_id = ? AND week_start < ?", userID, week).Order("week_start DESC").First(&last).Error; err != nil {
+		return 0
+	}
+
+	// Job rollover belum jalan: finalisasi dulu cohort lama supaya hasilnya ada
+	if last.Result == "" {
+		finalizeLeagueCohort(last.CohortID)
+		config.DB.First(&last, last.ID)
+	}
+
+	tier := last.Tier
+	switch last.Result {
+	case "promoted":
+		tier++
+	case "demoted":
+		tier--
+	}
+	return max(0, min(tier, len(LeagueTierNames)-1))
+}
+
+// finalizeLeagueCohort menetapkan peringkat akhir, hasil promosi/degradasi dan hadiah koin. Idempoten.
+func finalizeLeagueCohort(cohortID uint) {
+	var notices []tournamentNotice
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Klaim finalisasi: hanya satu proses yang bisa menutup cohort
+		res := tx.Model(&models.LeagueCohort{}).
+			Where("id = ? AND finalized_at IS NULL", cohortID).
+			Update("finalized_at", time.Now())
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+
+		var cohort models.LeagueCohort
+		if err := tx.First(&cohort, cohortID).Error; err != nil {
+			return err
+		}
+
+		// Seri XP: yang lebih dulu mencapai XP tersebut menang
+		var members []models.LeagueMember
+		tx.Where("cohort_id = ?", cohortID).Order("weekly_xp DESC, updated_at ASC").Find(&members)
+
+		promote, demote := LeagueMovement(cohort.Tier, len(members))
+		tierName := LeagueTierName(cohort.Tier)
+
+		for i, m := range members {
+			rank := i + 1
+			result := "stayed"
+			if rank <= promote {
+				result = "promoted"
+			} else if rank > len(members)-demote {
+				result = "demoted"
+			}
+
+			reward := 0
+			if rank <= len(leagueRankRewards) {
+				reward = leagueRankRewards[rank-1] * (cohort.Tier + 1)
+			}
+
+			if err := tx.Model(&models.LeagueMember{}).Where("id = ?", m.ID).
+				Updates(map[string]interface{}{"rank": rank, "result": result, "reward": reward}).Error; err != nil {
+				return err
+			}
+			if reward > 0 {
+				if err := MoveCoins(tx, AccountRewards, UserAccount(m.UserID), reward,
+					"league_reward", LedgerRef{Type: "league_member", ID: m.ID}); err != nil {
+					return err
+				}
+			}
+
+			notice := tournamentNotice{UserID: m.UserID, Title: "Liga Mingguan Selesai", Link: "/leagues"}
+			switch result {
+			case "promoted":
+				notice.Title = "Naik Liga!"
+				notice.Message = fmt.Sprintf("🚀 Peringkat #%d di liga %s. Kamu naik ke liga %s!", rank, tierName, LeagueTierName(cohort.Tier+1))
+			case "demoted":
+				notice.Title = "Turun Liga"
+				notice.Message = fmt.Sprintf("Peringkat #%d di liga %s. Kamu turun ke liga %s, semangat minggu ini!", rank, tierName, LeagueTierName(cohort.Tier-1))
+			default:
+				notice.Message = fmt.Sprintf("Peringkat #%d di liga %s. Kamu bertahan di liga %s.", rank, tierName, tierName)
+			}
+			if reward > 0 {
+				notice.Message += fmt.Sprintf(" Hadiah: %d koin 🪙", reward)
+			}
+			notices = append(notices, notice)
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	for _, n := range notices {
+		SendNotification(n.UserID, "info", n.Title, n.Message, n.Link)
+	}
+}
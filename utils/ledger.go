@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+)
+
+// LedgerAccount adalah akun di ledger koin. Akun user mengubah kolom users.coins,
+// akun lain (escrow, turnamen, sistem) hanya ada di ledger.
+type LedgerAccount struct {
+	Name   string
+	UserID uint
+}
+
+var (
+	AccountRewards        = LedgerAccount{Name: "system:rewards"}         // Sumber hadiah (login, misi, battle royale, prize pool admin)
+	AccountShop           = LedgerAccount{Name: "system:shop"}            // Tujuan pembelian item
+	AccountHouse          = LedgerAccount{Name: "system:house"}           // Potongan bandar taruhan
+	AccountOpeningBalance = LedgerAccount{Name: "system:opening_balance"} // Saldo sebelum ledger ada
+)
+
+func UserAccount(userID uint) LedgerAccount {
+	return LedgerAccount{Name: fmt.Sprintf("user:%d", userID), UserID: userID}
+}
+
+func EscrowAccount(challengeID uint) LedgerAccount {
+	return LedgerAccount{Name: fmt.Sprintf("escrow:challenge:%d", challengeID)}
+}
+
+func TournamentAccount(tournamentID uint) LedgerAccount {
+	return LedgerAccount{Name: fmt.Sprintf("tournament:%d", tournamentID)}
+}
+
+// LedgerRef menunjuk objek penyebab transaksi (challenge, item, mission, ...)
+type LedgerRef struct {
+	Type string
+	ID   uint
+}
+
+func newLedgerTxnID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// MoveCoins memindahkan koin antar akun dalam transaksi tx. Saldo user diubah secara
+// atomik (debit gagal dengan ErrInsufficientCoins jika saldo kurang), lalu dua entri
+// ledger ditulis. Selalu panggil di dalam transaksi yang sama dengan perubahan terkait.
+func MoveCoins(tx *gorm.DB, from LedgerAccount, to LedgerAccount, amount int, reason string, ref LedgerRef) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	if from.UserID != 0 {
+		res := tx.Model(&models.User{}).
+			Where("id = ? AND coins >= ?", from.UserID, amount).
+			UpdateColumn("coins", gorm.Expr("coins - ?", amount))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInsufficientCoins
+		}
+	}
+	if to.UserID != 0 {
+		if err := tx.Model(&models.User{}).Where("id = ?", to.UserID).
+			UpdateColumn("coins", gorm.Expr("coins + ?", amount)).Error; err != nil {
+			return err
+		}
+	}
+
+	return recordLedgerTransfer(tx, from, to, amount, reason, ref)
+}
+
+func recordLedgerTransfer(tx *gorm.DB, from LedgerAccount, to LedgerAccount, amount int, reason string, ref LedgerRef) error {
+	if amount == 0 {
+		return nil
+	}
+	txnID := newLedgerTxnID()
+	entries := []models.CoinTransaction{
+		ledgerEntry(tx, txnID, from, to, -amount, reason, ref),
+		ledgerEntry(tx, txnID, to, from, amount, reason, ref),
+	}
+	return tx.Create(&entries).Error
+}
+
+func ledgerEntry(tx *gorm.DB, txnID string, account LedgerAccount, counterparty LedgerAccount, amount int, reason string, ref LedgerRef) models.CoinTransaction {
+	entry := models.CoinTransaction{
+		TxnID:        txnID,
+		Account:      account.Name,
+		Amount:       amount,
+		Reason:       reason,
+		RefType:      ref.Type,
+		RefID:        ref.ID,
+		Counterparty: counterparty.Name,
+	}
+	if account.UserID != 0 {
+		userID := account.UserID
+		entry.UserID = &userID
+
+		var balance int
+		tx.Model(&models.User{}).Select("coins").Where("id = ?", userID).Scan(&balance)
+		entry.BalanceAfter = &balance
+	}
+	return entry
+}
+
+// SeedOpeningBalances mencatat saldo user yang sudah ada sebelum ledger dipakai.
+// Idempotent: user yang sudah punya entri ledger dilewati.
+func SeedOpeningBalances() {
+	var users []models.User
+	config.DB.Select("id", "coins").
+		Where("coins <> 0 AND id NOT IN (?)", config.DB.Model(&models.CoinTransaction{}).Select("user_id").Where("user_id IS NOT NULL")).
+		Find(&users)
+
+	for _, u := range users {
+		config.DB.Transaction(func(tx *gorm.DB) error {
+			return recordLedgerTransfer(tx, AccountOpeningBalance, UserAccount(u.ID), u.Coins, "opening_balance", LedgerRef{Type: "user", ID: u.ID})
+		})
+	}
+
+	// Prize pool turnamen yang masih berjalan juga dicatat sebagai saldo awal
+	var tournaments []models.Tournament
+	config.DB.Where("status IN ? AND prize_pool > 0", []string{"registration", "running"}).Find(&tournaments)
+	for _, t := range tournaments {
+		account := TournamentAccount(t.ID)
+		var count int64
+		config.DB.Model(&models.CoinTransaction{}).Where("account = ?", account.Name).Count(&count)
+		if count > 0 {
+			continue
+		}
+		config.DB.Transaction(func(tx *gorm.DB) error {
+			return recordLedgerTransfer(tx, AccountOpeningBalance, account, t.PrizePool, "opening_balance", LedgerRef{Type: "tournament", ID: t.ID})
+		})
+	}
+}
+
+// LedgerMismatch adalah user yang saldo users.coins berbeda dengan jumlah ledger-nya
+type LedgerMismatch struct {
+	UserID        uint   `json:"user_id"`
+	Username      string `json:"username"`
+	Coins         int    `json:"coins"`
+	LedgerBalance int    `json:"ledger_balance"`
+	Difference    int    `json:"difference"`
+}
+
+type LedgerAccountBalance struct {
+	Account string `json:"account"`
+	Balance int    `json:"balance"`
+}
+
+type LedgerReconciliation struct {
+	TotalEntries       int64                  `json:"total_entries"`
+	LedgerSum          int                    `json:"ledger_sum"`           // Harus 0 (double-entry)
+	UnbalancedTxns     []string               `json:"unbalanced_txns"`      // TxnID yang jumlahnya bukan 0
+	UserMismatches     []LedgerMismatch       `json:"user_mismatches"`      // users.coins != saldo ledger
+	SystemBalances     []LedgerAccountBalance `json:"system_balances"`      // Saldo akun system:*
+	OpenEscrowBalance  int                    `json:"open_escrow_balance"`  // Saldo ledger semua akun escrow
+	HeldStakeTotal     int                    `json:"held_stake_total"`     // Total stake berstatus held
+	OpenTournamentPool int                    `json:"open_tournament_pool"` // Saldo ledger semua akun turnamen
+	Healthy            bool                   `json:"healthy"`
+}
+
+// ReconcileLedger membandingkan saldo user dengan ledger dan mengecek keseimbangan ledger
+func ReconcileLedger() LedgerReconciliation {
+	var report LedgerReconciliation
+
+	config.DB.Model(&models.CoinTransaction{}).Count(&report.TotalEntries)
+	config.DB.Model(&models.CoinTransaction{}).Select("COALESCE(SUM(amount), 0)").Scan(&report.LedgerSum)
+
+	config.DB.Model(&models.CoinTransaction{}).
+		Select("txn_id").
+		Group("txn_id").
+		Having("SUM(amount) <> 0").
+		Limit(100).
+		Scan(&report.UnbalancedTxns)
+
+	config.DB.Table("users").
+		Select("users.id AS user_id, users.username, users.coins, COALESCE(l.balance, 0) AS ledger_balance, users.coins - COALESCE(l.balance, 0) AS difference").
+		Joins("LEFT JOIN (SELECT user_id, SUM(amount) AS balance FROM coin_transactions WHERE user_id IS NOT NULL GROUP BY user_id) l ON l.user_id = users.id").
+		Where("users.deleted_at IS NULL AND users.coins <> COALESCE(l.balance, 0)").
+		Order("users.id").
+		Limit(500).
+		Scan(&report.UserMismatches)
+
+	config.DB.Model(&models.CoinTransaction{}).
+		Select("account, SUM(amount) AS balance").
+		Where("account LIKE ?", "system:%").
+		Group("account").
+		Order("account").
+		Scan(&report.SystemBalances)
+
+	config.DB.Model(&models.CoinTransaction{}).Select("COALESCE(SUM(amount), 0)").
+		Where("account LIKE ?", "escrow:%").Scan(&report.OpenEscrowBalance)
+	config.DB.Model(&models.WagerStake{}).Select("COALESCE(SUM(amount), 0)").
+		Where("status = ?", "held").Scan(&report.HeldStakeTotal)
+	config.DB.Model(&models.CoinTransaction{}).Select("COALESCE(SUM(amount), 0)").
+		Where("account LIKE ?", "tournament:%").Scan(&report.OpenTournamentPool)
+
+	report.Healthy = report.LedgerSum == 0 && len(report.UnbalancedTxns) == 0 &&
+		len(report.UserMismatches) == 0 && report.OpenEscrowBalance == report.HeldStakeTotal
+	return report
+}
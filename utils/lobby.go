@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrLobbyNotOpen     = errors.New("lobby is not open")
+	ErrLobbyFull        = errors.New("lobby is full")
+	ErrLobbyFriendsOnly = errors.New("lobby is for host's friends only")
+	ErrLobbyKicked      = errors.New("you were kicked from this lobby")
+)
+
+// Tanpa 0/O dan 1/I supaya kode mudah dibaca & diketik
+const joinCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+const joinCodeLength = 6
+
+// MaxOpenLobbyPlayers: batas pemain lobby terbuka mode survival
+const MaxOpenLobbyPlayers = 16
+
+// GenerateJoinCode membuat kode join lobby yang belum dipakai
+func GenerateJoinCode() string {
+	for {
+		code := make([]byte, joinCodeLength)
+		for i := range code {
+			n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(joinCodeAlphabet))))
+			code[i] = joinCodeAlphabet[n.Int64()]
+		}
+
+		var count int64
+		config.DB.Model(&models.Challenge{}).Where("join_code = ?", string(code)).Count(&count)
+		if count == 0 {
+			return string(code)
+		}
+	}
+}
+
+// LobbyInviteLink adalah deep link frontend untuk join lobby
+func LobbyInviteLink(code string) string {
+	return fmt.Sprintf("%s/lobby/join/%s", os.Getenv("FRONTEND_URL"), code)
+}
+
+// LobbyCapacity: kapasitas default lobby terbuka per mode
+func LobbyCapacity(mode string, requested int) int {
+	switch mode {
+	case "1v1":
+		return 2
+	case "2v2":
+		return 4
+	}
+	if requested < 2 || requested > MaxOpenLobbyPlayers {
+		return 8
+	}
+	return requested
+}
+
+// AreFriends mengecek pertemanan (accepted) dua arah
+func AreFriends(userA uint, userB uint) bool {
+	var count int64
+	config.DB.Model(&models.Friendship{}).
+		Where("status = ? AND ((user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?))", "accepted", userA, userB, userB, userA).
+		Count(&count)
+	return count > 0
+}
+
+// JoinLobbyByCode memasukkan user ke lobby terbuka. Kursi dihitung dari peserta
+// pending/accepted; taruhan langsung ditahan di escrow seperti saat accept.
+func JoinLobbyByCode(code string, userID uint) (models.Challenge, error) {
+	var challenge models.Challenge
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("join_code = ?", code).First(&challenge).Error; err != nil {
+			return err
+		}
+
+		if challenge.Status != "pending" || challenge.Visibility == "private" {
+			return ErrLobbyNotOpen
+		}
+		if challenge.AcceptDeadline != nil && time.Now().After(*challenge.AcceptDeadline) {
+			return ErrLobbyNotOpen
+		}
+		if challenge.Visibility == "friends" && challenge.CreatorID != userID && !AreFriends(challenge.CreatorID, userID) {
+			return ErrLobbyFriendsOnly
+		}
+
+		var participants []models.ChallengeParticipant
+		tx.Where("challenge_id = ?", challenge.ID).Find(&participants)
+
+		var existing *models.ChallengeParticipant
+		seats := 0
+		teamCount := map[string]int{}
+		for i, p := range participants {
+			if p.UserID == userID {
+				existing = &participants[i]
+			}
+			if p.Status == "pending" || p.Status == "accepted" {
+				seats++
+				teamCount[p.Team]++
+			}
+		}
+
+		if existing != nil {
+			switch existing.Status {
+			case "accepted":
+				return nil
+			case "kicked":
+				return ErrLobbyKicked
+			case "pending":
+				// Sudah punya kursi (diundang / keluar lobby), cukup accept
+			default:
+				if challenge.MaxPlayers > 0 && seats >= challenge.MaxPlayers {
+					return ErrLobbyFull
+				}
+			}
+			if err := tx.Model(&models.ChallengeParticipant{}).Where("id = ?", existing.ID).
+				Updates(map[string]interface{}{"status": "accepted", "is_ready": false}).Error; err != nil {
+				return err
+			}
+			return HoldWagerStake(tx, challenge, userID)
+		}
+
+		if challenge.MaxPlayers > 0 && seats >= challenge.MaxPlayers {
+			return ErrLobbyFull
+		}
+
+		// 2v2: isi tim yang masih kurang
+		team := "solo"
+		if challenge.Mode == "2v2" {
+			team = "A"
+			if teamCount["A"] >= 2 {
+				team = "B"
+			}
+		}
+
+		participant := models.ChallengeParticipant{
+			ChallengeID: challenge.ID,
+			UserID:      userID,
+			Status:      "accepted",
+			Team:        team,
+		}
+		if err := tx.Create(&participant).Error; err != nil {
+			return err
+		}
+		return HoldWagerStake(tx, challenge, userID)
+	})
+
+	return challenge, err
+}
@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+)
+
+var (
+	ErrChatRateLimited  = errors.New("terlalu banyak pesan, tunggu sebentar")
+	ErrChatEmpty        = errors.New("pesan kosong")
+	ErrChatTooLong      = errors.New("pesan terlalu panjang")
+	ErrChatUnknownEmote = errors.New("unknown emote")
+	ErrChatNotInLobby   = errors.New("kamu tidak ada di lobby ini")
+)
+
+const (
+	MaxLobbyChatLength = 200
+	lobbyChatBurst     = 5                // Maksimal pesan per jendela waktu
+	lobbyChatWindow    = 10 * time.Second // Jendela rate limit per user
+)
+
+// LobbyEmotes adalah daftar emote yang boleh dikirim di lobby
+var LobbyEmotes = map[string]bool{
+	"gg":    true,
+	"wow":   true,
+	"haha":  true,
+	"clap":  true,
+	"fire":  true,
+	"think": true,
+}
+
+// Kata default yang disensor; admin bisa menambah lewat SystemConfig "chat_banned_words" (pisah koma)
+var defaultBannedWords = []string{
+	"anjing", "bangsat", "babi", "kontol", "memek", "goblok", "tolol", "bajingan", "jancok", "asu",
+	"fuck", "shit", "bitch", "bastard", "asshole",
+}
+
+var lobbyChatLimiter = struct {
+	sync.Mutex
+	Sent map[string][]time.Time
+}{Sent: make(map[string][]time.Time)}
+
+// allowLobbyChat: sliding window sederhana per challenge+user
+func allowLobbyChat(challengeID uint, userID uint) bool {
+	key := disconnectTimerKey(challengeID, userID)
+	now := time.Now()
+
+	lobbyChatLimiter.Lock()
+	defer lobbyChatLimiter.Unlock()
+
+	var recent []time.Time
+	for _, t := range lobbyChatLimiter.Sent[key] {
+		if now.Sub(t) < lobbyChatWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= lobbyChatBurst {
+		lobbyChatLimiter.Sent[key] = recent
+		return false
+	}
+	lobbyChatLimiter.Sent[key] = append(recent, now)
+	return true
+}
+
+func bannedChatWords() []string {
+	words := append([]string(nil), defaultBannedWords...)
+
+	var conf models.SystemConfig
+	config.DB.Where("key = ?", "chat_banned_words").Find(&conf)
+	for _, w := range strings.Split(conf.Value, ",") {
+		if w = strings.TrimSpace(strings.ToLower(w)); w != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// FilterProfanity menyensor kata kasar (case-insensitive, utuh per kata) dengan bintang
+func FilterProfanity(text string) (string, bool) {
+	filtered := false
+	for _, word := range bannedChatWords() {
+		re, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(word) + `\b`)
+		if err != nil {
+			continue
+		}
+		text = re.ReplaceAllStringFunc(text, func(m string) string {
+			filtered = true
+			return strings.Repeat("*", utf8.RuneCountInString(m))
+		})
+	}
+	return text, filtered
+}
+
+// canChatInLobby: hanya peserta accepted di lobby yang belum selesai
+func canChatInLobby(challengeID uint, userID uint) bool {
+	var count int64
+	config.DB.Model(&models.ChallengeParticipant{}).
+		Joins("JOIN challenges ON challenges.id = challenge_participants.challenge_id").
+		Where("challenge_participants.challenge_id = ? AND challenge_participants.user_id = ? AND challenge_participants.status = ?", challengeID, userID, "accepted").
+		Where("challenges.status IN ?", []string{"pending", "active"}).
+		Count(&count)
+	return count > 0
+}
+
+// SendLobbyChat menyimpan pesan chat lalu mengirimnya ke lobby sebagai event chat_message
+func SendLobbyChat(challengeID uint, userID uint, text string) (models.LobbyMessage, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return models.LobbyMessage{}, ErrChatEmpty
+	}
+	if utf8.RuneCountInString(text) > MaxLobbyChatLength {
+		return models.LobbyMessage{}, ErrChatTooLong
+	}
+
+	content, filtered := FilterProfanity(text)
+	return saveLobbyMessage(challengeID, userID, "chat", content, filtered)
+}
+
+// SendLobbyEmote menyimpan emote lalu mengirimnya ke lobby sebagai event emote
+func SendLobbyEmote(challengeID uint, userID uint, emote string) (models.LobbyMessage, error) {
+	if !LobbyEmotes[emote] {
+		return models.LobbyMessage{}, ErrChatUnknownEmote
+	}
+	return saveLobbyMessage(challengeID, userID, "emote", emote, false)
+}
+
+func saveLobbyMessage(challengeID uint, userID uint, kind string, content string, filtered bool) (models.LobbyMessage, error) {
+	if !canChatInLobby(challengeID, userID) {
+		return models.LobbyMessage{}, ErrChatNotInLobby
+	}
+	if !allowLobbyChat(challengeID, userID) {
+		return models.LobbyMessage{}, ErrChatRateLimited
+	}
+
+	msg := models.LobbyMessage{
+		ChallengeID: challengeID,
+		UserID:      userID,
+		Kind:        kind,
+		Content:     content,
+		Filtered:    filtered,
+	}
+	if err := config.DB.Create(&msg).Error; err != nil {
+		return msg, err
+	}
+	config.DB.First(&msg.User, userID)
+
+	if kind == "emote" {
+		BroadcastLobby(challengeID, "emote", map[string]interface{}{
+			"id":      msg.ID,
+			"user_id": userID,
+			"emote":   content,
+		})
+	} else {
+		BroadcastLobby(challengeID, "chat_message", LobbyMessagePayload(msg))
+	}
+	return msg, nil
+}
+
+// LobbyMessagePayload adalah format chat untuk event & riwayat chat
+func LobbyMessagePayload(msg models.LobbyMessage) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         msg.ID,
+		"user_id":    msg.UserID,
+		"username":   msg.User.Username,
+		"name":       msg.User.Name,
+		"kind":       msg.Kind,
+		"content":    msg.Content,
+		"created_at": msg.CreatedAt,
+	}
+}
+
+// PurgeEndedLobbyChats menghapus chat lobby yang challenge-nya sudah berakhir.
+// Pesan yang dilaporkan tetap disimpan untuk ditinjau admin.
+func PurgeEndedLobbyChats() {
+	config.DB.Unscoped().
+		Where("challenge_id NOT IN (?)", config.DB.Model(&models.Challenge{}).Select("id").Where("status IN ?", []string{"pending", "active"})).
+		Where("id NOT IN (?)", config.DB.Model(&models.Report{}).Select("target_id").Where("target_type = ?", "chat_message")).
+		Delete(&models.LobbyMessage{})
+
+	lobbyChatLimiter.Lock()
+	for key, sent := range lobbyChatLimiter.Sent {
+		if len(sent) == 0 || time.Since(sent[len(sent)-1]) > lobbyChatWindow {
+			delete(lobbyChatLimiter.Sent, key)
+		}
+	}
+	lobbyChatLimiter.Unlock()
+}
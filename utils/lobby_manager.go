@@ -6,69 +6,137 @@ import (
 	"sync"
 )
 
-type LobbyManagerStruct struct {
-	Clients map[uint]map[uint]chan string
-	Lock    sync.Mutex
-}
-
-var LobbyManager = LobbyManagerStruct{
-	Clients: make(map[uint]map[uint]chan string),
+// LobbyEvent adalah satu pesan lobby. Transport (SSE / WebSocket) yang
+// menentukan bagaimana event ini diformat saat dikirim ke client.
+type LobbyEvent struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
 }
 
-// BroadcastLobby mengirim pesan dengan format Standar SSE
+// SSE memformat event dengan format Standar SSE
 // Format:
 // event: nama_event
 // data: {json_payload}
 // <baris kosong>
+func (e LobbyEvent) SSE() string {
+	jsonPayload, err := json.Marshal(e.Data)
+	if err != nil {
+		fmt.Println("Error marshal payload:", err)
+		jsonPayload = []byte("{}")
+	}
+	return fmt.Sprintf("event: %s\ndata: %s\n\n", e.Type, string(jsonPayload))
+}
+
+type LobbyManagerStruct struct {
+	Clients    map[uint]map[uint]chan LobbyEvent
+	Spectators map[uint]map[uint]chan LobbyEvent // Penonton (read-only), terpisah dari pemain
+	Lock       sync.Mutex
+}
+
+var LobbyManager = LobbyManagerStruct{
+	Clients:    make(map[uint]map[uint]chan LobbyEvent),
+	Spectators: make(map[uint]map[uint]chan LobbyEvent),
+}
+
+// Event yang boleh diterima penonton. Event lain (misal balasan pribadi) hanya untuk pemain.
+var spectatorEvents = map[string]bool{
+	"player_update":       true,
+	"spectator_update":    true,
+	"start_countdown":     true,
+	"game_start":          true,
+	"opponent_progress":   true,
+	"opponent_answer":     true,
+	"round_result":        true,
+	"player_finished":     true,
+	"player_forfeit":      true,
+	"player_disconnected": true,
+	"player_reconnected":  true,
+	"final_standings":     true,
+	"challenge_cancelled": true,
+	"challenge_expired":   true,
+	"emote":               true,
+	"host_changed":        true,
+	"settings_updated":    true,
+	"chat_message":        true,
+	"player_eliminated":   true,
+	"round_survivors":     true,
+}
+
+// BroadcastLobby mengirim event ke semua client yang terhubung ke lobby,
+// baik lewat SSE maupun WebSocket. Penonton hanya menerima event di spectatorEvents.
 func BroadcastLobby(challengeID uint, msgType string, payload interface{}) {
 	LobbyManager.Lock.Lock()
 	defer LobbyManager.Lock.Unlock()
 
-	clients, ok := LobbyManager.Clients[challengeID]
-	if !ok {
-		return
+	event := LobbyEvent{Type: msgType, Data: payload}
+	for _, ch := range LobbyManager.Clients[challengeID] {
+		select {
+		case ch <- event:
+		default:
+		}
 	}
 
-	// 1. Marshal Payload jadi JSON string
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		fmt.Println("Error marshal payload:", err)
+	if !spectatorEvents[msgType] {
 		return
 	}
+	for _, ch := range LobbyManager.Spectators[challengeID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
 
-	// 2. Format Pesan SSE yang Benar
-	// "event: ... \n data: ... \n\n"
-	msgString := fmt.Sprintf("event: %s\ndata: %s\n\n", msgType, string(jsonPayload))
+// SendToLobbyClient mengirim event hanya ke satu user di lobby (misal balasan pong / error)
+func SendToLobbyClient(challengeID uint, userID uint, msgType string, payload interface{}) {
+	LobbyManager.Lock.Lock()
+	defer LobbyManager.Lock.Unlock()
 
-	// 3. Kirim ke semua client
-	for _, ch := range clients {
+	clients, ok := LobbyManager.Clients[challengeID]
+	if !ok {
+		return
+	}
+	if ch, exists := clients[userID]; exists {
 		select {
-		case ch <- msgString:
+		case ch <- LobbyEvent{Type: msgType, Data: payload}:
 		default:
 		}
 	}
 }
 
-func AddClientToLobby(challengeID uint, userID uint) chan string {
+// AddClientToLobby mendaftarkan koneksi baru. Jika user yang sama sudah punya
+// koneksi lain (tab lama / transport lain), koneksi lama ditutup.
+// Event awal dimasukkan ke buffer di bawah lock, jadi pemanggil tidak perlu mengirim ke
+// channel yang bisa saja sudah ditutup oleh koneksi pengganti.
+func AddClientToLobby(challengeID uint, userID uint, initial ...LobbyEvent) chan LobbyEvent {
 	LobbyManager.Lock.Lock()
 	defer LobbyManager.Lock.Unlock()
 
 	if _, ok := LobbyManager.Clients[challengeID]; !ok {
-		LobbyManager.Clients[challengeID] = make(map[uint]chan string)
+		LobbyManager.Clients[challengeID] = make(map[uint]chan LobbyEvent)
+	}
+
+	if old, exists := LobbyManager.Clients[challengeID][userID]; exists {
+		close(old)
 	}
 
 	// Buffer channel
-	msgChan := make(chan string, 10)
+	msgChan := make(chan LobbyEvent, 10)
+	for _, event := range initial {
+		msgChan <- event
+	}
 	LobbyManager.Clients[challengeID][userID] = msgChan
 	return msgChan
 }
 
-func RemoveClientFromLobby(challengeID uint, userID uint) {
+// RemoveClientFromLobby hanya menghapus jika channel masih milik koneksi ini,
+// supaya koneksi lama yang sudah digantikan tidak menghapus koneksi baru.
+func RemoveClientFromLobby(challengeID uint, userID uint, msgChan chan LobbyEvent) {
 	LobbyManager.Lock.Lock()
 	defer LobbyManager.Lock.Unlock()
 
 	if clients, ok := LobbyManager.Clients[challengeID]; ok {
-		if ch, exists := clients[userID]; exists {
+		if ch, exists := clients[userID]; exists && ch == msgChan {
 			close(ch)
 			delete(clients, userID)
 		}
@@ -76,4 +144,94 @@ func RemoveClientFromLobby(challengeID uint, userID uint) {
 			delete(LobbyManager.Clients, challengeID)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// KickLobbyClient mengirim event terakhir lalu menutup koneksi lobby user (misal dikick host)
+func KickLobbyClient(challengeID uint, userID uint, msgType string, payload interface{}) {
+	LobbyManager.Lock.Lock()
+	defer LobbyManager.Lock.Unlock()
+
+	clients, ok := LobbyManager.Clients[challengeID]
+	if !ok {
+		return
+	}
+	if ch, exists := clients[userID]; exists {
+		select {
+		case ch <- LobbyEvent{Type: msgType, Data: payload}:
+		default:
+		}
+		close(ch)
+		delete(clients, userID)
+	}
+	if len(clients) == 0 {
+		delete(LobbyManager.Clients, challengeID)
+	}
+}
+
+// IsInLobby mengecek apakah user sedang terhubung ke stream lobby
+func IsInLobby(challengeID uint, userID uint) bool {
+	LobbyManager.Lock.Lock()
+	defer LobbyManager.Lock.Unlock()
+
+	if clients, ok := LobbyManager.Clients[challengeID]; ok {
+		_, exists := clients[userID]
+		return exists
+	}
+	return false
+}
+
+// AddSpectatorToLobby mendaftarkan penonton. Koneksi lama user yang sama ditutup.
+func AddSpectatorToLobby(challengeID uint, userID uint, initial ...LobbyEvent) chan LobbyEvent {
+	LobbyManager.Lock.Lock()
+	defer LobbyManager.Lock.Unlock()
+
+	if _, ok := LobbyManager.Spectators[challengeID]; !ok {
+		LobbyManager.Spectators[challengeID] = make(map[uint]chan LobbyEvent)
+	}
+
+	if old, exists := LobbyManager.Spectators[challengeID][userID]; exists {
+		close(old)
+	}
+
+	msgChan := make(chan LobbyEvent, 10)
+	for _, event := range initial {
+		msgChan <- event
+	}
+	LobbyManager.Spectators[challengeID][userID] = msgChan
+	return msgChan
+}
+
+// RemoveSpectatorFromLobby menghapus penonton jika channel masih milik koneksi ini
+func RemoveSpectatorFromLobby(challengeID uint, userID uint, msgChan chan LobbyEvent) {
+	LobbyManager.Lock.Lock()
+	defer LobbyManager.Lock.Unlock()
+
+	if spectators, ok := LobbyManager.Spectators[challengeID]; ok {
+		if ch, exists := spectators[userID]; exists && ch == msgChan {
+			close(ch)
+			delete(spectators, userID)
+		}
+		if len(spectators) == 0 {
+			delete(LobbyManager.Spectators, challengeID)
+		}
+	}
+}
+
+// KickAllSpectators menutup semua koneksi penonton (misal host mematikan mode penonton)
+func KickAllSpectators(challengeID uint) {
+	LobbyManager.Lock.Lock()
+	defer LobbyManager.Lock.Unlock()
+
+	for _, ch := range LobbyManager.Spectators[challengeID] {
+		close(ch)
+	}
+	delete(LobbyManager.Spectators, challengeID)
+}
+
+// SpectatorCount menghitung jumlah penonton yang sedang terhubung
+func SpectatorCount(challengeID uint) int {
+	LobbyManager.Lock.Lock()
+	defer LobbyManager.Lock.Unlock()
+
+	return len(LobbyManager.Spectators[challengeID])
+}
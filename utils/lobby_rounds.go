@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+)
+
+// Jawaban per ronde selama match realtime. Satu ronde = satu soal (urutan soal tiap
+// pemain bisa acak). Ronde dianggap selesai saat semua pemain aktif sudah menjawab soal itu;
+// baru saat itu kunci jawaban boleh dikirim ke lobby.
+var roundAnswers = struct {
+	sync.Mutex
+	Rounds map[uint]map[uint]map[uint]bool
+}{Rounds: make(map[uint]map[uint]map[uint]bool)}
+
+// RecordRoundAnswer mencatat jawaban pemain untuk satu ronde. Return hasil ronde
+// (user -> benar/salah) dan true tepat satu kali, yaitu saat ronde tertutup.
+// recorded false berarti jawaban ditolak: pemain sudah menjawab atau ronde sudah ditutup.
+func RecordRoundAnswer(challengeID uint, questionID uint, userID uint, correct bool) (results map[uint]bool, closed bool, recorded bool) {
+	var participants []models.ChallengeParticipant
+	config.DB.Where("challenge_id = ? AND status = ? AND forfeited = ? AND eliminated = ?", challengeID, "accepted", false, false).Find(&participants)
+
+	roundAnswers.Lock()
+	defer roundAnswers.Unlock()
+
+	if _, ok := roundAnswers.Rounds[challengeID]; !ok {
+		roundAnswers.Rounds[challengeID] = make(map[uint]map[uint]bool)
+	}
+	round, ok := roundAnswers.Rounds[challengeID][questionID]
+	if !ok {
+		round = make(map[uint]bool)
+		roundAnswers.Rounds[challengeID][questionID] = round
+	}
+	if round == nil {
+		// Ronde sudah ditutup sebelumnya
+		return nil, false, false
+	}
+	if _, answered := round[userID]; answered {
+		return nil, false, false
+	}
+	round[userID] = correct
+
+	if !roundComplete(round, participants) {
+		return nil, false, true
+	}
+
+	// Tandai ronde tertutup supaya round_result tidak dikirim dua kali
+	roundAnswers.Rounds[challengeID][questionID] = nil
+	return round, true, true
+}
+
+// RecheckRoundAnswers menutup ronde yang tinggal menunggu pemain yang baru keluar
+// (kalah WO / tereliminasi). Tanpa ini ronde tidak pernah tertutup karena hanya
+// RecordRoundAnswer yang menutup ronde.
+func RecheckRoundAnswers(challengeID uint) {
+	var participants []models.ChallengeParticipant
+	config.DB.Where("challenge_id = ? AND status = ? AND forfeited = ? AND eliminated = ?", challengeID, "accepted", false, false).Find(&participants)
+
+	closed := map[uint]map[uint]bool{}
+	roundAnswers.Lock()
+	for questionID, round := range roundAnswers.Rounds[challengeID] {
+		// Ronde tanpa jawaban sama sekali belum dimulai siapa pun, biarkan
+		if round == nil || len(round) == 0 || !roundComplete(round, participants) {
+			continue
+		}
+		roundAnswers.Rounds[challengeID][questionID] = nil
+		closed[questionID] = round
+	}
+	roundAnswers.Unlock()
+
+	for questionID, results := range closed {
+		CloseLobbyRound(challengeID, questionID, results)
+	}
+}
+
+// CloseLobbyRound membuka kunci jawaban ronde ke lobby dan menjalankan eliminasi battle royale
+func CloseLobbyRound(challengeID uint, questionID uint, results map[uint]bool) {
+	var question models.Question
+	if err := config.DB.First(&question, questionID).Error; err != nil {
+		return
+	}
+	BroadcastLobby(challengeID, "round_result", map[string]interface{}{
+		"question_id":    question.ID,
+		"correct_answer": question.CorrectAnswer,
+		"results":        results,
+	})
+
+	var challenge models.Challenge
+	if err := config.DB.Select("id", "mode").First(&challenge, challengeID).Error; err == nil && challenge.Mode == BattleRoyaleMode {
+		ResolveBattleRoyaleRound(challengeID, questionID, results)
+	}
+}
+
+func roundComplete(round map[uint]bool, participants []models.ChallengeParticipant) bool {
+	for _, p := range participants {
+		if _, answered := round[p.UserID]; !answered {
+			return false
+		}
+	}
+	return true
+}
+
+// ClearRoundAnswers membuang catatan ronde setelah match selesai
+func ClearRoundAnswers(challengeID uint) {
+	roundAnswers.Lock()
+	defer roundAnswers.Unlock()
+
+	delete(roundAnswers.Rounds, challengeID)
+}
+
+// BroadcastFinalStandings mengirim klasemen akhir ke pemain & penonton lobby
+func BroadcastFinalStandings(challenge models.Challenge) {
+	var standings []map[string]interface{}
+
+	parts := append([]models.ChallengeParticipant(nil), challenge.Participants...)
+	sort.SliceStable(parts, func(i, j int) bool {
+		if parts[i].Placement != parts[j].Placement && parts[i].Placement > 0 && parts[j].Placement > 0 {
+			return parts[i].Placement < parts[j].Placement
+		}
+		if parts[i].Score != parts[j].Score {
+			return parts[i].Score > parts[j].Score
+		}
+		return parts[i].TimeTaken < parts[j].TimeTaken
+	})
+
+	for _, p := range parts {
+		if p.Status != "accepted" {
+			continue
+		}
+		standings = append(standings, map[string]interface{}{
+			"user_id":    p.UserID,
+			"name":       p.User.Name,
+			"team":       p.Team,
+			"score":      p.Score,
+			"time_taken": p.TimeTaken,
+			"forfeited":  p.Forfeited,
+			"placement":  p.Placement,
+		})
+	}
+
+	BroadcastLobby(challenge.ID, "final_standings", map[string]interface{}{
+		"winner_id":    challenge.WinnerID,
+		"winning_team": challenge.WinningTeam,
+		"standings":    standings,
+	})
+	ClearRoundAnswers(challenge.ID)
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+)
+
+// LogMatchEvent menyimpan event match realtime. Challenge async tidak dicatat
+// (ghost race punya timeline sendiri).
+func LogMatchEvent(challengeID uint, userID uint, eventType string, fields models.ChallengeMatchEvent) {
+	var challenge models.Challenge
+	if err := config.DB.Select("id", "is_realtime", "started_at").First(&challenge, challengeID).Error; err != nil {
+		return
+	}
+	if !challenge.IsRealtime {
+		return
+	}
+
+	// Satu jawaban per soal per pemain, jawaban ganda diabaikan
+	if eventType == "answer" && fields.QuestionID != nil && MatchAnswerLogged(challengeID, userID, *fields.QuestionID) {
+		return
+	}
+
+	fields.ChallengeID = challengeID
+	fields.UserID = userID
+	fields.Type = eventType
+	if challenge.StartedAt != nil {
+		if elapsed := time.Since(*challenge.StartedAt); elapsed > 0 {
+			fields.ElapsedMs = int(elapsed.Milliseconds())
+		}
+	}
+	config.DB.Create(&fields)
+}
+
+// GetMatchEvents mengembalikan log event match urut waktu
+func GetMatchEvents(challengeID uint) []models.ChallengeMatchEvent {
+	var events []models.ChallengeMatchEvent
+	config.DB.Where("challenge_id = ?", challengeID).Order("elapsed_ms ASC, id ASC").Find(&events)
+	return events
+}
+
+// MatchAnswerLogged: pemain sudah pernah menjawab soal ini di match (tahan restart server)
+func MatchAnswerLogged(challengeID uint, userID uint, questionID uint) bool {
+	var count int64
+	config.DB.Model(&models.ChallengeMatchEvent{}).
+		Where("challenge_id = ? AND user_id = ? AND type = ? AND question_id = ?", challengeID, userID, "answer", questionID).
+		Count(&count)
+	return count > 0
+}
@@ -0,0 +1,461 @@
+package utils
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+)
+
+// Ringkasan pasca-game: statistik per soal, pergantian pemimpin, MVP & comeback terbesar.
+// Sumber jawaban: log event (realtime) atau timeline ghost (async).
+
+type matchAnswer struct {
+	UserID     uint
+	QuestionID uint
+	Answer     string
+	Correct    bool
+	ElapsedMs  int
+	ResponseMs int
+}
+
+type RoundAnswer struct {
+	UserID     uint   `json:"user_id"`
+	Answer     string `json:"answer"`
+	Correct    bool   `json:"correct"`
+	ResponseMs int    `json:"response_ms"` // Waktu menjawab soal ini
+	ElapsedMs  int    `json:"elapsed_ms"`  // Waktu sejak mulai
+}
+
+type RoundSummary struct {
+	Round         int           `json:"round"`
+	QuestionID    uint          `json:"question_id"`
+	Question      string        `json:"question"`
+	CorrectAnswer string        `json:"correct_answer"`
+	CorrectCount  int           `json:"correct_count"`
+	FastestUserID *uint         `json:"fastest_user_id"` // Penjawab benar tercepat
+	Answers       []RoundAnswer `json:"answers"`
+}
+
+type PlayerSummary struct {
+	UserID        uint   `json:"user_id"`
+	Username      string `json:"username"`
+	Name          string `json:"name"`
+	Team          string `json:"team"`
+	Score         int    `json:"score"`
+	TimeTaken     int    `json:"time_taken"`
+	Forfeited     bool   `json:"forfeited"`
+	Answered      int    `json:"answered"`
+	Correct       int    `json:"correct"`
+	AvgResponseMs int    `json:"avg_response_ms"`
+}
+
+type LeadChange struct {
+	ElapsedMs  int    `json:"elapsed_ms"`
+	QuestionID uint   `json:"question_id"`
+	Leader     string `json:"leader"` // user_id atau tim (2v2)
+	Lead       int    `json:"lead"`   // Selisih jawaban benar setelah berganti
+}
+
+type Comeback struct {
+	Leader  string `json:"leader"`
+	Deficit int    `json:"deficit"` // Ketinggalan terbesar (jawaban benar) yang berhasil dibalik
+	AtMs    int    `json:"at_ms"`
+}
+
+type MatchSummary struct {
+	ChallengeID  uint            `json:"challenge_id"`
+	Mode         string          `json:"mode"`
+	Source       string          `json:"source"` // events, ghost, scores
+	WinnerID     *uint           `json:"winner_id"`
+	WinningTeam  string          `json:"winning_team"`
+	Players      []PlayerSummary `json:"players"`
+	Rounds       []RoundSummary  `json:"rounds"`
+	LeadChanges  []LeadChange    `json:"lead_changes"`
+	MVP          *PlayerSummary  `json:"mvp"`
+	BestComeback *Comeback       `json:"biggest_comeback"`
+}
+
+func collectMatchAnswers(challenge models.Challenge) ([]matchAnswer, string) {
+	var answers []matchAnswer
+
+	if challenge.IsRealtime {
+		for _, e := range GetMatchEvents(challenge.ID) {
+			if e.Type != "answer" || e.QuestionID == nil || e.Correct == nil {
+				continue
+			}
+			answers = append(answers, matchAnswer{UserID: e.UserID, QuestionID: *e.QuestionID, Answer: e.Answer, Correct: *e.Correct, ElapsedMs: e.ElapsedMs})
+		}
+		if len(answers) > 0 {
+			return withResponseTimes(answers), "events"
+		}
+	}
+
+	var entries []models.ChallengeTimelineEntry
+	config.DB.Where("challenge_id = ?", challenge.ID).Find(&entries)
+	for _, e := range entries {
+		answers = append(answers, matchAnswer{UserID: e.UserID, QuestionID: e.QuestionID, Answer: e.Answer, Correct: e.Correct, ElapsedMs: e.ElapsedMs})
+	}
+	if len(answers) > 0 {
+		return withResponseTimes(answers), "ghost"
+	}
+	return nil, "scores"
+}
+
+// withResponseTimes mengurutkan jawaban & menghitung waktu jawab per soal tiap pemain
+func withResponseTimes(answers []matchAnswer) []matchAnswer {
+	sort.SliceStable(answers, func(i, j int) bool { return answers[i].ElapsedMs < answers[j].ElapsedMs })
+
+	last := map[uint]int{}
+	for i := range answers {
+		answers[i].ResponseMs = answers[i].ElapsedMs - last[answers[i].UserID]
+		last[answers[i].UserID] = answers[i].ElapsedMs
+	}
+	return answers
+}
+
+// BuildMatchSummary menyusun ringkasan challenge (Participants.User harus di-preload)
+func BuildMatchSummary(challenge models.Challenge) MatchSummary {
+	summary := MatchSummary{
+		ChallengeID: challenge.ID,
+		Mode:        challenge.Mode,
+		WinnerID:    challenge.WinnerID,
+		WinningTeam: challenge.WinningTeam,
+	}
+
+	answers, source := collectMatchAnswers(challenge)
+	summary.Source = source
+
+	// --- Pemain ---
+	teamOf := map[uint]string{}
+	playerIdx := map[uint]int{}
+	totalResponse := map[uint]int{}
+	for _, p := range challenge.Participants {
+		if p.Status != "accepted" {
+			continue
+		}
+		teamOf[p.UserID] = p.Team
+		playerIdx[p.UserID] = len(summary.Players)
+		summary.Players = append(summary.Players, PlayerSummary{
+			UserID:    p.UserID,
+			Username:  p.User.Username,
+			Name:      p.User.Name,
+			Team:      p.Team,
+			Score:     p.Score,
+			TimeTaken: p.TimeTaken,
+			Forfeited: p.Forfeited,
+		})
+	}
+	for _, a := range answers {
+		i, ok := playerIdx[a.UserID]
+		if !ok {
+			continue
+		}
+		summary.Players[i].Answered++
+		if a.Correct {
+			summary.Players[i].Correct++
+		}
+		totalResponse[a.UserID] += a.ResponseMs
+	}
+	for i, p := range summary.Players {
+		if p.Answered > 0 {
+			summary.Players[i].AvgResponseMs = totalResponse[p.UserID] / p.Answered
+		}
+	}
+
+	// --- Ronde per soal, urut dari soal yang pertama dijawab ---
+	var questionOrder []uint
+	roundAnswers := map[uint][]RoundAnswer{}
+	for _, a := range answers {
+		if _, ok := roundAnswers[a.QuestionID]; !ok {
+			questionOrder = append(questionOrder, a.QuestionID)
+		}
+		roundAnswers[a.QuestionID] = append(roundAnswers[a.QuestionID], RoundAnswer{
+			UserID:     a.UserID,
+			Answer:     a.Answer,
+			Correct:    a.Correct,
+			ResponseMs: a.ResponseMs,
+			ElapsedMs:  a.ElapsedMs,
+		})
+	}
+
+	questions := map[uint]models.Question{}
+	if len(questionOrder) > 0 {
+		var qs []models.Question
+		config.DB.Where("id IN ?", questionOrder).Find(&qs)
+		for _, q := range qs {
+			questions[q.ID] = q
+		}
+	}
+
+	for i, qID := range questionOrder {
+		round := RoundSummary{
+			Round:         i + 1,
+			QuestionID:    qID,
+			Question:      questions[qID].QuestionText,
+			CorrectAnswer: questions[qID].CorrectAnswer,
+			Answers:       roundAnswers[qID],
+		}
+		for _, ra := range round.Answers {
+			if !ra.Correct {
+				continue
+			}
+			round.CorrectCount++
+			if round.FastestUserID == nil || ra.ResponseMs < fastestResponse(round) {
+				uid := ra.UserID
+				round.FastestUserID = &uid
+			}
+		}
+		summary.Rounds = append(summary.Rounds, round)
+	}
+
+	// --- Pergantian pemimpin & comeback (unit = pemain, atau tim untuk 2v2) ---
+	unitOf := func(userID uint) string {
+		if challenge.Mode == "2v2" {
+			return teamOf[userID]
+		}
+		return strconv.Itoa(int(userID))
+	}
+
+	tally := map[string]int{}
+	for _, p := range summary.Players {
+		tally[unitOf(p.UserID)] = 0
+	}
+	maxDeficit := map[string]int{}
+	deficitAt := map[string]int{}
+	currentLeader := ""
+
+	for _, a := range answers {
+		unit := unitOf(a.UserID)
+		if _, ok := tally[unit]; !ok {
+			continue
+		}
+		if a.Correct {
+			tally[unit]++
+		}
+
+		leader, top, second := "", -1, -1
+		for u, n := range tally {
+			if n > top {
+				leader, second, top = u, top, n
+			} else {
+				if n == top {
+					leader = ""
+				}
+				if n > second {
+					second = n
+				}
+			}
+		}
+
+		for u, n := range tally {
+			if d := top - n; d > maxDeficit[u] {
+				maxDeficit[u] = d
+				deficitAt[u] = a.ElapsedMs
+			}
+		}
+
+		if leader != "" && leader != currentLeader {
+			currentLeader = leader
+			summary.LeadChanges = append(summary.LeadChanges, LeadChange{
+				ElapsedMs:  a.ElapsedMs,
+				QuestionID: a.QuestionID,
+				Leader:     leader,
+				Lead:       top - second,
+			})
+		}
+	}
+
+	winner := ""
+	if challenge.Mode == "2v2" && challenge.WinningTeam != "" && challenge.WinningTeam != "DRAW" {
+		winner = challenge.WinningTeam
+	} else if challenge.WinnerID != nil {
+		winner = strconv.Itoa(int(*challenge.WinnerID))
+	}
+	if winner != "" && maxDeficit[winner] > 0 {
+		summary.BestComeback = &Comeback{Leader: winner, Deficit: maxDeficit[winner], AtMs: deficitAt[winner]}
+	}
+
+	// --- MVP: jawaban benar terbanyak, lalu rata-rata waktu jawab tercepat.
+	// Tanpa data per soal: skor tertinggi lalu waktu tercepat.
+	for i := range summary.Players {
+		p := &summary.Players[i]
+		if p.Forfeited {
+			continue
+		}
+		if summary.MVP == nil || betterMVP(*p, *summary.MVP, source != "scores") {
+			summary.MVP = p
+		}
+	}
+
+	return summary
+}
+
+func fastestResponse(round RoundSummary) int {
+	for _, ra := range round.Answers {
+		if ra.UserID == *round.FastestUserID {
+			return ra.ResponseMs
+		}
+	}
+	return 0
+}
+
+func betterMVP(a PlayerSummary, b PlayerSummary, useAnswers bool) bool {
+	if useAnswers {
+		if a.Correct != b.Correct {
+			return a.Correct > b.Correct
+		}
+		return a.AvgResponseMs < b.AvgResponseMs
+	}
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.TimeTaken < b.TimeTaken
+}
+
+// HeadToHeadRecord: rekap challenge selesai antara dua user (dari sisi userA)
+type HeadToHeadRecord struct {
+	OpponentID uint              `json:"opponent_id"`
+	Username   string            `json:"username"`
+	Name       string            `json:"name"`
+	Played     int               `json:"played"`
+	Wins       int               `json:"wins"`
+	Losses     int               `json:"losses"`
+	Draws      int               `json:"draws"`
+	AvgMargin  float64           `json:"avg_margin"` // Rata-rata selisih skor (positif = userA unggul)
+	Recent     []HeadToHeadMatch `json:"recent,omitempty"`
+}
+
+type HeadToHeadMatch struct {
+	ChallengeID uint      `json:"challenge_id"`
+	Mode        string    `json:"mode"`
+	Result      string    `json:"result"` // win, loss, draw
+	Margin      int       `json:"margin"`
+	PlayedAt    time.Time `json:"played_at"`
+}
+
+const headToHeadRecentLimit = 5
+
+// HeadToHead menghitung rekor userA melawan userB di semua challenge selesai yang diikuti keduanya.
+// 2v2 dihitung lewat WinningTeam dengan selisih skor total tim; rekan setim tidak dihitung.
+func HeadToHead(userA uint, userB uint) HeadToHeadRecord {
+	record := HeadToHeadRecord{OpponentID: userB}
+
+	var opponent models.User
+	if err := config.DB.First(&opponent, userB).Error; err == nil {
+		record.Username = opponent.Username
+		record.Name = opponent.Name
+	}
+
+	shared := config.DB.Model(&models.ChallengeParticipant{}).Select("challenge_id").
+		Where("user_id = ? AND status = ?", userB, "accepted")
+
+	var challenges []models.Challenge
+	config.DB.Preload("Participants", "status = ?", "accepted").
+		Where("status = ?", "finished").
+		Where("id IN (?)", config.DB.Model(&models.ChallengeParticipant{}).Select("challenge_id").
+			Where("user_id = ? AND status = ? AND challenge_id IN (?)", userA, "accepted", shared)).
+		Order("updated_at DESC").
+		Find(&challenges)
+
+	totalMargin := 0
+	for _, ch := range challenges {
+		var a, b *models.ChallengeParticipant
+		for i := range ch.Participants {
+			if ch.Participants[i].UserID == userA {
+				a = &ch.Participants[i]
+			} else if ch.Participants[i].UserID == userB {
+				b = &ch.Participants[i]
+			}
+		}
+		if a == nil || b == nil {
+			continue
+		}
+		if ch.Mode == "2v2" && a.Team == b.Team {
+			continue
+		}
+
+		margin := a.Score - b.Score
+		if ch.Mode == "2v2" {
+			margin = 0
+			for _, p := range ch.Participants {
+				if !p.IsFinished || p.Forfeited {
+					continue
+				}
+				if p.Team == a.Team {
+					margin += p.Score
+				} else if p.Team == b.Team {
+					margin -= p.Score
+				}
+			}
+		}
+
+		record.Played++
+		totalMargin += margin
+		result := "draw"
+		switch headToHeadResult(ch, *a, *b) {
+		case 1:
+			record.Wins++
+			result = "win"
+		case -1:
+			record.Losses++
+			result = "loss"
+		default:
+			record.Draws++
+		}
+
+		if len(record.Recent) < headToHeadRecentLimit {
+			record.Recent = append(record.Recent, HeadToHeadMatch{
+				ChallengeID: ch.ID,
+				Mode:        ch.Mode,
+				Result:      result,
+				Margin:      margin,
+				PlayedAt:    ch.UpdatedAt,
+			})
+		}
+	}
+
+	if record.Played > 0 {
+		record.AvgMargin = math.Round(float64(totalMargin)/float64(record.Played)*10) / 10
+	}
+	return record
+}
+
+// headToHeadResult: 1 jika a menang atas b, -1 jika kalah, 0 seri
+func headToHeadResult(ch models.Challenge, a models.ChallengeParticipant, b models.ChallengeParticipant) int {
+	if ch.Mode == "2v2" {
+		switch ch.WinningTeam {
+		case a.Team:
+			return 1
+		case b.Team:
+			return -1
+		}
+		return 0
+	}
+	if ch.WinnerID != nil {
+		if *ch.WinnerID == a.UserID {
+			return 1
+		}
+		if *ch.WinnerID == b.UserID {
+			return -1
+		}
+	}
+
+	// Keduanya bukan pemenang (battle royale): bandingkan hasil masing-masing
+	if a.Forfeited != b.Forfeited {
+		if b.Forfeited {
+			return 1
+		}
+		return -1
+	}
+	if a.Score != b.Score {
+		if a.Score > b.Score {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
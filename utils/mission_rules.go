@@ -0,0 +1,389 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tipe event misi
+const (
+	MissionQuizFinished    = "quiz_finished"    // value = skor; attrs: score, questions, topic, quiz, survival
+	MissionLogin           = "login"            // attrs: hour (WIB)
+	MissionShopBuy         = "shop_buy"         // attrs: item_type, price
+	MissionShopEquip       = "shop_equip"       // attrs: item_type
+	MissionFriendAdded     = "friend_added"     //
+	MissionLeaderboardView = "leaderboard_view" //
+	MissionProfileShare    = "profile_share"    //
+	MissionXPGained        = "xp_gained"        // value = XP
+	MissionLevelUp         = "level_up"         // attrs: level
+	MissionChallengeWon    = "challenge_won"    // attrs: mode
+	MissionChallengePlayed = "challenge_played" // attrs: mode
+)
+
+var (
+	MissionEventTypes = map[string]bool{
+		MissionQuizFinished: true, MissionLogin: true, MissionShopBuy: true, MissionShopEquip: true,
+		MissionFriendAdded: true, MissionLeaderboardView: true, MissionProfileShare: true,
+		MissionXPGained: true, MissionLevelUp: true, MissionChallengeWon: true, MissionChallengePlayed: true,
+	}
+	missionAggregations = map[string]bool{"count": true, "sum": true, "max": true}
+	MissionRarities     = map[string]int{"common": 0, "rare": 2, "epic": 1} // Batas jumlah per pembagian misi (0 = bebas)
+)
+
+// MissionEvent adalah satu kejadian yang bisa memajukan misi.
+// Key opsional: event dengan Key yang sama untuk user yang sama hanya diproses sekali.
+type MissionEvent struct {
+	Type  string
+	Key   string
+	Value int
+	Attrs map[string]interface{}
+}
+
+type missionCondition struct {
+	Field string
+	Op    string
+	Value string
+	IsNum bool
+	Num   float64
+}
+
+var missionOperators = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// ParseMissionFilter mem-parse ekspresi filter sederhana: kondisi `field op value`
+// digabung dengan `&&`. Value berupa angka atau string dalam tanda kutip.
+func ParseMissionFilter(expr string) ([]missionCondition, error) {
+	var conds []missionCondition
+	if strings.TrimSpace(expr) == "" {
+		return conds, nil
+	}
+
+	for _, part := range strings.Split(expr, "&&") {
+		part = strings.TrimSpace(part)
+
+		op := ""
+		idx := -1
+		for _, candidate := range missionOperators {
+			if i := strings.Index(part, candidate); i > 0 {
+				op, idx = candidate, i
+				break
+			}
+		}
+		if op == "" {
+			return nil, fmt.Errorf("invalid condition %q", part)
+		}
+
+		cond := missionCondition{
+			Field: strings.TrimSpace(part[:idx]),
+			Op:    op,
+		}
+		raw := strings.TrimSpace(part[idx+len(op):])
+		if cond.Field == "" || raw == "" {
+			return nil, fmt.Errorf("invalid condition %q", part)
+		}
+
+		if unquoted, err := strconv.Unquote(raw); err == nil {
+			cond.Value = unquoted
+		} else if num, err := strconv.ParseFloat(raw, 64); err == nil {
+			cond.IsNum, cond.Num, cond.Value = true, num, raw
+		} else {
+			return nil, fmt.Errorf("value must be a number or quoted string in %q", part)
+		}
+		if !cond.IsNum && op != "==" && op != "!=" {
+			return nil, fmt.Errorf("operator %s needs a number in %q", op, part)
+		}
+		conds = append(conds, cond)
+	}
+	return conds, nil
+}
+
+func (cond missionCondition) matches(attrs map[string]interface{}) bool {
+	actual, ok := attrs[cond.Field]
+	if !ok {
+		return false
+	}
+
+	if !cond.IsNum {
+		equal := strings.EqualFold(fmt.Sprint(actual), cond.Value)
+		if cond.Op == "==" {
+			return equal
+		}
+		return !equal
+	}
+
+	var num float64
+	switch v := actual.(type) {
+	case int:
+		num = float64(v)
+	case uint:
+		num = float64(v)
+	case float64:
+		num = v
+	case bool:
+		if v {
+			num = 1
+		}
+	default:
+		parsed, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+		if err != nil {
+			return false
+		}
+		num = parsed
+	}
+
+	switch cond.Op {
+	case "==":
+		return num == cond.Num
+	case "!=":
+		return num != cond.Num
+	case ">=":
+		return num >= cond.Num
+	case "<=":
+		return num <= cond.Num
+	case ">":
+		return num > cond.Num
+	default:
+		return num < cond.Num
+	}
+}
+
+// MissionMatches mengecek apakah event memenuhi filter misi
+func MissionMatches(mission models.Mission, event MissionEvent) bool {
+	if mission.EventType != event.Type {
+		return false
+	}
+	conds, err := ParseMissionFilter(mission.Filter)
+	if err != nil {
+		return false
+	}
+	for _, cond := range conds {
+		if !cond.matches(event.Attrs) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateMissionRule mengecek aturan misi sebelum disimpan admin
+func ValidateMissionRule(mission models.Mission) error {
+	if mission.Key == "" || mission.Title == "" {
+		return errors.New("key and title are required")
+	}
+	if !MissionEventTypes[mission.EventType] {
+		return fmt.Errorf("unknown event_type %q", mission.EventType)
+	}
+	if !missionAggregations[mission.Aggregation] {
+		return errors.New("aggregation must be count, sum or max")
+	}
+	if _, ok := MissionRarities[mission.Rarity]; !ok {
+		return errors.New("rarity must be common, rare or epic")
+	}
+	if mission.Period != "daily" && mission.Period != "weekly" {
+		return errors.New("period must be daily or weekly")
+	}
+	if mission.Target < 1 || mission.Reward < 0 || mission.PassPoints < 0 || mission.Weight < 1 {
+		return errors.New("target and weight must be at least 1, reward and pass_points cannot be negative")
+	}
+	_, err := ParseMissionFilter(mission.Filter)
+	return err
+}
+
+// missionIncrement menghitung tambahan progress dari satu event sesuai agregasi
+func missionIncrement(mission models.Mission, event MissionEvent) interface{} {
+	switch mission.Aggregation {
+	case "sum":
+		if event.Value <= 0 {
+			return nil
+		}
+		return gorm.Expr("LEAST(progress + ?, ?)", event.Value, mission.Target)
+	case "max":
+		return gorm.Expr("GREATEST(progress, LEAST(?, ?))", event.Value, mission.Target)
+	default:
+		return gorm.Expr("LEAST(progress + 1, ?)", mission.Target)
+	}
+}
+
+// TrackMissionEvent adalah satu-satunya jalur untuk memajukan progress misi.
+// Event dengan Key hanya diproses sekali per user; update progress atomik.
+func TrackMissionEvent(userID uint, event MissionEvent) {
+	if userID == 0 {
+		return
+	}
+	if event.Attrs == nil {
+		event.Attrs = map[string]interface{}{}
+	}
+
+	if event.Key != "" {
+		res := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.MissionEventLog{
+			UserID:    userID,
+			EventKey:  event.Key,
+			EventType: event.Type,
+		})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return
+		}
+	}
+
+	// Kuis selesai juga memberi poin season pass
+	if event.Type == MissionQuizFinished {
+		AddSeasonPoints(config.DB, userID, seasonQuizPoints)
+	}
+
+	now := GetJakartaTime()
+	today := StripTime(now)
+
+	var userMissions []models.UserMission
+	config.DB.Preload("Mission").
+		Joins("JOIN missions ON missions.id = user_missions.mission_id").
+		Where("user_missions.user_id = ? AND user_missions.is_claimed = ?", userID, false).
+		Where("(missions.period = ? AND user_missions.reset_date = ?) OR (missions.period <> ? AND user_missions.reset_date = ?)",
+			"weekly", WeekStart(now), "weekly", today).
+		Where("missions.event_type = ?", event.Type).
+		Find(&userMissions)
+
+	for _, um := range userMissions {
+		if !MissionMatches(um.Mission, event) {
+			continue
+		}
+		expr := missionIncrement(um.Mission, event)
+		if expr == nil {
+			continue
+		}
+
+		var updated models.UserMission
+		res := config.DB.Model(&updated).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "progress"}}}).
+			Where("id = ? AND is_claimed = ? AND progress < ?", um.ID, false, um.Mission.Target).
+			Updates(map[string]interface{}{"progress": expr, "updated_at": time.Now()})
+		if res.RowsAffected == 0 {
+			continue
+		}
+
+		// Hanya update yang membuat progress mencapai target yang mengirim notifikasi
+		if updated.Progress >= um.Mission.Target {
+			SendNotification(userID, "success", "Misi Selesai!", "Kamu menyelesaikan misi: "+um.Mission.Title, "/")
+		}
+	}
+}
+
+// QuizFinishedMissionEvent membuat event misi dari History yang baru disimpan
+func QuizFinishedMissionEvent(history models.History) MissionEvent {
+	attrs := map[string]interface{}{
+		"score":     history.Score,
+		"questions": history.TotalSoal,
+		"quiz":      history.QuizTitle,
+		"survival":  history.QuizID == 0 && history.QuizTitle == survivalHistoryTitle,
+	}
+	if history.QuizID != 0 {
+		var topic string
+		config.DB.Table("quizzes").Select("topics.title").
+			Joins("JOIN topics ON topics.id = quizzes.topic_id").
+			Where("quizzes.id = ?", history.QuizID).
+			Scan(&topic)
+		attrs["topic"] = topic
+	} else if attrs["survival"] == true {
+		// Survival per topik: topik diambil dari run yang menyimpan history ini
+		var topic string
+		config.DB.Table("survival_runs").Select("topics.title").
+			Joins("JOIN topics ON topics.id = survival_runs.topic_id").
+			Where("survival_runs.history_id = ?", history.ID).
+			Scan(&topic)
+		if topic != "" {
+			attrs["topic"] = topic
+		}
+	}
+
+	return MissionEvent{
+		Type:  MissionQuizFinished,
+		Key:   "history:" + strconv.Itoa(int(history.ID)),
+		Value: history.Score,
+		Attrs: attrs,
+	}
+}
+
+const (
+	dailyMissionCount  = 5
+	weeklyMissionCount = 3
+)
+
+// AssignDailyMissions membagikan misi harian secara acak berbobot (Weight).
+// Misi langka dibatasi per hari sesuai MissionRarities.
+func AssignDailyMissions(userID uint) {
+	assignPeriodMissions(userID, "daily", StripTime(GetJakartaTime()), dailyMissionCount)
+}
+
+// AssignWeeklyMissions membagikan misi mingguan, reset setiap Senin (WIB)
+func AssignWeeklyMissions(userID uint) {
+	assignPeriodMissions(userID, "weekly", WeekStart(GetJakartaTime()), weeklyMissionCount)
+}
+
+func assignPeriodMissions(userID uint, period string, resetDate time.Time, n int) {
+	// Cek apakah user sudah punya misi periode ini?
+	var count int64
+	config.DB.Model(&models.UserMission{}).
+		Joins("JOIN missions ON missions.id = user_missions.mission_id").
+		Where("user_missions.user_id = ? AND user_missions.reset_date = ? AND missions.period = ?", userID, resetDate, period).
+		Count(&count)
+	if count > 0 {
+		return
+	}
+
+	var pool []models.Mission
+	config.DB.Where("is_active = ? AND event_type <> '' AND period = ?", true, period).Find(&pool)
+
+	for _, mission := range pickWeightedMissions(pool, n) {
+		config.DB.Create(&models.UserMission{
+			UserID:    userID,
+			MissionID: mission.ID,
+			ResetDate: resetDate,
+		})
+	}
+}
+
+// pickWeightedMissions memilih n misi tanpa pengulangan, peluang sebanding Weight
+func pickWeightedMissions(pool []models.Mission, n int) []models.Mission {
+	var picked []models.Mission
+	rarityCount := make(map[string]int)
+
+	for len(picked) < n && len(pool) > 0 {
+		total := 0
+		for _, m := range pool {
+			total += missionWeight(m)
+		}
+
+		roll := rand.Intn(total)
+		idx := 0
+		for i, m := range pool {
+			roll -= missionWeight(m)
+			if roll < 0 {
+				idx = i
+				break
+			}
+		}
+
+		mission := pool[idx]
+		pool = append(pool[:idx], pool[idx+1:]...)
+
+		if limit := MissionRarities[mission.Rarity]; limit > 0 && rarityCount[mission.Rarity] >= limit {
+			continue
+		}
+		rarityCount[mission.Rarity]++
+		picked = append(picked, mission)
+	}
+	return picked
+}
+
+func missionWeight(m models.Mission) int {
+	if m.Weight < 1 {
+		return 1
+	}
+	return m.Weight
+}
@@ -0,0 +1,304 @@
+package utils
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tipe item power-up (consumable)
+const (
+	PowerUpFiftyFifty = "powerup_fifty_fifty"
+	PowerUpTimePlus   = "powerup_time_plus"
+	PowerUpSkip       = "powerup_skip"
+	PowerUpExtraLife  = "powerup_extra_life"
+
+	PowerUpBonusSeconds = 15
+
+	// Toleransi latensi jaringan saat mengecek batas waktu attempt
+	attemptGraceSeconds = 5
+)
+
+var (
+	ErrPowerUpsDisabled   = errors.New("power-ups are disabled for this attempt")
+	ErrPowerUpInvalid     = errors.New("power-up cannot be used here")
+	ErrPowerUpUsed        = errors.New("power-up already used")
+	ErrAttemptNotActive   = errors.New("attempt is not active")
+	ErrAttemptMismatch    = errors.New("attempt does not match this submission")
+	ErrAttemptExpired     = errors.New("attempt time limit exceeded")
+	ErrPowerUpRankedRun   = errors.New("extra life cannot be used in daily or ranked survival runs")
+	ErrPowerUpNoQuestion  = errors.New("question is not part of this quiz")
+	ErrPowerUpUnsupported = errors.New("question type does not support this power-up")
+)
+
+// PowerUpResult adalah efek power-up yang dikirim ke client
+type PowerUpResult struct {
+	Type         string   `json:"type"`
+	QuestionID   uint     `json:"question_id,omitempty"`
+	Removed      []string `json:"removed,omitempty"`       // 50/50: opsi yang dihapus
+	BonusSeconds int      `json:"bonus_seconds,omitempty"` // Total tambahan waktu di attempt
+	Skipped      bool     `json:"skipped,omitempty"`
+	Lives        int      `json:"lives,omitempty"` // Extra life: nyawa run survival sekarang
+	Remaining    int      `json:"remaining"`       // Sisa stok power-up
+}
+
+// QuizAttemptSubmission adalah ringkasan attempt yang dipakai saat penilaian history
+type QuizAttemptSubmission struct {
+	Attempt models.QuizAttempt
+	Skipped map[uint]bool
+}
+
+// PowerUpsAllowed: power-up dilarang di kuis mode ujian, tugas kelas, dan challenge yang mematikannya
+func PowerUpsAllowed(quiz models.Quiz, challengeID *uint, assignmentID *uint) bool {
+	if quiz.ExamMode || assignmentID != nil {
+		return false
+	}
+	return challengeAllowsPowerUps(config.DB, challengeID)
+}
+
+func challengeAllowsPowerUps(tx *gorm.DB, challengeID *uint) bool {
+	if challengeID == nil {
+		return true
+	}
+	var challenge models.Challenge
+	if err := tx.Select("id", "disable_powerups").First(&challenge, *challengeID).Error; err != nil {
+		return false
+	}
+	return !challenge.DisablePowerups
+}
+
+// StartQuizAttempt membuka sesi pengerjaan kuis
+func StartQuizAttempt(userID uint, quiz models.Quiz, challengeID *uint, assignmentID *uint) (models.QuizAttempt, error) {
+	attempt := models.QuizAttempt{
+		UserID:          userID,
+		QuizID:          quiz.ID,
+		ChallengeID:     challengeID,
+		AssignmentID:    assignmentID,
+		PowerupsAllowed: PowerUpsAllowed(quiz, challengeID, assignmentID),
+		Status:          "active",
+	}
+	if challengeID != nil {
+		var challenge models.Challenge
+		if config.DB.Select("id", "time_limit").First(&challenge, *challengeID).Error == nil {
+			attempt.TimeLimit = challenge.TimeLimit
+		}
+	}
+	err := config.DB.Create(&attempt).Error
+	return attempt, err
+}
+
+// StartRemedialAttempt membuka attempt remedial (QuizID 0) dengan daftar soal pilihan server
+func StartRemedialAttempt(userID uint, questionIDs []uint) (models.QuizAttempt, error) {
+	attempt := models.QuizAttempt{
+		UserID:         userID,
+		Status:         "active",
+		QuestionSetKey: QuestionSetKey(questionIDs),
+	}
+	for _, id := range questionIDs {
+		attempt.QuestionIDs = append(attempt.QuestionIDs, int64(id))
+	}
+	err := config.DB.Create(&attempt).Error
+	return attempt, err
+}
+
+// QuestionSetKey adalah ID soal terurut ("3,8,21"), dipakai untuk mengenali remedial yang sama
+func QuestionSetKey(questionIDs []uint) string {
+	ids := slices.Clone(questionIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// UseQuizPowerUp memvalidasi & menerapkan power-up pada satu soal di attempt, lalu memotong stoknya
+func UseQuizPowerUp(userID uint, attemptID uint, questionID uint, powerUp string) (PowerUpResult, error) {
+	result := PowerUpResult{Type: powerUp, QuestionID: questionID}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var attempt models.QuizAttempt
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", attemptID, userID).First(&attempt).Error; err != nil {
+			return err
+		}
+		if attempt.Status != "active" {
+			return ErrAttemptNotActive
+		}
+		if !attempt.PowerupsAllowed {
+			return ErrPowerUpsDisabled
+		}
+
+		var question models.Question
+		if err := tx.Where("id = ? AND quiz_id = ?", questionID, attempt.QuizID).First(&question).Error; err != nil {
+			return ErrPowerUpNoQuestion
+		}
+
+		use := models.PowerUpUse{UserID: userID, AttemptID: attempt.ID, QuestionID: question.ID, Type: powerUp}
+
+		switch powerUp {
+		case PowerUpFiftyFifty:
+			removed := fiftyFiftyRemoval(question)
+			if len(removed) == 0 {
+				return ErrPowerUpUnsupported
+			}
+			use.Removed = removed
+			result.Removed = removed
+		case PowerUpTimePlus:
+			// Tanpa batas waktu, tambahan detik tidak ada artinya
+			if attempt.TimeLimit <= 0 {
+				return ErrPowerUpInvalid
+			}
+			attempt.BonusSeconds += PowerUpBonusSeconds
+			if err := tx.Model(&attempt).Update("bonus_seconds", attempt.BonusSeconds).Error; err != nil {
+				return err
+			}
+			result.BonusSeconds = attempt.BonusSeconds
+		case PowerUpSkip:
+			result.Skipped = true
+		default:
+			return ErrPowerUpInvalid
+		}
+
+		if err := recordPowerUpUse(tx, &use); err != nil {
+			return err
+		}
+		result.Remaining = CountUserItems(tx, userID, powerUp)
+		return nil
+	})
+	return result, err
+}
+
+// UseSurvivalPowerUp menerapkan power-up di run survival (saat ini hanya extra life, sekali per run).
+// Run daily dan run ranked (masuk leaderboard) ditolak supaya nyawa tetap setara antar pemain.
+func UseSurvivalPowerUp(userID uint, runID uint, powerUp string) (PowerUpResult, error) {
+	result := PowerUpResult{Type: powerUp}
+	if powerUp != PowerUpExtraLife {
+		return result, ErrPowerUpInvalid
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var run models.SurvivalRun
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", runID, userID).First(&run).Error; err != nil {
+			return err
+		}
+		if run.Status != "active" {
+			return ErrSurvivalRunNotActive
+		}
+		if run.Mode == "daily" || SurvivalRunRanked(run) {
+			return ErrPowerUpRankedRun
+		}
+		if !challengeAllowsPowerUps(tx, run.ChallengeID) {
+			return ErrPowerUpsDisabled
+		}
+
+		if err := recordPowerUpUse(tx, &models.PowerUpUse{UserID: userID, RunID: run.ID, Type: powerUp}); err != nil {
+			return err
+		}
+
+		run.Lives++
+		if run.Lives > run.MaxLives {
+			run.MaxLives = run.Lives
+		}
+		if err := tx.Model(&run).Updates(map[string]interface{}{"lives": run.Lives, "max_lives": run.MaxLives}).Error; err != nil {
+			return err
+		}
+		result.Lives = run.Lives
+		result.Remaining = CountUserItems(tx, userID, powerUp)
+		return nil
+	})
+	return result, err
+}
+
+// recordPowerUpUse mencatat pemakaian (unik per soal/run) lalu memotong satu stok item
+func recordPowerUpUse(tx *gorm.DB, use *models.PowerUpUse) error {
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(use)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrPowerUpUsed
+	}
+	return ConsumeUserItem(tx, use.UserID, use.Type, 1)
+}
+
+// fiftyFiftyRemoval memilih opsi salah yang dihapus: maks 2, minimal satu opsi salah tetap tersisa
+func fiftyFiftyRemoval(q models.Question) []string {
+	var wrong []string
+	for _, opt := range q.Options {
+		if !IsAnswerCorrect(q, opt) {
+			wrong = append(wrong, opt)
+		}
+	}
+	n := min(2, len(wrong)-1)
+	if n <= 0 || len(wrong) == len(q.Options) {
+		return nil
+	}
+
+	rand.Shuffle(len(wrong), func(i, j int) { wrong[i], wrong[j] = wrong[j], wrong[i] })
+	return wrong[:n]
+}
+
+// SubmitQuizAttempt menutup attempt saat history dikirim. Attempt harus milik user dan cocok
+// dengan kuis / challenge / tugas yang dikirim; soal yang di-skip dikembalikan supaya jawabannya diabaikan (dinilai salah).
+func SubmitQuizAttempt(userID uint, attemptID uint, quizID uint, challengeID *uint, assignmentID *uint) (QuizAttemptSubmission, error) {
+	submission := QuizAttemptSubmission{Skipped: map[uint]bool{}}
+
+	var attempt models.QuizAttempt
+	if err := config.DB.Where("id = ? AND user_id = ?", attemptID, userID).First(&attempt).Error; err != nil {
+		return submission, err
+	}
+	if attempt.QuizID != quizID || !sameOptionalID(attempt.ChallengeID, challengeID) || !sameOptionalID(attempt.AssignmentID, assignmentID) {
+		return submission, ErrAttemptMismatch
+	}
+
+	// Batas waktu = time limit challenge + bonus power-up +15 detik
+	if attempt.TimeLimit > 0 {
+		deadline := attempt.CreatedAt.Add(time.Duration(attempt.TimeLimit+attempt.BonusSeconds+attemptGraceSeconds) * time.Second)
+		if time.Now().After(deadline) {
+			config.DB.Model(&models.QuizAttempt{}).
+				Where("id = ? AND status = ?", attempt.ID, "active").
+				Update("status", "expired")
+			return submission, ErrAttemptExpired
+		}
+	}
+
+	res := config.DB.Model(&models.QuizAttempt{}).
+		Where("id = ? AND status = ?", attempt.ID, "active").
+		Update("status", "submitted")
+	if res.Error != nil {
+		return submission, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return submission, ErrAttemptNotActive
+	}
+
+	var skipped []uint
+	config.DB.Model(&models.PowerUpUse{}).
+		Where("attempt_id = ? AND type = ?", attempt.ID, PowerUpSkip).
+		Pluck("question_id", &skipped)
+	for _, id := range skipped {
+		submission.Skipped[id] = true
+	}
+
+	submission.Attempt = attempt
+	return submission, nil
+}
+
+func sameOptionalID(a *uint, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSeasonNotActive       = errors.New("no active season")
+	ErrSeasonTierLocked      = errors.New("not enough pass points for this tier")
+	ErrSeasonTierClaimed     = errors.New("tier already claimed")
+	ErrSeasonPremiumRequired = errors.New("premium pass required")
+	ErrSeasonPremiumOwned    = errors.New("premium pass already owned")
+)
+
+const (
+	seasonJobInterval = time.Minute
+	seasonQuizPoints  = 10 // Poin pass per kuis selesai
+)
+
+// StartSeasonJob mengaktifkan season yang sudah mulai dan mengarsipkan yang sudah berakhir
+func StartSeasonJob() {
+	go func() {
+		ticker := time.NewTicker(seasonJobInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			RolloverSeasons()
+		}
+	}()
+}
+
+// RolloverSeasons mengarsipkan season yang lewat EndAt lalu mengaktifkan season berikutnya
+func RolloverSeasons() {
+	now := time.Now()
+
+	var ended []models.Season
+	config.DB.Where("status = ? AND end_at <= ?", "active", now).Find(&ended)
+	for _, s := range ended {
+		ArchiveSeason(s.ID)
+	}
+
+	var activeCount int64
+	config.DB.Model(&models.Season{}).Where("status = ?", "active").Count(&activeCount)
+	if activeCount > 0 {
+		return
+	}
+
+	var next models.Season
+	if err := config.DB.Where("status = ? AND start_at <= ? AND end_at > ?", "upcoming", now, now).
+		Order("start_at ASC").First(&next).Error; err != nil {
+		return
+	}
+	config.DB.Model(&models.Season{}).Where("id = ? AND status = ?", next.ID, "upcoming").Update("status", "active")
+}
+
+// ArchiveSeason menutup season: progress semua user dibekukan beserta tier akhir (track free)
+func ArchiveSeason(seasonID uint) bool {
+	res := config.DB.Model(&models.Season{}).
+		Where("id = ? AND status = ?", seasonID, "active").
+		Update("status", "archived")
+	if res.RowsAffected == 0 {
+		return false
+	}
+
+	config.DB.Exec(`UPDATE user_season_passes p SET archived = true, updated_at = NOW(), final_tier = COALESCE((
+		SELECT MAX(t.tier) FROM season_tiers t
+		WHERE t.season_id = p.season_id AND t.track = 'free' AND t.deleted_at IS NULL AND t.points_required <= p.points
+	), 0) WHERE p.season_id = ? AND p.archived = false`, seasonID)
+	return true
+}
+
+// GetActiveSeason mengambil season yang sedang berjalan
+func GetActiveSeason() (models.Season, error) {
+	var season models.Season
+	err := config.DB.Where("status = ? AND start_at <= ? AND end_at > ?", "active", time.Now(), time.Now()).First(&season).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return season, ErrSeasonNotActive
+	}
+	return season, err
+}
+
+// AddSeasonPoints menambah poin pass user di season aktif (atomik). Tanpa season aktif tidak terjadi apa-apa.
+func AddSeasonPoints(tx *gorm.DB, userID uint, points int) error {
+	if points <= 0 {
+		return nil
+	}
+	season, err := GetActiveSeason()
+	if err != nil {
+		return nil
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "season_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"points": gorm.Expr("user_season_passes.points + ?", points), "updated_at": time.Now()}),
+	}).Create(&models.UserSeasonPass{UserID: userID, SeasonID: season.ID, Points: points}).Error
+}
+
+// GetSeasonPass mengambil (atau membuat) pass user untuk season
+func GetSeasonPass(userID uint, seasonID uint) models.UserSeasonPass {
+	config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UserSeasonPass{UserID: userID, SeasonID: seasonID})
+
+	var pass models.UserSeasonPass
+	config.DB.Where("user_id = ? AND season_id = ?", userID, seasonID).First(&pass)
+	return pass
+}
+
+// BuySeasonPremium membuka track premium season aktif dengan koin
+func BuySeasonPremium(userID uint) (models.UserSeasonPass, error) {
+	season, err := GetActiveSeason()
+	if err != nil {
+		return models.UserSeasonPass{}, err
+	}
+	pass := GetSeasonPass(userID, season.ID)
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.UserSeasonPass{}).
+			Where("id = ? AND premium = ?", pass.ID, false).
+			Update("premium", true)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrSeasonPremiumOwned
+		}
+		return MoveCoins(tx, UserAccount(userID), AccountShop, season.PremiumPrice,
+			"season_premium", LedgerRef{Type: "season", ID: season.ID})
+	})
+	if err != nil {
+		return pass, err
+	}
+
+	pass.Premium = true
+	return pass, nil
+}
+
+// ClaimSeasonTier mengklaim hadiah satu tier season aktif (koin, item, atau gelar)
+func ClaimSeasonTier(userID uint, tierID uint) (models.SeasonTier, error) {
+	var tier models.SeasonTier
+
+	season, err := GetActiveSeason()
+	if err != nil {
+		return tier, err
+	}
+	if err := config.DB.Where("id = ? AND season_id = ?", tierID, season.ID).First(&tier).Error; err != nil {
+		return tier, err
+	}
+
+	pass := GetSeasonPass(userID, season.ID)
+	if pass.Points < tier.PointsRequired {
+		return tier, ErrSeasonTierLocked
+	}
+	if tier.Track == "premium" && !pass.Premium {
+		return tier, ErrSeasonPremiumRequired
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UserSeasonClaim{
+			UserID:       userID,
+			SeasonTierID: tier.ID,
+			SeasonID:     season.ID,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrSeasonTierClaimed
+		}
+
+		switch tier.RewardType {
+		case "coins":
+			return MoveCoins(tx, AccountRewards, UserAccount(userID), tier.RewardCoins,
+				"season_reward", LedgerRef{Type: "season_tier", ID: tier.ID})
+		case "item":
+			if tier.ItemID == nil {
+				return nil
+			}
+			var item models.Item
+			if err := tx.First(&item, *tier.ItemID).Error; err != nil {
+				return err
+			}
+			// Item yang sudah dimiliki / stok penuh tidak menggagalkan klaim
+			if err := GrantUserItem(tx, userID, item, 1); err != nil && !errors.Is(err, ErrItemOwned) && !errors.Is(err, ErrItemStackFull) {
+				return err
+			}
+			return nil
+		case "title":
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.UserTitle{UserID: userID, Title: tier.Title, SeasonID: &season.ID}).Error
+		}
+		return nil
+	})
+	return tier, err
+}
+
+// ValidateSeasonTier mengecek hadiah tier sebelum disimpan admin
+func ValidateSeasonTier(tier models.SeasonTier) error {
+	if tier.Tier < 1 || tier.PointsRequired < 0 {
+		return errors.New("tier must be at least 1 and points_required cannot be negative")
+	}
+	if tier.Track != "free" && tier.Track != "premium" {
+		return errors.New("track must be free or premium")
+	}
+	switch tier.RewardType {
+	case "coins":
+		if tier.RewardCoins <= 0 {
+			return errors.New("reward_coins must be positive")
+		}
+	case "item":
+		if tier.ItemID == nil {
+			return errors.New("item_id is required")
+		}
+		var count int64
+		config.DB.Model(&models.Item{}).Where("id = ?", *tier.ItemID).Count(&count)
+		if count == 0 {
+			return errors.New("item not found")
+		}
+	case "title":
+		if tier.Title == "" {
+			return errors.New("title is required")
+		}
+	default:
+		return errors.New("reward_type must be coins, item or title")
+	}
+	return nil
+}
+
+// WeekStart mengembalikan Senin 00:00 WIB dari minggu t
+func WeekStart(t time.Time) time.Time {
+	day := StripTime(t)
+	offset := (int(day.Weekday()) + 6) % 7 // Senin = 0
+	return day.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,357 @@
+package utils
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Jumlah item unggulan di rotasi harian toko
+const ShopRotationSize = 6
+
+// Bobot kemunculan item di rotasi harian menurut rarity
+var ShopRarityWeights = map[string]int{
+	"common":    10,
+	"rare":      5,
+	"epic":      2,
+	"legendary": 1,
+}
+
+var (
+	ErrShopItemUnavailable   = errors.New("item is not available in the shop right now")
+	ErrShopBundleUnavailable = errors.New("bundle is not available right now")
+	ErrShopLevelLocked       = errors.New("your level is too low for this item")
+	ErrShopAchievementLocked = errors.New("this item requires an achievement")
+)
+
+// ShopItemView adalah item toko beserta harga akhir (setelah diskon) dan status kunci untuk user
+type ShopItemView struct {
+	models.Item
+	FinalPrice      int        `json:"final_price"`
+	DiscountPercent int        `json:"discount_percent"`
+	OfferEndsAt     *time.Time `json:"offer_ends_at,omitempty"`
+	Featured        bool       `json:"featured"`
+	Locked          bool       `json:"locked"`
+	LockReason      string     `json:"lock_reason,omitempty"` // level, achievement
+}
+
+// ShopPurchase adalah hasil pembelian item atau bundle
+type ShopPurchase struct {
+	Item      *ShopItemView      `json:"item,omitempty"`
+	Bundle    *models.ShopBundle `json:"bundle,omitempty"`
+	Quantity  int                `json:"quantity"`
+	Price     int                `json:"price"`
+	CoinsLeft int                `json:"coins_left"`
+
+	Granted []models.ShopBundleItem `json:"granted,omitempty"` // Isi bundle yang benar-benar diberikan
+	Skipped []models.ShopBundleItem `json:"skipped,omitempty"` // Item yang sudah dimiliki, tidak ditagih
+}
+
+// GetShopRotation mengambil rotasi item unggulan hari ini (dibuat otomatis saat pertama diminta)
+func GetShopRotation(tx *gorm.DB) models.ShopRotation {
+	today := StripTime(GetJakartaTime())
+
+	var rotation models.ShopRotation
+	if err := tx.Where("date = ?", today).First(&rotation).Error; err == nil {
+		return rotation
+	}
+
+	rotation = models.ShopRotation{Date: today, ItemIDs: pickShopRotation(tx, today)}
+	tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rotation)
+	tx.Where("date = ?", today).First(&rotation)
+	return rotation
+}
+
+// RegenerateShopRotation membuat ulang rotasi hari ini (admin)
+func RegenerateShopRotation() models.ShopRotation {
+	today := StripTime(GetJakartaTime())
+	rotation := models.ShopRotation{Date: today, ItemIDs: pickShopRotation(config.DB, time.Now())}
+
+	config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"item_ids"}),
+	}).Create(&rotation)
+	config.DB.Where("date = ?", today).First(&rotation)
+	return rotation
+}
+
+// pickShopRotation memilih item kosmetik aktif secara acak berbobot rarity, deterministik per seed
+func pickShopRotation(tx *gorm.DB, seedTime time.Time) []int64 {
+	var items []models.Item
+	tx.Where("is_active = ? AND consumable = ?", true, false).Order("id ASC").Find(&items)
+
+	h := fnv.New64a()
+	h.Write([]byte(seedTime.Format(time.RFC3339Nano)))
+	rng := rand.New(rand.NewSource(int64(h.Sum64())))
+
+	var picked []int64
+	for len(picked) < ShopRotationSize && len(items) > 0 {
+		total := 0
+		for _, it := range items {
+			total += shopRarityWeight(it.Rarity)
+		}
+		roll := rng.Intn(total)
+		for i, it := range items {
+			roll -= shopRarityWeight(it.Rarity)
+			if roll < 0 {
+				picked = append(picked, int64(it.ID))
+				items = append(items[:i], items[i+1:]...)
+				break
+			}
+		}
+	}
+	return picked
+}
+
+func shopRarityWeight(rarity string) int {
+	if w, ok := ShopRarityWeights[rarity]; ok {
+		return w
+	}
+	return ShopRarityWeights["common"]
+}
+
+// activeShopOffers mengambil diskon terbesar yang sedang berlaku per item
+func activeShopOffers(tx *gorm.DB) map[uint]models.ShopOffer {
+	now := time.Now()
+	var offers []models.ShopOffer
+	tx.Where("start_at <= ? AND end_at > ?", now, now).Find(&offers)
+
+	best := make(map[uint]models.ShopOffer)
+	for _, o := range offers {
+		if cur, ok := best[o.ItemID]; !ok || o.DiscountPercent > cur.DiscountPercent {
+			best[o.ItemID] = o
+		}
+	}
+	return best
+}
+
+// shopItemLock mengecek syarat level / achievement item untuk user
+func shopItemLock(tx *gorm.DB, user models.User, item models.Item) string {
+	if item.MinLevel > 0 && user.Level < item.MinLevel {
+		return "level"
+	}
+	if item.RequiredAchievementID != nil {
+		var count int64
+		tx.Model(&models.UserAchievement{}).
+			Where("user_id = ? AND achievement_id = ?", user.ID, *item.RequiredAchievementID).
+			Count(&count)
+		if count == 0 {
+			return "achievement"
+		}
+	}
+	return ""
+}
+
+func shopLockError(reason string) error {
+	switch reason {
+	case "level":
+		return ErrShopLevelLocked
+	case "achievement":
+		return ErrShopAchievementLocked
+	}
+	return nil
+}
+
+func buildShopItemView(tx *gorm.DB, user models.User, item models.Item, featured map[uint]bool, offers map[uint]models.ShopOffer) ShopItemView {
+	view := ShopItemView{Item: item, FinalPrice: item.Price, Featured: featured[item.ID]}
+	if offer, ok := offers[item.ID]; ok {
+		view.DiscountPercent = offer.DiscountPercent
+		view.FinalPrice = item.Price * (100 - offer.DiscountPercent) / 100
+		endsAt := offer.EndAt
+		view.OfferEndsAt = &endsAt
+	}
+	view.LockReason = shopItemLock(tx, user, item)
+	view.Locked = view.LockReason != ""
+	return view
+}
+
+func rotationSet(rotation models.ShopRotation) map[uint]bool {
+	featured := make(map[uint]bool)
+	for _, id := range rotation.ItemIDs {
+		featured[uint(id)] = true
+	}
+	return featured
+}
+
+// ShopCatalog mengembalikan item yang sedang dijual: item aktif biasa + item rotasi hari ini
+func ShopCatalog(userID uint, featuredOnly bool) []ShopItemView {
+	var user models.User
+	config.DB.Select("id", "level").First(&user, userID)
+
+	featured := rotationSet(GetShopRotation(config.DB))
+	offers := activeShopOffers(config.DB)
+
+	var items []models.Item
+	config.DB.Where("is_active = ?", true).Order("price ASC").Find(&items)
+
+	views := []ShopItemView{}
+	for _, item := range items {
+		if featuredOnly && !featured[item.ID] {
+			continue
+		}
+		if item.RotationOnly && !featured[item.ID] {
+			continue
+		}
+		views = append(views, buildShopItemView(config.DB, user, item, featured, offers))
+	}
+	return views
+}
+
+// QuoteShopItem menghitung harga item untuk user sekaligus memastikan item bisa dibeli
+func QuoteShopItem(tx *gorm.DB, userID uint, itemID uint) (ShopItemView, error) {
+	var item models.Item
+	if err := tx.Where("id = ? AND is_active = ?", itemID, true).First(&item).Error; err != nil {
+		return ShopItemView{}, ErrShopItemUnavailable
+	}
+	var user models.User
+	if err := tx.Select("id", "level").First(&user, userID).Error; err != nil {
+		return ShopItemView{}, err
+	}
+
+	featured := rotationSet(GetShopRotation(tx))
+	if item.RotationOnly && !featured[item.ID] {
+		return ShopItemView{}, ErrShopItemUnavailable
+	}
+
+	view := buildShopItemView(tx, user, item, featured, activeShopOffers(tx))
+	if view.Locked {
+		return view, shopLockError(view.LockReason)
+	}
+	return view, nil
+}
+
+// BuyShopItem membeli item (harga setelah diskon) dalam satu transaksi dengan pemotongan koin
+func BuyShopItem(userID uint, itemID uint, qty int) (ShopPurchase, error) {
+	purchase := ShopPurchase{Quantity: qty}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		view, err := QuoteShopItem(tx, userID, itemID)
+		if err != nil {
+			return err
+		}
+		if !view.Consumable {
+			purchase.Quantity = 1
+		}
+		purchase.Item = &view
+		purchase.Price = view.FinalPrice * purchase.Quantity
+
+		if err := GrantUserItem(tx, userID, view.Item, purchase.Quantity); err != nil {
+			return err
+		}
+		return chargeShopPurchase(tx, userID, purchase.Price, LedgerRef{Type: "item", ID: view.ID}, &purchase)
+	})
+	return purchase, err
+}
+
+// ActiveShopBundles mengambil bundle yang sedang dijual
+func ActiveShopBundles(tx *gorm.DB) []models.ShopBundle {
+	now := time.Now()
+	var bundles []models.ShopBundle
+	tx.Preload("Items.Item").
+		Where("is_active = ?", true).
+		Where("(start_at IS NULL OR start_at <= ?) AND (end_at IS NULL OR end_at > ?)", now, now).
+		Order("id DESC").
+		Find(&bundles)
+	return bundles
+}
+
+// BuyShopBundle membeli bundle. Item biasa yang sudah dimiliki dilewati dan harga bundle dipotong
+// sebanding harga normal item yang dilewati; gagal jika semua isi sudah dimiliki.
+func BuyShopBundle(userID uint, bundleID uint) (ShopPurchase, error) {
+	purchase := ShopPurchase{Quantity: 1}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var bundle models.ShopBundle
+		if err := tx.Preload("Items.Item").
+			Where("id = ? AND is_active = ?", bundleID, true).
+			Where("(start_at IS NULL OR start_at <= ?) AND (end_at IS NULL OR end_at > ?)", now, now).
+			First(&bundle).Error; err != nil {
+			return ErrShopBundleUnavailable
+		}
+
+		var user models.User
+		if err := tx.Select("id", "level").First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		fullValue, grantedValue := 0, 0
+		for _, bi := range bundle.Items {
+			if reason := shopItemLock(tx, user, bi.Item); reason != "" {
+				return shopLockError(reason)
+			}
+			qty := 1
+			if bi.Item.Consumable && bi.Quantity > 1 {
+				qty = bi.Quantity
+			}
+			// Item gratis tetap dihitung supaya pembagian tidak nol
+			value := max(bi.Item.Price, 1) * qty
+			fullValue += value
+
+			err := GrantUserItem(tx, userID, bi.Item, qty)
+			if errors.Is(err, ErrItemOwned) {
+				purchase.Skipped = append(purchase.Skipped, bi)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			grantedValue += value
+			purchase.Granted = append(purchase.Granted, bi)
+		}
+		if len(purchase.Granted) == 0 {
+			return ErrItemOwned
+		}
+
+		purchase.Bundle = &bundle
+		purchase.Price = bundle.Price
+		if len(purchase.Skipped) > 0 {
+			purchase.Price = int(math.Round(float64(bundle.Price) * float64(grantedValue) / float64(fullValue)))
+		}
+		return chargeShopPurchase(tx, userID, purchase.Price, LedgerRef{Type: "bundle", ID: bundle.ID}, &purchase)
+	})
+	return purchase, err
+}
+
+// chargeShopPurchase memotong koin user ke akun toko lewat ledger lalu membaca saldo terbaru
+func chargeShopPurchase(tx *gorm.DB, userID uint, price int, ref LedgerRef, purchase *ShopPurchase) error {
+	if err := MoveCoins(tx, UserAccount(userID), AccountShop, price, "shop_purchase", ref); err != nil {
+		return err
+	}
+	var user models.User
+	if err := tx.Select("coins").First(&user, userID).Error; err != nil {
+		return err
+	}
+	purchase.CoinsLeft = user.Coins
+	return nil
+}
+
+// ValidateShopItem mengecek field item sebelum disimpan admin
+func ValidateShopItem(item models.Item) error {
+	if item.Name == "" || item.Price < 0 {
+		return errors.New("name is required and price cannot be negative")
+	}
+	if item.Rarity != "" {
+		if _, ok := ShopRarityWeights[item.Rarity]; !ok {
+			return errors.New("rarity must be common, rare, epic or legendary")
+		}
+	}
+	if item.MinLevel < 0 || item.MaxStack < 0 {
+		return errors.New("min_level and max_stack cannot be negative")
+	}
+	if item.RequiredAchievementID != nil {
+		var count int64
+		config.DB.Model(&models.Achievement{}).Where("id = ?", *item.RequiredAchievementID).Count(&count)
+		if count == 0 {
+			return errors.New("required achievement not found")
+		}
+	}
+	return nil
+}
@@ -1,10 +1,22 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ROFL1ST/quizzes-backend/config"
 	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Streak yang putus masih bisa di-repair 48 jam setelah hari pertama terlewat berakhir
+const streakRepairWindow = 48 * time.Hour
+
+var (
+	ErrStreakNotRepairable = errors.New("no broken streak to repair")
+	ErrStreakRepairExpired = errors.New("streak repair window has passed")
 )
 
 func DaysBetween(lastDate time.Time, nowDate time.Time) int {
@@ -19,6 +31,9 @@ func RecordActivity(userID uint) {
 	now := GetJakartaTime()
 	today := StripTime(now)
 
+	// Hari yang terlewat diproses dulu (Streak Freeze atau streak putus)
+	SettleStreak(userID)
+
 	var exists int64
 	config.DB.Model(&models.StreakLog{}).
 		Where("user_id = ? AND date = ?", userID, today).
@@ -30,7 +45,7 @@ func RecordActivity(userID uint) {
 		if err := config.DB.First(&user, userID).Error; err == nil {
 			if user.StreakCount == 0 {
 				user.StreakCount = 1
-				config.DB.Save(&user)
+				config.DB.Omit("coins").Save(&user)
 			}
 		}
 		return
@@ -39,6 +54,7 @@ func RecordActivity(userID uint) {
 	logEntry := models.StreakLog{
 		UserID: userID,
 		Date:   today,
+		Type:   "active",
 	}
 	if err := config.DB.Create(&logEntry).Error; err != nil {
 		return
@@ -63,7 +79,114 @@ func RecordActivity(userID uint) {
 
 	user.LastActivityDate = &now
 
-	config.DB.Save(&user)
+	config.DB.Omit("coins").Save(&user)
+}
+
+// SettleStreak memproses hari yang terlewat sejak aktivitas terakhir sampai kemarin.
+// Jika Streak Freeze cukup untuk semua hari itu, freeze dipakai dan hari tersebut ditandai "frozen".
+// Jika tidak, streak putus dan nilainya disimpan supaya bisa di-repair.
+func SettleStreak(userID uint) {
+	today := StripTime(GetJakartaTime())
+	frozen := 0
+
+	config.DB.Transaction(func(tx *gorm.DB) error {
+		// Row user dikunci supaya freeze tidak terpakai dua kali oleh request paralel
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "streak_count").First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.StreakCount == 0 {
+			return nil
+		}
+
+		var last models.StreakLog
+		if err := tx.Where("user_id = ? AND date < ?", userID, today).Order("date desc").First(&last).Error; err != nil {
+			return nil
+		}
+		missed := DaysBetween(last.Date, today) - 1
+		if missed <= 0 {
+			return nil
+		}
+		firstMissed := today.AddDate(0, 0, -missed)
+
+		err := ConsumeUserItem(tx, userID, ItemStreakFreeze, missed)
+		if err == nil {
+			for d := firstMissed; d.Before(today); d = d.AddDate(0, 0, 1) {
+				if err := tx.Create(&models.StreakLog{UserID: userID, Date: d, Type: "frozen"}).Error; err != nil {
+					return err
+				}
+			}
+			frozen = missed
+			return nil
+		}
+		if !errors.Is(err, ErrItemNotOwned) {
+			return err
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"streak_count":   0,
+			"lost_streak":    user.StreakCount,
+			"streak_lost_on": firstMissed,
+		}).Error
+	})
+
+	if frozen > 0 {
+		SendNotification(userID, "info", "Streak Freeze Terpakai", fmt.Sprintf("🧊 %d Streak Freeze dipakai, streak kamu aman!", frozen), "/shop")
+	}
+}
+
+// StreakRepairDeadline mengembalikan batas waktu repair untuk streak yang putus
+func StreakRepairDeadline(user models.User) (time.Time, bool) {
+	if user.LostStreak == 0 || user.StreakLostOn == nil {
+		return time.Time{}, false
+	}
+	deadline := StripTime(*user.StreakLostOn).AddDate(0, 0, 1).Add(streakRepairWindow)
+	return deadline, GetJakartaTime().Before(deadline)
+}
+
+// RepairStreak memakai satu Streak Repair untuk memulihkan streak yang putus.
+// Hari yang terlewat ditandai "repaired" dan streak lama disambung dengan streak saat ini.
+func RepairStreak(userID uint) (int, error) {
+	today := StripTime(GetJakartaTime())
+	restored := 0
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		deadline, ok := StreakRepairDeadline(user)
+		if deadline.IsZero() {
+			return ErrStreakNotRepairable
+		}
+		if !ok {
+			return ErrStreakRepairExpired
+		}
+
+		if err := ConsumeUserItem(tx, userID, ItemStreakRepair, 1); err != nil {
+			return err
+		}
+
+		for d := StripTime(*user.StreakLostOn); d.Before(today); d = d.AddDate(0, 0, 1) {
+			var exists int64
+			tx.Model(&models.StreakLog{}).Where("user_id = ? AND date = ?", userID, d).Count(&exists)
+			if exists > 0 {
+				continue
+			}
+			if err := tx.Create(&models.StreakLog{UserID: userID, Date: d, Type: "repaired"}).Error; err != nil {
+				return err
+			}
+		}
+
+		restored = user.LostStreak + user.StreakCount
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"streak_count":   restored,
+			"lost_streak":    0,
+			"streak_lost_on": nil,
+		}).Error
+	})
+	return restored, err
 }
 
 func UpdateQuizStreak(user *models.User) {
@@ -0,0 +1,647 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mrand "math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	SurvivalMaxLives       = 3
+	SurvivalMaxShields     = 3
+	SurvivalShieldEvery    = 10 // Dapat 1 tameng tiap 10 jawaban benar berturut-turut
+	survivalMediumAfter    = 5  // Soal ke-6 dst: tingkat sedang
+	survivalHardAfter      = 15 // Soal ke-16 dst: tingkat sulit
+	survivalHistoryTitle   = "Survival Mode"
+	survivalLeaderboardMax = 50
+
+	survivalOrderMax   = 500 // Panjang urutan soal run ber-seed; lebih dari ini run selesai seperti soal habis
+	survivalCandidates = 50  // Jumlah kandidat acak per soal untuk run tanpa seed
+)
+
+var (
+	ErrSurvivalRunNotActive  = errors.New("survival run is not active")
+	ErrSurvivalWrongQuestion = errors.New("question does not match current survival question")
+	ErrSurvivalNoQuestions   = errors.New("no questions available")
+	ErrSurvivalAlreadyPlayed = errors.New("survival challenge already played")
+)
+
+// SurvivalRunOptions adalah pengaturan run baru
+type SurvivalRunOptions struct {
+	Mode        string // classic, daily
+	Lives       int
+	TopicID     *uint
+	ChallengeID *uint
+	Seed        string // Seed dari server (challenge survival), bukan dari client
+}
+
+// Daily Survival #1 = 1 Januari 2024
+var survivalDailyEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SurvivalAnswerResult adalah hasil satu jawaban survival
+type SurvivalAnswerResult struct {
+	Run           models.SurvivalRun
+	Correct       bool
+	CorrectAnswer string
+	ShieldUsed    bool
+	GameOver      bool
+	NextQuestion  *models.Question
+}
+
+// SurvivalDifficulty menentukan tingkat kesulitan dari jumlah soal yang sudah dijawab.
+// Berbasis posisi (bukan streak) supaya run dengan seed yang sama selalu dapat urutan soal yang sama.
+func SurvivalDifficulty(answered int) int {
+	switch {
+	case answered >= survivalHardAfter:
+		return 3
+	case answered >= survivalMediumAfter:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// survivalAccuracyRange: rentang akurasi soal (benar / total dijawab) untuk tiap tingkat
+func survivalAccuracyRange(difficulty int) (float64, float64) {
+	switch difficulty {
+	case 3:
+		return 0, 0.5
+	case 2:
+		return 0.3, 0.75
+	default:
+		return 0.6, 1
+	}
+}
+
+// questionAccuracy: benar / total dijawab, soal yang belum pernah dijawab dianggap 0.5
+func questionAccuracy(q models.Question) float64 {
+	total := q.CorrectCount + q.IncorrectCount
+	if total == 0 {
+		return 0.5
+	}
+	return float64(q.CorrectCount) / float64(total)
+}
+
+// survivalQuestionPool adalah soal yang boleh muncul di run (semua soal atau satu topik)
+func survivalQuestionPool(tx *gorm.DB, topicID *uint) *gorm.DB {
+	query := tx.Model(&models.Question{})
+	if topicID != nil {
+		query = query.Where("quiz_id IN (?)", tx.Model(&models.Quiz{}).Select("id").Where("topic_id = ?", *topicID))
+	}
+	return query
+}
+
+// SurvivalSeedOrder menghitung urutan soal deterministik MD5(id || seed). Dipanggil sekali saat
+// run (atau seed daily) dibuat, bukan tiap jawaban.
+func SurvivalSeedOrder(tx *gorm.DB, seed string, topicID *uint) (pq.Int64Array, error) {
+	var ids []int64
+	err := survivalQuestionPool(tx, topicID).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "MD5(CAST(id AS TEXT) || ?)",
+			Vars:               []interface{}{seed},
+			WithoutParentheses: true,
+		}}).
+		Limit(survivalOrderMax).
+		Pluck("id", &ids).Error
+	return pq.Int64Array(ids), err
+}
+
+// NextSurvivalQuestion memilih soal berikutnya yang belum pernah muncul di run ini.
+// Run ber-seed (daily & challenge) mengikuti QuestionOrder yang dihitung saat run dibuat, tanpa filter
+// akurasi: akurasi soal berubah tiap ada jawaban, jadi urutan untuk seed yang sama tidak boleh bergantung padanya.
+// Run tanpa seed mengambil sampel acak terbatas lalu memilih soal sesuai tingkat kesulitan;
+// jika tidak ada yang cocok, ambil kandidat apa saja.
+func NextSurvivalQuestion(tx *gorm.DB, run *models.SurvivalRun) (models.Question, error) {
+	var seenIDs []uint
+	tx.Model(&models.SurvivalSeenQuestion{}).Where("run_id = ?", run.ID).Pluck("question_id", &seenIDs)
+	seen := make(map[uint]bool, len(seenIDs))
+	for _, id := range seenIDs {
+		seen[id] = true
+	}
+
+	if run.Seed != "" {
+		return nextSeededSurvivalQuestion(tx, run, seen)
+	}
+	return randomSurvivalQuestion(tx, run, seen)
+}
+
+// nextSeededSurvivalQuestion memajukan cursor sampai ketemu soal yang masih ada & belum muncul
+func nextSeededSurvivalQuestion(tx *gorm.DB, run *models.SurvivalRun, seen map[uint]bool) (models.Question, error) {
+	// Run lama sebelum urutan disimpan: hitung sekali sekarang
+	if run.QuestionOrder == nil {
+		order, err := SurvivalSeedOrder(tx, run.Seed, run.TopicID)
+		if err != nil {
+			return models.Question{}, err
+		}
+		run.QuestionOrder = order
+	}
+
+	for run.OrderCursor < len(run.QuestionOrder) {
+		id := uint(run.QuestionOrder[run.OrderCursor])
+		run.OrderCursor++
+		if seen[id] {
+			continue
+		}
+		var question models.Question
+		if err := tx.First(&question, id).Error; err == nil {
+			return question, nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return question, err
+		}
+		// Soal sudah dihapus sejak urutan dibuat: lewati
+	}
+	return models.Question{}, ErrSurvivalNoQuestions
+}
+
+// randomSurvivalQuestion mengambil kandidat dari posisi id acak (lewat index primary key,
+// bukan ORDER BY RANDOM() di seluruh tabel) lalu memilih yang sesuai tingkat kesulitan
+func randomSurvivalQuestion(tx *gorm.DB, run *models.SurvivalRun, seen map[uint]bool) (models.Question, error) {
+	var bounds struct {
+		MinID uint
+		MaxID uint
+	}
+	survivalQuestionPool(tx, run.TopicID).Select("MIN(id) AS min_id, MAX(id) AS max_id").Scan(&bounds)
+	if bounds.MaxID == 0 {
+		return models.Question{}, ErrSurvivalNoQuestions
+	}
+	pivot := bounds.MinID + uint(mrand.Int63n(int64(bounds.MaxID-bounds.MinID)+1))
+
+	candidates := func(where string) []models.Question {
+		var questions []models.Question
+		query := survivalQuestionPool(tx, run.TopicID).Where(where, pivot)
+		if len(seen) > 0 {
+			query = query.Where("id NOT IN ?", mapKeys(seen))
+		}
+		query.Order("id").Limit(survivalCandidates).Find(&questions)
+		return questions
+	}
+	pool := candidates("id >= ?")
+	if len(pool) < survivalCandidates {
+		// Pivot dekat ujung: lanjutkan dari awal
+		pool = append(pool, candidates("id < ?")...)
+	}
+	if len(pool) == 0 {
+		return models.Question{}, ErrSurvivalNoQuestions
+	}
+
+	mrand.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+	minAcc, maxAcc := survivalAccuracyRange(run.Difficulty)
+	for _, q := range pool {
+		if acc := questionAccuracy(q); acc >= minAcc && acc <= maxAcc {
+			return q, nil
+		}
+	}
+	return pool[0], nil
+}
+
+func mapKeys(m map[uint]bool) []uint {
+	keys := make([]uint, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// StartSurvivalRun membuat run baru. Run aktif sebelumnya milik user (selain run challenge) ditandai abandoned.
+// Satu run per (user, challenge): seed challenge tidak bisa diulang setelah kunci jawaban terlihat.
+// Mode daily selalu 1 nyawa, tanpa filter topik, dan hanya percobaan pertama per hari yang ranked.
+func StartSurvivalRun(userID uint, opts SurvivalRunOptions) (models.SurvivalRun, models.Question, error) {
+	lives := opts.Lives
+	if lives < 1 {
+		lives = 1
+	}
+	if lives > SurvivalMaxLives {
+		lives = SurvivalMaxLives
+	}
+
+	run := models.SurvivalRun{
+		UserID:      userID,
+		Mode:        "classic",
+		Seed:        opts.Seed,
+		TopicID:     opts.TopicID,
+		ChallengeID: opts.ChallengeID,
+		Status:      "active",
+		Lives:       lives,
+		MaxLives:    lives,
+		Difficulty:  SurvivalDifficulty(0),
+	}
+
+	var dailyOrder pq.Int64Array
+	if opts.Mode == "daily" {
+		daily, err := GetDailySurvivalSeed()
+		if err != nil {
+			return run, models.Question{}, err
+		}
+		run.Mode = "daily"
+		run.Seed = daily.Seed
+		run.DailyDate = &daily.Date
+		run.TopicID = nil
+		run.ChallengeID = nil
+		run.Lives, run.MaxLives = 1, 1
+		dailyOrder = daily.QuestionOrder
+	}
+
+	var question models.Question
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if run.ChallengeID != nil {
+			// Kunci baris peserta supaya dua request start paralel tidak membuat dua run
+			var participant models.ChallengeParticipant
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("challenge_id = ? AND user_id = ?", *run.ChallengeID, userID).
+				First(&participant).Error; err != nil {
+				return err
+			}
+			var runs int64
+			tx.Model(&models.SurvivalRun{}).Where("user_id = ? AND challenge_id = ?", userID, *run.ChallengeID).Count(&runs)
+			if runs > 0 {
+				return ErrSurvivalAlreadyPlayed
+			}
+		}
+
+		// Run challenge tetap aktif supaya bisa dilanjutkan, bukan dibuang
+		if err := tx.Model(&models.SurvivalRun{}).
+			Where("user_id = ? AND status = ? AND challenge_id IS NULL", userID, "active").
+			Update("status", "abandoned").Error; err != nil {
+			return err
+		}
+
+		// Urutan soal ber-seed dihitung sekali di sini; daily memakai urutan milik seed hari itu
+		if run.Mode == "daily" && dailyOrder != nil {
+			run.QuestionOrder = dailyOrder
+		} else if run.Seed != "" {
+			order, err := SurvivalSeedOrder(tx, run.Seed, run.TopicID)
+			if err != nil {
+				return err
+			}
+			run.QuestionOrder = order
+		}
+
+		if run.Mode == "daily" {
+			var attempts int64
+			tx.Model(&models.SurvivalRun{}).
+				Where("user_id = ? AND mode = ? AND daily_date = ?", userID, "daily", *run.DailyDate).
+				Count(&attempts)
+			run.Unranked = attempts > 0
+		}
+
+		if err := tx.Create(&run).Error; err != nil {
+			return err
+		}
+
+		var err error
+		question, err = NextSurvivalQuestion(tx, &run)
+		if err != nil {
+			return err
+		}
+		return serveSurvivalQuestion(tx, &run, question)
+	})
+
+	return run, question, err
+}
+
+// SurvivalRunRanked: run yang masuk leaderboard survival umum (classic, bukan run challenge)
+func SurvivalRunRanked(run models.SurvivalRun) bool {
+	return run.Mode == "classic" && run.ChallengeID == nil && !run.Unranked
+}
+
+// NewSurvivalSeed membuat seed acak untuk urutan soal survival
+func NewSurvivalSeed() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// GetDailySurvivalSeed mengambil (atau membuat) seed Daily Survival hari ini (waktu Jakarta)
+func GetDailySurvivalSeed() (models.SurvivalDailySeed, error) {
+	today := StripTime(GetJakartaTime())
+	date := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	var daily models.SurvivalDailySeed
+	err := config.DB.Where("date = ?", date).First(&daily).Error
+	if err == nil {
+		// Seed yang dibuat sebelum urutan disimpan: lengkapi sekali
+		if daily.QuestionOrder == nil {
+			if daily.QuestionOrder, err = SurvivalSeedOrder(config.DB, daily.Seed, nil); err != nil {
+				return daily, err
+			}
+			config.DB.Model(&daily).Update("question_order", daily.QuestionOrder)
+		}
+		return daily, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return daily, err
+	}
+
+	// Urutan soal dihitung sekali per hari bersama seed-nya.
+	// Request paralel di awal hari: yang kalah unique index membaca seed pemenang
+	daily = models.SurvivalDailySeed{Date: date, Seed: NewSurvivalSeed()}
+	if daily.QuestionOrder, err = SurvivalSeedOrder(config.DB, daily.Seed, nil); err != nil {
+		return daily, err
+	}
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&daily).Error; err != nil {
+		return daily, err
+	}
+	err = config.DB.Where("date = ?", date).First(&daily).Error
+	return daily, err
+}
+
+// SurvivalDailyNumber adalah nomor urut Daily Survival (untuk teks share)
+func SurvivalDailyNumber(date time.Time) int {
+	d := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return int(d.Sub(survivalDailyEpoch).Hours()/24) + 1
+}
+
+// SurvivalShareText membuat ringkasan hasil ala Wordle, tanpa membocorkan soal
+func SurvivalShareText(run models.SurvivalRun) string {
+	var seen []models.SurvivalSeenQuestion
+	config.DB.Where("run_id = ? AND correct IS NOT NULL", run.ID).Order("id ASC").Find(&seen)
+
+	var grid strings.Builder
+	for i, s := range seen {
+		if i > 0 && i%10 == 0 {
+			grid.WriteString("\n")
+		}
+		if s.Correct != nil && *s.Correct {
+			grid.WriteString("🟩")
+		} else {
+			grid.WriteString("🟥")
+		}
+	}
+
+	title := "Survival"
+	if run.Mode == "daily" && run.DailyDate != nil {
+		title = fmt.Sprintf("Daily Survival #%d", SurvivalDailyNumber(*run.DailyDate))
+	}
+	return fmt.Sprintf("%s 🔥 %d benar (streak terbaik %d)\n%s", title, run.Score, run.BestStreak, grid.String())
+}
+
+// AnswerSurvivalRun menilai jawaban untuk soal yang sedang aktif di run.
+// Streak, nyawa, dan tameng dihitung di server; client tidak bisa mengirim skor sendiri.
+func AnswerSurvivalRun(runID uint, userID uint, questionID uint, answer string) (SurvivalAnswerResult, error) {
+	var result SurvivalAnswerResult
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var run models.SurvivalRun
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", runID, userID).First(&run).Error; err != nil {
+			return err
+		}
+		if run.Status != "active" {
+			return ErrSurvivalRunNotActive
+		}
+		if run.CurrentQuestionID == nil || *run.CurrentQuestionID != questionID {
+			return ErrSurvivalWrongQuestion
+		}
+
+		var question models.Question
+		if err := tx.First(&question, questionID).Error; err != nil {
+			return err
+		}
+
+		isCorrect := IsAnswerCorrect(question, answer)
+		result.Correct = isCorrect
+
+		if err := tx.Model(&models.SurvivalSeenQuestion{}).
+			Where("run_id = ? AND question_id = ?", run.ID, question.ID).
+			Updates(map[string]interface{}{"answer": answer, "correct": isCorrect}).Error; err != nil {
+			return err
+		}
+
+		// Statistik soal ikut dipakai untuk ramp kesulitan
+		statColumn := "incorrect_count"
+		if isCorrect {
+			statColumn = "correct_count"
+		}
+		tx.Model(&models.Question{}).Where("id = ?", question.ID).UpdateColumn(statColumn, gorm.Expr(statColumn+" + 1"))
+
+		run.Answered++
+		if isCorrect {
+			run.Score++
+			run.Streak++
+			if run.Streak > run.BestStreak {
+				run.BestStreak = run.Streak
+			}
+			if run.Streak%SurvivalShieldEvery == 0 && run.Shields < SurvivalMaxShields {
+				run.Shields++
+			}
+		} else {
+			result.CorrectAnswer = question.CorrectAnswer
+			if run.Shields > 0 {
+				// Tameng menahan satu jawaban salah: nyawa & streak aman
+				run.Shields--
+				result.ShieldUsed = true
+			} else {
+				run.Lives--
+				run.Streak = 0
+			}
+		}
+
+		if run.Lives <= 0 {
+			result.GameOver = true
+			run.CurrentQuestionID = nil
+			return finishSurvivalRun(tx, &run)
+		}
+
+		run.Difficulty = SurvivalDifficulty(run.Answered)
+		next, err := NextSurvivalQuestion(tx, &run)
+		if errors.Is(err, ErrSurvivalNoQuestions) {
+			// Semua soal sudah dijawab: run selesai dengan skor penuh
+			result.GameOver = true
+			run.CurrentQuestionID = nil
+			return finishSurvivalRun(tx, &run)
+		}
+		if err != nil {
+			return err
+		}
+		result.NextQuestion = &next
+		return serveSurvivalQuestion(tx, &run, next)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if err := config.DB.First(&result.Run, runID).Error; err != nil {
+		return result, err
+	}
+	if result.GameOver {
+		afterSurvivalRunFinished(result.Run)
+	}
+	return result, nil
+}
+
+// serveSurvivalQuestion menandai soal sebagai sudah muncul dan menyimpannya sebagai soal aktif
+func serveSurvivalQuestion(tx *gorm.DB, run *models.SurvivalRun, question models.Question) error {
+	if err := tx.Create(&models.SurvivalSeenQuestion{RunID: run.ID, QuestionID: question.ID}).Error; err != nil {
+		return err
+	}
+	run.CurrentQuestionID = &question.ID
+	return tx.Save(run).Error
+}
+
+// finishSurvivalRun menutup run dan menyimpan History dari data server
+func finishSurvivalRun(tx *gorm.DB, run *models.SurvivalRun) error {
+	var seen []models.SurvivalSeenQuestion
+	tx.Where("run_id = ? AND correct IS NOT NULL", run.ID).Order("id ASC").Find(&seen)
+
+	snapshot := make(map[string]string)
+	for _, s := range seen {
+		snapshot[strconv.Itoa(int(s.QuestionID))] = s.Answer
+	}
+	snapshotJSON, _ := json.Marshal(snapshot)
+
+	now := time.Now()
+	history := models.History{
+		UserID:    run.UserID,
+		QuizID:    0,
+		QuizTitle: survivalHistoryTitle,
+		Score:     run.Score,
+		TotalSoal: run.Answered,
+		TimeTaken: int(now.Sub(run.CreatedAt).Seconds()),
+		Snapshot:  datatypes.JSON(snapshotJSON),
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return err
+	}
+
+	run.Status = "finished"
+	run.FinishedAt = &now
+	run.HistoryID = &history.ID
+	return tx.Save(run).Error
+}
+
+// afterSurvivalRunFinished: XP, aktivitas, misi, dan skor challenge survival (jika ada)
+func afterSurvivalRunFinished(run models.SurvivalRun) {
+	var topicID uint
+	if run.TopicID != nil {
+		topicID = *run.TopicID
+	}
+	xp := ApplyXPEvents(run.Score, topicID)
+	RecordActivity(run.UserID)
+
+	if run.HistoryID != nil {
+		var history models.History
+		if err := config.DB.First(&history, *run.HistoryID).Error; err == nil {
+			// Run yang terlalu cepat masuk antrean review: XP & misi ditahan
+			rules := GetAntiFarmingRules()
+			if reason, details := DetectFarming(run.Answered, history.TimeTaken, run.Score == run.Answered, rules); reason != "" {
+				FlagFarming(models.FarmingFlag{
+					UserID:          run.UserID,
+					HistoryID:       &history.ID,
+					Source:          XPSourceSurvival,
+					Reason:          reason,
+					Details:         details,
+					WithheldXP:      xp,
+					MissionWithheld: true,
+				})
+			} else {
+				granted := AwardXP(run.UserID, XPSourceSurvival, xp)
+				config.DB.Model(&history).Update("xp_gained", granted)
+				TrackMissionEvent(run.UserID, QuizFinishedMissionEvent(history))
+			}
+		}
+	}
+
+	if run.ChallengeID != nil {
+		var user models.User
+		config.DB.First(&user, run.UserID)
+		BroadcastLobby(*run.ChallengeID, "player_finished", map[string]interface{}{
+			"user_id":  run.UserID,
+			"username": user.Name,
+			"score":    run.Score,
+			"status":   "finished",
+		})
+
+		timeTaken := 0
+		if run.FinishedAt != nil {
+			timeTaken = int(run.FinishedAt.Sub(run.CreatedAt).Seconds())
+		}
+		SubmitChallengeScore(*run.ChallengeID, run.UserID, run.Score, timeTaken)
+	}
+
+	go FireAchievementEvent(run.UserID, AchievementQuizFinished)
+}
+
+// SurvivalLeaderboardEntry adalah skor terbaik satu user pada periode tertentu
+type SurvivalLeaderboardEntry struct {
+	UserID     uint   `json:"user_id"`
+	Username   string `json:"username"`
+	Name       string `json:"name"`
+	BestScore  int    `json:"best_score"`
+	BestStreak int    `json:"best_streak"`
+	Runs       int    `json:"runs"`
+}
+
+// GetSurvivalLeaderboard: period = daily, weekly, atau all_time (waktu Jakarta).
+// topicID nil = leaderboard umum (semua soal), selain itu leaderboard per topik.
+// Run Daily Survival punya leaderboard sendiri (GetDailySurvivalLeaderboard).
+func GetSurvivalLeaderboard(period string, topicID *uint, lives int) []SurvivalLeaderboardEntry {
+	query := config.DB.Table("survival_runs").
+		Select("survival_runs.user_id, users.username, users.name, MAX(survival_runs.score) AS best_score, MAX(survival_runs.best_streak) AS best_streak, COUNT(*) AS runs").
+		Joins("JOIN users ON users.id = survival_runs.user_id").
+		Where("survival_runs.status = ? AND survival_runs.deleted_at IS NULL", "finished").
+		Where("survival_runs.mode = ? AND survival_runs.unranked = ?", "classic", false).
+		Where("survival_runs.challenge_id IS NULL"). // Run challenge bisa memakai extra life
+		Where("survival_runs.max_lives = ?", lives)  // Run 1 nyawa & 3 nyawa tidak dibandingkan
+
+	if topicID != nil {
+		query = query.Where("survival_runs.topic_id = ?", *topicID)
+	} else {
+		query = query.Where("survival_runs.topic_id IS NULL")
+	}
+
+	today := StripTime(GetJakartaTime())
+	switch period {
+	case "daily":
+		query = query.Where("survival_runs.finished_at >= ?", today)
+	case "weekly":
+		// Minggu dimulai hari Senin
+		offset := (int(today.Weekday()) + 6) % 7
+		query = query.Where("survival_runs.finished_at >= ?", today.AddDate(0, 0, -offset))
+	}
+
+	var entries []SurvivalLeaderboardEntry
+	query.Group("survival_runs.user_id, users.username, users.name").
+		Order("best_score DESC, best_streak DESC").
+		Limit(survivalLeaderboardMax).
+		Scan(&entries)
+	return entries
+}
+
+// DailySurvivalEntry adalah hasil ranked satu user di Daily Survival
+type DailySurvivalEntry struct {
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	Name      string `json:"name"`
+	Score     int    `json:"score"`
+	TimeTaken int    `json:"time_taken"`
+}
+
+// GetDailySurvivalLeaderboard: satu percobaan ranked per user, seri dipecah dengan waktu tercepat
+func GetDailySurvivalLeaderboard(date time.Time) []DailySurvivalEntry {
+	var entries []DailySurvivalEntry
+	config.DB.Table("survival_runs").
+		Select("survival_runs.user_id, users.username, users.name, survival_runs.score, CAST(EXTRACT(EPOCH FROM (survival_runs.finished_at - survival_runs.created_at)) AS INTEGER) AS time_taken").
+		Joins("JOIN users ON users.id = survival_runs.user_id").
+		Where("survival_runs.status = ? AND survival_runs.deleted_at IS NULL", "finished").
+		Where("survival_runs.mode = ? AND survival_runs.daily_date = ? AND survival_runs.unranked = ?", "daily", date, false).
+		Order("survival_runs.score DESC, time_taken ASC").
+		Limit(survivalLeaderboardMax).
+		Scan(&entries)
+	return entries
+}
@@ -0,0 +1,708 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTournamentNotOpen    = errors.New("tournament is not open for registration")
+	ErrTournamentFull       = errors.New("tournament is full")
+	ErrTournamentJoined     = errors.New("already joined this tournament")
+	ErrTournamentNotJoined  = errors.New("not registered in this tournament")
+	ErrTournamentTooFew     = errors.New("tournament needs at least 2 players")
+	ErrTournamentNotRunning = errors.New("tournament cannot be changed in its current status")
+)
+
+const tournamentJobInterval = time.Minute
+
+type tournamentNotice struct {
+	UserID  uint
+	Title   string
+	Message string
+	Link    string
+}
+
+// StartTournamentJob memulai turnamen yang sudah lewat jadwal StartAt
+func StartTournamentJob() {
+	go func() {
+		ticker := time.NewTicker(tournamentJobInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			StartScheduledTournaments()
+		}
+	}()
+}
+
+// StartScheduledTournaments memulai turnamen terjadwal; yang pesertanya kurang dibatalkan
+func StartScheduledTournaments() {
+	var due []models.Tournament
+	config.DB.Where("status = ? AND start_at IS NOT NULL AND start_at < ?", "registration", time.Now()).Find(&due)
+
+	for _, t := range due {
+		if err := StartTournament(t.ID); errors.Is(err, ErrTournamentTooFew) {
+			CancelTournament(t.ID)
+		}
+	}
+}
+
+// JoinTournament mendaftarkan user dan memotong entry fee ke prize pool
+func JoinTournament(tournamentID uint, userID uint) error {
+	var tournament models.Tournament
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tournament, tournamentID).Error; err != nil {
+			return err
+		}
+		if tournament.Status != "registration" {
+			return ErrTournamentNotOpen
+		}
+
+		var count int64
+		tx.Model(&models.TournamentEntry{}).Where("tournament_id = ? AND user_id = ?", tournamentID, userID).Count(&count)
+		if count > 0 {
+			return ErrTournamentJoined
+		}
+
+		tx.Model(&models.TournamentEntry{}).Where("tournament_id = ?", tournamentID).Count(&count)
+		if tournament.MaxPlayers > 0 && int(count) >= tournament.MaxPlayers {
+			return ErrTournamentFull
+		}
+
+		if tournament.EntryFee > 0 {
+			if err := MoveCoins(tx, UserAccount(userID), TournamentAccount(tournamentID), tournament.EntryFee,
+				"tournament_entry", LedgerRef{Type: "tournament", ID: tournamentID}); err != nil {
+				return err
+			}
+			if err := tx.Model(&tournament).UpdateColumn("prize_pool", gorm.Expr("prize_pool + ?", tournament.EntryFee)).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.TournamentEntry{TournamentID: tournamentID, UserID: userID}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	SendNotification(userID, "success", "Terdaftar di Turnamen", "🏆 Kamu terdaftar di turnamen "+tournament.Name, tournamentLink(tournamentID))
+	return nil
+}
+
+// LeaveTournament membatalkan pendaftaran (hanya saat registrasi) dan mengembalikan entry fee
+func LeaveTournament(tournamentID uint, userID uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var tournament models.Tournament
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tournament, tournamentID).Error; err != nil {
+			return err
+		}
+		if tournament.Status != "registration" {
+			return ErrTournamentNotOpen
+		}
+
+		// Unscoped supaya user bisa daftar ulang (unique index tournament+user)
+		res := tx.Unscoped().Where("tournament_id = ? AND user_id = ?", tournamentID, userID).Delete(&models.TournamentEntry{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrTournamentNotJoined
+		}
+
+		if tournament.EntryFee > 0 {
+			if err := MoveCoins(tx, TournamentAccount(tournamentID), UserAccount(userID), tournament.EntryFee,
+				"tournament_refund", LedgerRef{Type: "tournament", ID: tournamentID}); err != nil {
+				return err
+			}
+			return tx.Model(&tournament).UpdateColumn("prize_pool", gorm.Expr("prize_pool - ?", tournament.EntryFee)).Error
+		}
+		return nil
+	})
+}
+
+// CancelTournament membatalkan turnamen, mengembalikan entry fee & menutup match yang masih jalan
+func CancelTournament(tournamentID uint) error {
+	var entries []models.TournamentEntry
+	var tournament models.Tournament
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tournament, tournamentID).Error; err != nil {
+			return err
+		}
+		if tournament.Status != "registration" && tournament.Status != "running" {
+			return ErrTournamentNotRunning
+		}
+
+		tx.Where("tournament_id = ?", tournamentID).Find(&entries)
+		if tournament.EntryFee > 0 {
+			for _, e := range entries {
+				if err := MoveCoins(tx, TournamentAccount(tournamentID), UserAccount(e.UserID), tournament.EntryFee,
+					"tournament_refund", LedgerRef{Type: "tournament", ID: tournamentID}); err != nil {
+					return err
+				}
+			}
+		}
+		// Sisa pool (hadiah dasar admin) kembali ke akun hadiah
+		if err := sweepTournamentPool(tx, tournamentID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Challenge{}).
+			Where("tournament_id = ? AND status IN ?", tournamentID, []string{"pending", "active"}).
+			Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TournamentMatch{}).
+			Where("tournament_id = ? AND status = ?", tournamentID, "active").
+			Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&tournament).Updates(map[string]interface{}{"status": "cancelled", "prize_pool": 0}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		SendNotification(e.UserID, "info", "Turnamen Dibatalkan", "Turnamen "+tournament.Name+" dibatalkan, entry fee dikembalikan.", tournamentLink(tournamentID))
+	}
+	return nil
+}
+
+// StartTournament menentukan seed peserta lalu membuat ronde pertama
+func StartTournament(tournamentID uint) error {
+	var notices []tournamentNotice
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var tournament models.Tournament
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tournament, tournamentID).Error; err != nil {
+			return err
+		}
+		if tournament.Status != "registration" {
+			return ErrTournamentNotOpen
+		}
+
+		entries, err := seedTournamentEntries(tx, tournament)
+		if err != nil {
+			return err
+		}
+		if len(entries) < 2 {
+			return ErrTournamentTooFew
+		}
+
+		if tournament.Format == "swiss" && tournament.SwissRounds <= 0 {
+			// Default jumlah ronde Swiss = ceil(log2(jumlah pemain))
+			rounds := 0
+			for n := 1; n < len(entries); n *= 2 {
+				rounds++
+			}
+			tournament.SwissRounds = rounds
+		}
+
+		tournament.Status = "running"
+		tournament.CurrentRound = 1
+		if err := tx.Save(&tournament).Error; err != nil {
+			return err
+		}
+
+		notices, err = createTournamentRound(tx, tournament, entries)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	sendTournamentNotices(notices)
+	return nil
+}
+
+// AdvanceTournamentMatch dipanggil setelah challenge match turnamen selesai (dari DetermineWinner).
+// Mencatat hasil, lalu membuat ronde berikutnya atau menutup turnamen jika ronde sudah lengkap.
+func AdvanceTournamentMatch(challenge models.Challenge) {
+	if challenge.TournamentID == nil {
+		return
+	}
+
+	var notices []tournamentNotice
+
+	config.DB.Transaction(func(tx *gorm.DB) error {
+		var tournament models.Tournament
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tournament, *challenge.TournamentID).Error; err != nil {
+			return err
+		}
+		if tournament.Status != "running" {
+			return nil
+		}
+
+		var match models.TournamentMatch
+		if err := tx.Where("challenge_id = ? AND status = ?", challenge.ID, "active").First(&match).Error; err != nil {
+			return nil
+		}
+		if match.PlayerAID == nil || match.PlayerBID == nil {
+			return nil
+		}
+
+		var entries []models.TournamentEntry
+		tx.Where("tournament_id = ? AND user_id IN ?", tournament.ID, []uint{*match.PlayerAID, *match.PlayerBID}).Find(&entries)
+		if len(entries) != 2 {
+			return nil
+		}
+		a, b := &entries[0], &entries[1]
+		if a.UserID != *match.PlayerAID {
+			a, b = b, a
+		}
+
+		winnerID := challenge.WinnerID
+		if winnerID == nil && tournament.Format != "swiss" {
+			// Eliminasi tidak boleh seri: seed lebih tinggi yang lolos
+			better := a.UserID
+			if b.Seed < a.Seed {
+				better = b.UserID
+			}
+			winnerID = &better
+		}
+
+		lossLimit := 1
+		if tournament.Format == "double_elimination" {
+			lossLimit = 2
+		}
+
+		if winnerID == nil {
+			a.Draws++
+			b.Draws++
+			a.Points++
+			b.Points++
+		} else {
+			winner, loser := a, b
+			if *winnerID == b.UserID {
+				winner, loser = b, a
+			}
+			winner.Wins++
+			winner.Points += 2
+			loser.Losses++
+			if tournament.Format != "swiss" && loser.Losses >= lossLimit {
+				loser.Eliminated = true
+				loser.EliminatedRound = match.Round
+			}
+			notices = append(notices, tournamentNotice{loser.UserID, "Match Turnamen Selesai", "Kamu kalah di ronde " + strconv.Itoa(match.Round) + " turnamen " + tournament.Name, tournamentLink(tournament.ID)})
+			notices = append(notices, tournamentNotice{winner.UserID, "Match Turnamen Selesai", "🎉 Kamu menang di ronde " + strconv.Itoa(match.Round) + " turnamen " + tournament.Name, tournamentLink(tournament.ID)})
+		}
+
+		for _, e := range []*models.TournamentEntry{a, b} {
+			if err := tx.Save(e).Error; err != nil {
+				return err
+			}
+		}
+
+		match.WinnerID = winnerID
+		match.Status = "finished"
+		if err := tx.Save(&match).Error; err != nil {
+			return err
+		}
+
+		// Ronde belum lengkap
+		var remaining int64
+		tx.Model(&models.TournamentMatch{}).
+			Where("tournament_id = ? AND round = ? AND status = ?", tournament.ID, match.Round, "active").
+			Count(&remaining)
+		if remaining > 0 || match.Round != tournament.CurrentRound {
+			return nil
+		}
+
+		var all []models.TournamentEntry
+		tx.Where("tournament_id = ?", tournament.ID).Find(&all)
+
+		if tournamentIsOver(tournament, all) {
+			finished, err := finishTournament(tx, tournament, all)
+			notices = append(notices, finished...)
+			return err
+		}
+
+		tournament.CurrentRound++
+		if err := tx.Model(&tournament).Update("current_round", tournament.CurrentRound).Error; err != nil {
+			return err
+		}
+
+		created, err := createTournamentRound(tx, tournament, all)
+		notices = append(notices, created...)
+		return err
+	})
+
+	sendTournamentNotices(notices)
+}
+
+// TournamentStandings mengurutkan peserta: Swiss berdasarkan poin, eliminasi berdasarkan
+// seberapa jauh bertahan. Tie-break: menang terbanyak lalu seed.
+func TournamentStandings(tournament models.Tournament, entries []models.TournamentEntry) []models.TournamentEntry {
+	sorted := append([]models.TournamentEntry(nil), entries...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.FinalRank > 0 && b.FinalRank > 0 {
+			return a.FinalRank < b.FinalRank
+		}
+		if tournament.Format == "swiss" {
+			if a.Points != b.Points {
+				return a.Points > b.Points
+			}
+		} else {
+			if a.Eliminated != b.Eliminated {
+				return !a.Eliminated
+			}
+			if a.EliminatedRound != b.EliminatedRound {
+				return a.EliminatedRound > b.EliminatedRound
+			}
+			if a.Losses != b.Losses {
+				return a.Losses < b.Losses
+			}
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		return a.Seed < b.Seed
+	})
+
+	return sorted
+}
+
+// seedTournamentEntries memberi seed berdasarkan level (level, XP) atau rating (jumlah kemenangan challenge)
+func seedTournamentEntries(tx *gorm.DB, tournament models.Tournament) ([]models.TournamentEntry, error) {
+	var entries []models.TournamentEntry
+	if err := tx.Preload("User").Where("tournament_id = ?", tournament.ID).Order("created_at ASC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	rating := make(map[uint]int64)
+	if tournament.SeedBy == "rating" && len(entries) > 0 {
+		userIDs := make([]uint, 0, len(entries))
+		for _, e := range entries {
+			userIDs = append(userIDs, e.UserID)
+		}
+
+		var rows []struct {
+			WinnerID uint
+			Total    int64
+		}
+		tx.Model(&models.Challenge{}).
+			Select("winner_id, COUNT(*) AS total").
+			Where("status = ? AND winner_id IN ?", "finished", userIDs).
+			Group("winner_id").
+			Scan(&rows)
+		for _, r := range rows {
+			rating[r.WinnerID] = r.Total
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if tournament.SeedBy == "rating" && rating[a.UserID] != rating[b.UserID] {
+			return rating[a.UserID] > rating[b.UserID]
+		}
+		if a.User.Level != b.User.Level {
+			return a.User.Level > b.User.Level
+		}
+		return a.User.XP > b.User.XP
+	})
+
+	for i := range entries {
+		entries[i].Seed = i + 1
+		if err := tx.Model(&entries[i]).Update("seed", entries[i].Seed).Error; err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func tournamentIsOver(tournament models.Tournament, entries []models.TournamentEntry) bool {
+	if tournament.Format == "swiss" {
+		return tournament.CurrentRound >= tournament.SwissRounds
+	}
+
+	alive := 0
+	for _, e := range entries {
+		if !e.Eliminated {
+			alive++
+		}
+	}
+	return alive <= 1
+}
+
+// createTournamentRound membuat match untuk tournament.CurrentRound
+func createTournamentRound(tx *gorm.DB, tournament models.Tournament, entries []models.TournamentEntry) ([]tournamentNotice, error) {
+	type pairing struct {
+		Bracket string
+		A       models.TournamentEntry
+		B       *models.TournamentEntry // nil = bye
+	}
+	var pairings []pairing
+
+	if tournament.Format == "swiss" {
+		played := make(map[[2]uint]bool)
+		var previous []models.TournamentMatch
+		tx.Where("tournament_id = ? AND player_b_id IS NOT NULL", tournament.ID).Find(&previous)
+		for _, m := range previous {
+			played[[2]uint{*m.PlayerAID, *m.PlayerBID}] = true
+			played[[2]uint{*m.PlayerBID, *m.PlayerAID}] = true
+		}
+
+		pool := TournamentStandings(tournament, entries)
+		if len(pool)%2 == 1 {
+			var bye models.TournamentEntry
+			pool, bye = takeSwissBye(pool)
+			pairings = append(pairings, pairing{Bracket: "swiss", A: bye})
+		}
+
+		// Pasangkan pemain dengan poin berdekatan, hindari rematch jika memungkinkan
+		paired := make([]bool, len(pool))
+		for i := range pool {
+			if paired[i] {
+				continue
+			}
+			opponent := -1
+			for j := i + 1; j < len(pool); j++ {
+				if paired[j] {
+					continue
+				}
+				if opponent == -1 {
+					opponent = j // fallback jika semua lawan sudah pernah bertemu
+				}
+				if !played[[2]uint{pool[i].UserID, pool[j].UserID}] {
+					opponent = j
+					break
+				}
+			}
+			if opponent == -1 {
+				break
+			}
+			paired[i], paired[opponent] = true, true
+			b := pool[opponent]
+			pairings = append(pairings, pairing{Bracket: "swiss", A: pool[i], B: &b})
+		}
+	} else {
+		// Kelompokkan pemain yang masih hidup berdasarkan jumlah kalah:
+		// 0 kalah = winners bracket, 1 kalah (double elim) = losers bracket
+		var winners, losers []models.TournamentEntry
+		for _, e := range entries {
+			if e.Eliminated {
+				continue
+			}
+			if e.Losses == 0 {
+				winners = append(winners, e)
+			} else {
+				losers = append(losers, e)
+			}
+		}
+
+		if len(winners)+len(losers) == 2 {
+			// Grand final (termasuk bracket reset jika juara losers bracket menang)
+			both := append(winners, losers...)
+			pairings = append(pairings, pairing{Bracket: "final", A: both[0], B: &both[1]})
+		} else {
+			// Pemain yang sendirian di bracket-nya menunggu bracket lain
+			for _, group := range []struct {
+				Name    string
+				Players []models.TournamentEntry
+			}{{"winners", winners}, {"losers", losers}} {
+				if len(group.Players) < 2 {
+					continue
+				}
+				players := group.Players
+				sort.SliceStable(players, func(i, j int) bool { return players[i].Seed < players[j].Seed })
+
+				if len(players)%2 == 1 {
+					var bye models.TournamentEntry
+					players, bye = takeEliminationBye(players)
+					pairings = append(pairings, pairing{Bracket: group.Name, A: bye})
+				}
+				// Seed terbaik lawan seed terburuk
+				for i := 0; i < len(players)/2; i++ {
+					b := players[len(players)-1-i]
+					pairings = append(pairings, pairing{Bracket: group.Name, A: players[i], B: &b})
+				}
+			}
+		}
+	}
+
+	now := time.Now()
+	deadline := now.Add(time.Duration(tournament.RoundHours) * time.Hour)
+	if tournament.RoundHours <= 0 {
+		deadline = now.Add(DefaultAcceptWindow)
+	}
+
+	var notices []tournamentNotice
+	for pos, p := range pairings {
+		playerA := p.A.UserID
+		match := models.TournamentMatch{
+			TournamentID: tournament.ID,
+			Round:        tournament.CurrentRound,
+			Bracket:      p.Bracket,
+			Position:     pos + 1,
+			PlayerAID:    &playerA,
+			Deadline:     &deadline,
+		}
+
+		if p.B == nil {
+			// Bye: otomatis lolos ke ronde berikutnya
+			match.Status = "bye"
+			match.WinnerID = &playerA
+			if err := tx.Create(&match).Error; err != nil {
+				return nil, err
+			}
+
+			updates := map[string]interface{}{"byes": gorm.Expr("byes + 1")}
+			if tournament.Format == "swiss" {
+				updates["wins"] = gorm.Expr("wins + 1")
+				updates["points"] = gorm.Expr("points + 2")
+			}
+			if err := tx.Model(&models.TournamentEntry{}).Where("id = ?", p.A.ID).Updates(updates).Error; err != nil {
+				return nil, err
+			}
+			notices = append(notices, tournamentNotice{playerA, "Bye Turnamen", fmt.Sprintf("Kamu mendapat bye di ronde %d turnamen %s", tournament.CurrentRound, tournament.Name), tournamentLink(tournament.ID)})
+			continue
+		}
+
+		playerB := p.B.UserID
+		challenge := models.Challenge{
+			QuizID:           tournament.QuizID,
+			CreatorID:        playerA,
+			Mode:             "1v1",
+			TimeLimit:        tournament.TimeLimit,
+			Status:           "active",
+			AcceptDeadline:   &deadline,
+			CompleteDeadline: &deadline,
+			TournamentID:     &tournament.ID,
+			Participants: []models.ChallengeParticipant{
+				{UserID: playerA, Status: "accepted", Team: "solo"},
+				{UserID: playerB, Status: "accepted", Team: "solo"},
+			},
+		}
+		if err := tx.Create(&challenge).Error; err != nil {
+			return nil, err
+		}
+
+		match.PlayerBID = &playerB
+		match.ChallengeID = &challenge.ID
+		match.Status = "active"
+		if err := tx.Create(&match).Error; err != nil {
+			return nil, err
+		}
+
+		msg := fmt.Sprintf("⚔️ Ronde %d turnamen %s dimulai! Selesaikan sebelum %s", tournament.CurrentRound, tournament.Name, deadline.Format("02 Jan 15:04"))
+		notices = append(notices,
+			tournamentNotice{playerA, "Match Turnamen", msg, "/challenges"},
+			tournamentNotice{playerB, "Match Turnamen", msg, "/challenges"},
+		)
+	}
+
+	return notices, nil
+}
+
+// takeSwissBye memberi bye ke pemain peringkat terbawah yang belum pernah bye
+func takeSwissBye(standings []models.TournamentEntry) ([]models.TournamentEntry, models.TournamentEntry) {
+	idx := len(standings) - 1
+	for i := len(standings) - 1; i >= 0; i-- {
+		if standings[i].Byes == 0 {
+			idx = i
+			break
+		}
+	}
+	bye := standings[idx]
+	rest := append(append([]models.TournamentEntry(nil), standings[:idx]...), standings[idx+1:]...)
+	return rest, bye
+}
+
+// takeEliminationBye memberi bye ke seed terbaik dengan jumlah bye paling sedikit
+func takeEliminationBye(players []models.TournamentEntry) ([]models.TournamentEntry, models.TournamentEntry) {
+	idx := 0
+	for i, p := range players {
+		if p.Byes < players[idx].Byes {
+			idx = i
+		}
+	}
+	bye := players[idx]
+	rest := append(append([]models.TournamentEntry(nil), players[:idx]...), players[idx+1:]...)
+	return rest, bye
+}
+
+// finishTournament menetapkan peringkat akhir dan membagikan prize pool sesuai PrizeSplit
+func finishTournament(tx *gorm.DB, tournament models.Tournament, entries []models.TournamentEntry) ([]tournamentNotice, error) {
+	standings := TournamentStandings(tournament, entries)
+	split := ParsePrizeSplit(tournament.PrizeSplit)
+
+	var notices []tournamentNotice
+	for i, e := range standings {
+		rank := i + 1
+		prize := 0
+		if i < len(split) {
+			prize = tournament.PrizePool * split[i] / 100
+		}
+
+		if err := tx.Model(&models.TournamentEntry{}).Where("id = ?", e.ID).
+			Updates(map[string]interface{}{"final_rank": rank, "prize": prize}).Error; err != nil {
+			return nil, err
+		}
+
+		msg := fmt.Sprintf("Turnamen %s selesai. Kamu peringkat #%d", tournament.Name, rank)
+		if prize > 0 {
+			if err := MoveCoins(tx, TournamentAccount(tournament.ID), UserAccount(e.UserID), prize,
+				"tournament_prize", LedgerRef{Type: "tournament", ID: tournament.ID}); err != nil {
+				return nil, err
+			}
+			msg = fmt.Sprintf("🏆 Turnamen %s selesai. Kamu peringkat #%d dan mendapat %d koin!", tournament.Name, rank, prize)
+		}
+		notices = append(notices, tournamentNotice{e.UserID, "Turnamen Selesai", msg, tournamentLink(tournament.ID)})
+	}
+
+	// Sisa pembulatan / split di bawah 100% kembali ke akun hadiah
+	if err := sweepTournamentPool(tx, tournament.ID); err != nil {
+		return nil, err
+	}
+
+	return notices, tx.Model(&tournament).Update("status", "finished").Error
+}
+
+// sweepTournamentPool memindahkan sisa saldo ledger turnamen ke akun hadiah
+func sweepTournamentPool(tx *gorm.DB, tournamentID uint) error {
+	account := TournamentAccount(tournamentID)
+
+	var balance int
+	tx.Model(&models.CoinTransaction{}).Select("COALESCE(SUM(amount), 0)").Where("account = ?", account.Name).Scan(&balance)
+	if balance <= 0 {
+		return nil
+	}
+	return MoveCoins(tx, account, AccountRewards, balance, "tournament_pool_return", LedgerRef{Type: "tournament", ID: tournamentID})
+}
+
+// ParsePrizeSplit mengubah "50,30,20" menjadi persentase per peringkat
+func ParsePrizeSplit(raw string) []int {
+	var split []int
+	for _, part := range strings.Split(raw, ",") {
+		val, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || val < 0 {
+			continue
+		}
+		split = append(split, val)
+	}
+	return split
+}
+
+func tournamentLink(tournamentID uint) string {
+	return "/tournaments/" + strconv.Itoa(int(tournamentID))
+}
+
+func sendTournamentNotices(notices []tournamentNotice) {
+	for _, n := range notices {
+		SendNotification(n.UserID, "info", n.Title, n.Message, n.Link)
+	}
+}
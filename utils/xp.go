@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/ROFL1ST/quizzes-backend/config"
+	"github.com/ROFL1ST/quizzes-backend/models"
+)
+
+const xpFormulaKey = "xp_formula"
+
+// XPFormula adalah komponen rumus XP kuis, disimpan sebagai JSON di SystemConfig "xp_formula"
+type XPFormula struct {
+	ScoreMultiplier       float64            `json:"score_multiplier"`       // XP per poin skor (0-100)
+	DifficultyMultipliers map[string]float64 `json:"difficulty_multipliers"` // easy, medium, hard
+	SpeedBonusMax         int                `json:"speed_bonus_max"`        // Bonus maksimal untuk skor 100 yang dijawab sangat cepat
+	SpeedTargetSeconds    int                `json:"speed_target_seconds"`   // Detik per soal; di bawah ini mulai dapat bonus
+	StreakBonusPerDay     float64            `json:"streak_bonus_per_day"`   // Tambahan pengali per hari streak
+	StreakBonusMax        float64            `json:"streak_bonus_max"`
+	FirstCompletionBonus  int                `json:"first_completion_bonus"`
+	ReplayDecay           float64            `json:"replay_decay"`          // Pengali untuk tiap pengulangan kuis yang sama
+	ReplayMinMultiplier   float64            `json:"replay_min_multiplier"` // Batas bawah pengali pengulangan
+}
+
+// DefaultXPFormula setara dengan rumus lama (XP = skor) ditambah bonus-bonus ringan
+func DefaultXPFormula() XPFormula {
+	return XPFormula{
+		ScoreMultiplier:       1,
+		DifficultyMultipliers: map[string]float64{"easy": 1, "medium": 1.2, "hard": 1.5},
+		SpeedBonusMax:         20,
+		SpeedTargetSeconds:    20,
+		StreakBonusPerDay:     0.02,
+		StreakBonusMax:        0.5,
+		FirstCompletionBonus:  25,
+		ReplayDecay:           0.5,
+		ReplayMinMultiplier:   0.1,
+	}
+}
+
+// GetXPFormula membaca rumus dari SystemConfig; field yang kosong memakai default
+func GetXPFormula() XPFormula {
+	formula := DefaultXPFormula()
+
+	var conf models.SystemConfig
+	config.DB.Where("key = ?", xpFormulaKey).Find(&conf)
+	if conf.Value == "" {
+		return formula
+	}
+
+	defaultDifficulty := formula.DifficultyMultipliers
+	formula.DifficultyMultipliers = nil
+	if err := json.Unmarshal([]byte(conf.Value), &formula); err != nil {
+		return DefaultXPFormula()
+	}
+	for k, v := range defaultDifficulty {
+		if _, ok := formula.DifficultyMultipliers[k]; !ok {
+			if formula.DifficultyMultipliers == nil {
+				formula.DifficultyMultipliers = map[string]float64{}
+			}
+			formula.DifficultyMultipliers[k] = v
+		}
+	}
+	return formula
+}
+
+// ValidateXPFormula menolak nilai yang bisa membuat XP negatif atau meledak
+func ValidateXPFormula(f XPFormula) error {
+	if f.ScoreMultiplier < 0 || f.ScoreMultiplier > 10 {
+		return errors.New("score_multiplier must be between 0 and 10")
+	}
+	for k, v := range f.DifficultyMultipliers {
+		if k != "easy" && k != "medium" && k != "hard" {
+			return errors.New("difficulty_multipliers only accepts easy, medium and hard")
+		}
+		if v < 0 || v > 10 {
+			return errors.New("difficulty multiplier must be between 0 and 10")
+		}
+	}
+	if f.SpeedBonusMax < 0 || f.SpeedTargetSeconds < 0 || f.FirstCompletionBonus < 0 {
+		return errors.New("bonus values cannot be negative")
+	}
+	if f.StreakBonusPerDay < 0 || f.StreakBonusMax < 0 || f.StreakBonusMax > 5 {
+		return errors.New("streak bonus must be between 0 and 5")
+	}
+	if f.ReplayDecay < 0 || f.ReplayDecay > 1 || f.ReplayMinMultiplier < 0 || f.ReplayMinMultiplier > 1 {
+		return errors.New("replay_decay and replay_min_multiplier must be between 0 and 1")
+	}
+	return nil
+}
+
+// SaveXPFormula menyimpan rumus ke SystemConfig
+func SaveXPFormula(f XPFormula) error {
+	if err := ValidateXPFormula(f); err != nil {
+		return err
+	}
+	value, _ := json.Marshal(f)
+
+	var conf models.SystemConfig
+	return config.DB.Where("key = ?", xpFormulaKey).
+		Assign(models.SystemConfig{Value: string(value)}).
+		FirstOrCreate(&conf).Error
+}
+
+// XPBreakdown adalah rincian XP yang dikembalikan bersama history
+type XPBreakdown struct {
+	Base                 int      `json:"base"`
+	Difficulty           string   `json:"difficulty"`
+	DifficultyMultiplier float64  `json:"difficulty_multiplier"`
+	SpeedBonus           int      `json:"speed_bonus"`
+	FirstCompletionBonus int      `json:"first_completion_bonus"`
+	StreakMultiplier     float64  `json:"streak_multiplier"`
+	ReplayCount          int      `json:"replay_count"`
+	ReplayMultiplier     float64  `json:"replay_multiplier"`
+	PerfectRepeats       int      `json:"perfect_repeats"` // Skor 100 sebelumnya di kuis yang sama
+	PerfectMultiplier    float64  `json:"perfect_multiplier"`
+	EventMultiplier      float64  `json:"event_multiplier"`
+	Events               []string `json:"events"`
+	Cooldown             bool     `json:"cooldown"`     // Kuis diulang dalam jeda cooldown: tanpa XP & progress misi
+	Flagged              bool     `json:"flagged"`      // Hasil masuk antrean review, XP ditahan
+	DailyCapped          int      `json:"daily_capped"` // XP yang terpotong batas harian
+	NoAttempt            bool     `json:"no_attempt"`   // Dikirim tanpa attempt (client lama): tanpa XP & progress misi
+	Total                int      `json:"total"`
+}
+
+// QuizXPInput adalah data satu penyelesaian kuis yang dinilai
+type QuizXPInput struct {
+	UserID    uint
+	QuizID    uint   // 0 = remedial, tidak dapat bonus penyelesaian pertama
+	SetKey    string // Remedial: QuestionSetKey dari attempt
+	TopicID   uint
+	Score     int
+	TimeTaken int // Detik menurut server (sejak attempt dibuka), bukan kiriman client; 0 = tanpa bonus kecepatan
+	Questions []models.Question
+}
+
+// CalculateQuizXP menghitung XP kuis:
+// (skor × difficulty + speed + first completion) × streak × replay × perfect × event
+func CalculateQuizXP(input QuizXPInput) XPBreakdown {
+	f := GetXPFormula()
+	rules := GetAntiFarmingRules()
+	b := XPBreakdown{
+		Base:              int(math.Round(float64(input.Score) * f.ScoreMultiplier)),
+		StreakMultiplier:  1,
+		ReplayMultiplier:  1,
+		PerfectMultiplier: 1,
+		EventMultiplier:   1,
+		Events:            []string{},
+	}
+
+	if QuizOnCooldown(input.UserID, input.QuizID, input.SetKey, rules) {
+		b.Cooldown = true
+		return b
+	}
+
+	b.Difficulty = QuizDifficulty(input.Questions)
+	b.DifficultyMultiplier = f.DifficultyMultipliers[b.Difficulty]
+
+	// Bonus kecepatan sebanding skor: jawaban cepat tapi salah tidak dapat bonus
+	if n := len(input.Questions); n > 0 && f.SpeedTargetSeconds > 0 && input.TimeTaken > 0 {
+		perQuestion := float64(input.TimeTaken) / float64(n)
+		ratio := math.Max(0, 1-perQuestion/float64(f.SpeedTargetSeconds))
+		b.SpeedBonus = int(math.Round(float64(f.SpeedBonusMax) * ratio * float64(input.Score) / 100))
+	}
+
+	var user models.User
+	if config.DB.Select("streak_count").First(&user, input.UserID).Error == nil {
+		b.StreakMultiplier = 1 + math.Min(f.StreakBonusMax, float64(user.StreakCount)*f.StreakBonusPerDay)
+	}
+
+	if input.QuizID != 0 || input.SetKey != "" {
+		var previous int64
+		sameQuizHistories(input.UserID, input.QuizID, input.SetKey).Count(&previous)
+		b.ReplayCount = int(previous)
+		if previous == 0 && input.Score > 0 && input.QuizID != 0 {
+			b.FirstCompletionBonus = f.FirstCompletionBonus
+		}
+		b.ReplayMultiplier = math.Max(f.ReplayMinMultiplier, math.Pow(f.ReplayDecay, float64(previous)))
+
+		// Skor sempurna berulang meluruh lebih cepat
+		if input.Score >= 100 {
+			var perfect int64
+			sameQuizHistories(input.UserID, input.QuizID, input.SetKey).
+				Where("score >= ?", 100).
+				Count(&perfect)
+			b.PerfectRepeats = int(perfect)
+			b.PerfectMultiplier = math.Pow(rules.PerfectRunDecay, float64(perfect))
+		}
+	}
+
+	b.EventMultiplier, b.Events = XPEventMultiplier(input.TopicID)
+
+	raw := float64(b.Base)*b.DifficultyMultiplier + float64(b.SpeedBonus) + float64(b.FirstCompletionBonus)
+	b.Total = int(math.Round(raw * b.StreakMultiplier * b.ReplayMultiplier * b.PerfectMultiplier * b.EventMultiplier))
+	return b
+}
+
+// QuizDifficulty menentukan tingkat kesulitan dari akurasi gabungan soal (sama seperti analisis soal admin)
+func QuizDifficulty(questions []models.Question) string {
+	correct, total := 0, 0
+	for _, q := range questions {
+		correct += q.CorrectCount
+		total += q.CorrectCount + q.IncorrectCount
+	}
+	if total == 0 {
+		return "medium"
+	}
+
+	accuracy := float64(correct) / float64(total) * 100
+	switch {
+	case accuracy > 80:
+		return "easy"
+	case accuracy > 40:
+		return "medium"
+	default:
+		return "hard"
+	}
+}
+
+// ActiveXPEvents mengembalikan event XP yang sedang berjalan untuk topik (0 = hanya event global)
+func ActiveXPEvents(topicID uint) []models.XPEvent {
+	now := time.Now()
+	var events []models.XPEvent
+	config.DB.Where("start_at <= ? AND end_at > ?", now, now).
+		Where("topic_id IS NULL OR topic_id = ?", topicID).
+		Order("multiplier DESC").
+		Find(&events)
+	return events
+}
+
+// XPEventMultiplier memakai pengali event tertinggi (event tidak ditumpuk)
+func XPEventMultiplier(topicID uint) (float64, []string) {
+	events := ActiveXPEvents(topicID)
+	if len(events) == 0 {
+		return 1, []string{}
+	}
+	return events[0].Multiplier, []string{events[0].Name}
+}
+
+// ApplyXPEvents mengalikan XP mode lain (misal survival) dengan event yang sedang aktif
+func ApplyXPEvents(amount int, topicID uint) int {
+	multiplier, _ := XPEventMultiplier(topicID)
+	return int(math.Round(float64(amount) * multiplier))
+}